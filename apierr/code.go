@@ -8,15 +8,57 @@ const (
 	// 注册登录
 	CodeNoLogin     = 1001
 	CodeTokenExpire = 1002
+	CodeRefreshTokenInvalid = 1003
+	CodeRefreshTokenReused  = 1004
+	CodeSessionNoExist      = 1005
+	CodeLoginTooFrequent    = 1006
+	CodeAccountLocked       = 1007
+	CodeLoginIpLocked       = 1008
+
+	CodeEmailCodeCooldown   = 1101
+	CodeEmailCodeEmailLimit = 1102
+	CodeEmailCodeIpLimit    = 1103
+	CodeEmailCodeAttempts   = 1104
 
 	// 用户
-	CodeUserExist   = 2001
-	CodeUserNoExist = 2002
+	CodeUserExist          = 2001
+	CodeUserNoExist        = 2002
+	CodePasswordResetToken = 2003
+	CodeOldPassword        = 2004
+	CodeUserBanned         = 2005
+	CodeUserBanSelf        = 2006
+	CodeUserBanExpire      = 2007
 
 	// 分类
 	CodeCategoryNameExist = 3001
 	CodeCategoryNoExist   = 3002
 
 	// 文章
-	CodeArticleNoExist = 4002
+	CodeArticleNoExist   = 4002
+	CodeArticlePublishAt = 4003
+
+	CodeArticleRevisionNoExist      = 4102
+	CodeArticleRevisionDiffTooLarge = 4103
+
+	// 评论
+	CodeCommentNoExist = 5002
+
+	// 标签
+	CodeTagNameExist = 6001
+	CodeTagNoExist   = 6002
+
+	// 站点
+	CodeSitemapNoExist = 7002
+
+	// 上传
+	CodeUploadTooLarge = 8001
+	CodeUploadType     = 8002
+
+	// 角色权限
+	CodeRoleNameExist      = 9001
+	CodeRoleNoExist        = 9002
+	CodePermissionNoExist  = 9003
+	CodeRoleBuiltin        = 9004
+	CodeRoleLastSuperAdmin = 9005
+	CodeRoleGrantDenied    = 9006
 )
@@ -20,15 +20,58 @@ var (
 	ErrLoginExpire    = errors.New("登录信息过期")
 	ErrNoLogin        = errors.New("未登录")
 
+	ErrRefreshTokenInvalid = errors.New("刷新token无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新token已被使用，会话已注销，请重新登录")
+	ErrSessionNoExist      = errors.New("会话不存在")
+	ErrLoginTooFrequent    = errors.New("登录失败，请稍后再试")
+	ErrAccountLocked       = errors.New("登录失败次数过多，账号已被临时锁定")
+	ErrLoginIpLocked       = errors.New("登录失败次数过多，请稍后再试")
+
+	ErrEmailCodeCooldown   = errors.New("验证码发送过于频繁，请稍后再试")
+	ErrEmailCodeEmailLimit = errors.New("该邮箱获取验证码次数过多，请稍后再试")
+	ErrEmailCodeIpLimit    = errors.New("获取验证码次数过多，请稍后再试")
+	ErrEmailCodeAttempts   = errors.New("验证码错误次数过多，请重新获取")
+
 	// 用户
-	ErrUserNoExist = errors.New("用户不存在")
+	ErrUserNoExist        = errors.New("用户不存在")
+	ErrPasswordResetToken = errors.New("重置密码链接无效或已过期")
+	ErrOldPassword        = errors.New("当前密码错误")
+	ErrUserBanned         = errors.New("账号已被封禁")
+	ErrUserBanSelf        = errors.New("不能封禁自己的账号")
+	ErrUserBanExpire      = errors.New("封禁到期时间必须晚于当前时间")
 
 	// 分类
 	ErrCategoryNameExist = errors.New("分类名已存在")
 	ErrCategoryNoExist   = errors.New("分类不存在")
 
 	// 文章
-	ErrArticleNoExist = errors.New("文章不存在")
+	ErrArticleNoExist   = errors.New("文章不存在")
+	ErrArticlePublishAt = errors.New("定时发布时间必须晚于当前时间")
+
+	ErrArticleRevisionNoExist      = errors.New("文章历史版本不存在")
+	ErrArticleRevisionDiffTooLarge = errors.New("两个版本的差异过大，无法对比")
+
+	// 评论
+	ErrCommentNoExist = errors.New("评论不存在")
+
+	// 标签
+	ErrTagNameExist = errors.New("标签名已存在")
+	ErrTagNoExist   = errors.New("标签不存在")
+
+	// 站点
+	ErrSitemapNoExist = errors.New("sitemap不存在")
+
+	// 上传
+	ErrUploadTooLarge = errors.New("文件过大")
+	ErrUploadType     = errors.New("不支持的文件类型")
+
+	// 角色权限
+	ErrRoleNameExist      = errors.New("角色名已存在")
+	ErrRoleNoExist        = errors.New("角色不存在")
+	ErrPermissionNoExist  = errors.New("权限不存在")
+	ErrRoleBuiltin        = errors.New("内置角色不能删除或改名")
+	ErrRoleLastSuperAdmin = errors.New("不能移除最后一个超级管理员")
+	ErrRoleGrantDenied    = errors.New("不能授予或收回自己没有的角色和权限")
 )
 
 var errCode = map[error]int{
@@ -40,15 +83,58 @@ var errCode = map[error]int{
 	ErrLoginExpire: CodeTokenExpire,
 	ErrNoLogin:     CodeNoLogin,
 
+	ErrRefreshTokenInvalid: CodeRefreshTokenInvalid,
+	ErrRefreshTokenReused:  CodeRefreshTokenReused,
+	ErrSessionNoExist:      CodeSessionNoExist,
+	ErrLoginTooFrequent:    CodeLoginTooFrequent,
+	ErrAccountLocked:       CodeAccountLocked,
+	ErrLoginIpLocked:       CodeLoginIpLocked,
+
+	ErrEmailCodeCooldown:   CodeEmailCodeCooldown,
+	ErrEmailCodeEmailLimit: CodeEmailCodeEmailLimit,
+	ErrEmailCodeIpLimit:    CodeEmailCodeIpLimit,
+	ErrEmailCodeAttempts:   CodeEmailCodeAttempts,
+
 	// 用户
-	ErrUserNoExist: CodeUserNoExist,
+	ErrUserNoExist:        CodeUserNoExist,
+	ErrPasswordResetToken: CodePasswordResetToken,
+	ErrOldPassword:        CodeOldPassword,
+	ErrUserBanned:         CodeUserBanned,
+	ErrUserBanSelf:        CodeUserBanSelf,
+	ErrUserBanExpire:      CodeUserBanExpire,
 
 	// 分类
 	ErrCategoryNameExist: CodeCategoryNameExist,
 	ErrCategoryNoExist:   CodeCategoryNoExist,
 
 	// 文章
-	ErrArticleNoExist: CodeArticleNoExist,
+	ErrArticleNoExist:   CodeArticleNoExist,
+	ErrArticlePublishAt: CodeArticlePublishAt,
+
+	ErrArticleRevisionNoExist:      CodeArticleRevisionNoExist,
+	ErrArticleRevisionDiffTooLarge: CodeArticleRevisionDiffTooLarge,
+
+	// 评论
+	ErrCommentNoExist: CodeCommentNoExist,
+
+	// 标签
+	ErrTagNameExist: CodeTagNameExist,
+	ErrTagNoExist:   CodeTagNoExist,
+
+	// 站点
+	ErrSitemapNoExist: CodeSitemapNoExist,
+
+	// 上传
+	ErrUploadTooLarge: CodeUploadTooLarge,
+	ErrUploadType:     CodeUploadType,
+
+	// 角色权限
+	ErrRoleNameExist:      CodeRoleNameExist,
+	ErrRoleNoExist:        CodeRoleNoExist,
+	ErrPermissionNoExist:  CodePermissionNoExist,
+	ErrRoleBuiltin:        CodeRoleBuiltin,
+	ErrRoleLastSuperAdmin: CodeRoleLastSuperAdmin,
+	ErrRoleGrantDenied:    CodeRoleGrantDenied,
 }
 
 func ErrCode(err error) int {
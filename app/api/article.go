@@ -32,10 +32,18 @@ type IArticleService interface {
 	Delete(id int64) error
 	UpdateInfo(article model.Article) error
 	UpdateWeight(id int64, weight int64) error
-	Get(id int64) (*model.Article, error)
+	UpdateStatus(id int64, status int8, publishAt int64) error
+	Get(id int64, visitor model.Visitor) (*model.Article, error)
+	AdminGet(id int64) (*model.Article, error)
+	AdminList(status int8, page, pageSize int) ([]model.Article, int64, error)
+	ListRevisions(articleId int64, page, pageSize int) ([]model.ArticleRevision, int64, error)
+	DiffRevisions(articleId, fromId, toId int64) (map[string]string, error)
+	RestoreRevision(articleId, revisionId int64) error
 	List(page, pageSize int) ([]model.Article, int64, error)
 	ListByCategory(categoryId int64, page, pageSize int) ([]model.Article, int64, error)
+	ListByTag(tagId int64, page, pageSize int) ([]model.Article, int64, error)
 	ListHome() ([]model.Article, error)
+	Search(q string, page, pageSize int) ([]model.Article, int64, error)
 }
 
 /**
@@ -48,6 +56,9 @@ type IArticleService interface {
  * @apiParam {string{1..64}} title 文章标题
  * @apiParam {string{1..1024}} preview_ctx 预览内容
  * @apiParam {string{1..}} content 文章正文
+ * @apiParam {number[]} tag_ids 文章标签id,最多10个
+ * @apiParam {number=1(草稿),2(定时发布),3(发布)} status=3 文章状态
+ * @apiParam {number} publish_at 发布时间戳,status=2时必须且晚于当前时间
  *
  * @apiSuccess {string} id 创建的文章id
  *
@@ -73,10 +84,12 @@ func (ctl *Article) Create(c *gin.Context) {
 		response.CopierErrAndLog(c, ctl.logger, err)
 		return
 	}
+	article.TagIds = req.TagIds
+	article.AuthorId = c.GetInt64("userId")
 
 	id, err := ctl.articleService.Create(article)
 	if err != nil {
-		response.CheckErrAndLog(c, ctl.logger, "createArticle", err, apierr.ErrCategoryNoExist)
+		response.CheckErrAndLog(c, ctl.logger, "createArticle", err, apierr.ErrCategoryNoExist, apierr.ErrTagNoExist, apierr.ErrArticlePublishAt)
 		return
 	}
 
@@ -123,13 +136,16 @@ func (ctl *Article) Delete(c *gin.Context) {
  * @api {patch} /article 编辑文章
  * @apiName Article.Update
  *
- * @apiParam {number=1(文章信息内容),2(文章权重)} update_type 更新类型
+ * @apiParam {number=1(文章信息内容),2(文章权重),3(文章状态)} update_type 更新类型
  * @apiParam {number{1..}} id 文章id,update_type=1时必须
  * @apiParam {number} category_id 所属分类id,update_type=1时必须
  * @apiParam {string{1..64}} title 文章标题,update_type=1时必须
  * @apiParam {string{1..1024}} preview_ctx 预览内容,update_type=1时必须
  * @apiParam {string{1..}} content 文章正文,update_type=1时必须
+ * @apiParam {number[]} tag_ids 文章标签id,update_type=1时可选,不传则不修改标签,传空数组则清空标签
  * @apiParam {number{0..}} weight 文章权重,update_type=2时可选
+ * @apiParam {number=1(草稿),2(定时发布),3(发布),4(归档)} status 文章状态,update_type=3时必须
+ * @apiParam {number} publish_at 发布时间戳,update_type=3且status=2时必须且晚于当前时间
  *
  * @apiErrorExample {json} 文章不存在
  *     {
@@ -151,6 +167,8 @@ func (ctl *Article) Update(c *gin.Context) {
 		ctl.updateInfo(c)
 	case 2:
 		ctl.updateWeight(c)
+	case 3:
+		ctl.updateStatus(c)
 	}
 
 	return
@@ -167,11 +185,14 @@ func (ctl *Article) Update(c *gin.Context) {
  * @apiSuccess {number} id 文章id
  * @apiSuccess {number} category_id 分类id
  * @apiSuccess {string} category_name 分类名
+ * @apiSuccess {object[]} tags 文章标签
  * @apiSuccess {string} title 文章标题
  * @apiSuccess {number} views 点击量
  * @apiSuccess {string} created_at 创建时间
  * @apiSuccess {string} updated_at 更新时间
- * @apiSuccess {string} content 文章正文
+ * @apiSuccess {string} content 文章正文(Markdown)
+ * @apiSuccess {string} content_html 正文渲染后的html，已过滤脚本和事件属性
+ * @apiSuccess {object[]} toc 标题目录，按出现顺序排列，level为标题级别，id为标题锚点
  *
  * @apiSuccessExample {json} Success-Response:
  *     {
@@ -181,11 +202,14 @@ func (ctl *Article) Update(c *gin.Context) {
  *                 "id": 14,
  *                 "category_id": 5,
  *                 "category_name": "Golang",
+ *                 "tags": [{"id": 2, "name": "并发"}],
  *                 "title": "文章标题",
  *                 "views": 0,
  *                 "created_at": 1625798089,
  *                 "int64": 1625798089,
- *                 "content": "内容",
+ *                 "content": "## 标题\n内容",
+ *                 "content_html": "<h2 id=\"标题\">标题</h2>\n<p>内容</p>\n",
+ *                 "toc": [{"level": 2, "id": "标题", "title": "标题"}],
  *                 "picture": "",
  *                 "sentence": ""
  *             }
@@ -201,7 +225,13 @@ func (ctl *Article) Get(c *gin.Context) {
 		return
 	}
 
-	article, err := ctl.articleService.Get(id)
+	visitor := model.Visitor{
+		UserId:    c.GetInt64("userId"),
+		Ip:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	article, err := ctl.articleService.Get(id, visitor)
 	if err != nil {
 		response.CheckErrAndLog(c, ctl.logger, "getArticle", err, apierr.ErrArticleNoExist)
 		return
@@ -219,6 +249,7 @@ func (ctl *Article) Get(c *gin.Context) {
  * @apiParam {number{1..}} page 页码
  * @apiParam {number{1..50}} page_size 数据分页大小
  * @apiParam {number{1..}} category_id 分类id,传空则为全部
+ * @apiParam {number{1..}} tag 标签id,传入时按标签筛选并忽略category_id
  *
  * @apiSuccess {number} id 文章id
  * @apiSuccess {number} category_id 分类id
@@ -274,7 +305,9 @@ func (ctl *Article) List(c *gin.Context) {
 		err error
 	)
 
-	if req.CategoryId > 0 {
+	if req.Tag > 0 {
+		articles, totalSize, err = ctl.articleService.ListByTag(req.Tag, req.Page, req.PageSize)
+	} else if req.CategoryId > 0 {
 		articles, totalSize, err = ctl.articleService.ListByCategory(req.CategoryId, req.Page, req.PageSize)
 	} else {
 		articles, totalSize, err = ctl.articleService.List(req.Page, req.PageSize)
@@ -345,6 +378,292 @@ func (ctl *Article) HomeList(c *gin.Context) {
 	ctl.transform.HomeListReply(c, articles)
 }
 
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Article
+ * @api {get} /articles/search 搜索文章
+ * @apiName Article.Search
+ *
+ * @apiParam {string{1..64}} q 搜索关键词,多个关键词使用空格分隔
+ * @apiParam {number{1..}} page=1 页码
+ * @apiParam {number{1..50}} page_size=10 数据分页大小
+ *
+ * @apiSuccess {number} id 文章id
+ * @apiSuccess {number} category_id 分类id
+ * @apiSuccess {string} category_name 分类名
+ * @apiSuccess {string} title 文章标题
+ * @apiSuccess {string} title_highlight 高亮后的文章标题,关键词使用<em>包裹
+ * @apiSuccess {string} snippet 命中关键词的正文高亮片段
+ * @apiSuccess {number} views 点击量
+ * @apiSuccess {string} created_at 创建时间
+ * @apiSuccess {string} updated_at 更新时间
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 14,
+ *                     "category_id": 5,
+ *                     "category_name": "Golang",
+ *                     "title": "Golang并发",
+ *                     "title_highlight": "<em>Golang</em>并发",
+ *                     "snippet": "...使用<em>golang</em>的channel...",
+ *                     "views": 0,
+ *                     "created_at": 1625798089,
+ *                     "updated_at": 0
+ *                 }
+ *             ],
+ *             "total_size": 1
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ */
+func (ctl *Article) Search(c *gin.Context) {
+	req := articleValidator.SearchReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.PageSize == 0 {
+		req.PageSize = 10
+	}
+
+	articles, totalSize, err := ctl.articleService.Search(req.Q, req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "article search", err)
+		return
+	}
+
+	ctl.transform.SearchReply(c, articles, totalSize)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Article
+ * @api {get} /article/:id/admin 管理员查询任意状态的文章详情
+ * @apiName Article.AdminGet
+ *
+ * @apiParam {number{1..}} id 文章id
+ *
+ * @apiSuccess {number} status 文章状态(1草稿,2定时发布,3已发布,4已归档)
+ * @apiSuccess {number} publish_at 发布时间
+ */
+func (ctl *Article) AdminGet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.FailMsg(c, "id must be greater than 0")
+		return
+	}
+
+	article, err := ctl.articleService.AdminGet(id)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "adminGetArticle", err, apierr.ErrArticleNoExist)
+		return
+	}
+
+	ctl.transform.AdminGetReply(c, article)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Article
+ * @api {get} /articles/admin 管理员文章分页列表(包括草稿、定时发布、归档的文章)
+ * @apiName Article.AdminList
+ *
+ * @apiParam {number{1..}} page 页码
+ * @apiParam {number{1..50}} page_size 数据分页大小
+ * @apiParam {number=1(草稿),2(定时发布),3(已发布),4(已归档)} status 文章状态,传空则为全部
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 14,
+ *                     "category_id": 5,
+ *                     "category_name": "Golang",
+ *                     "tags": [],
+ *                     "title": "文章标题",
+ *                     "views": 0,
+ *                     "status": 2,
+ *                     "publish_at": 1625900000,
+ *                     "created_at": 1625798089,
+ *                     "updated_at": 0
+ *                 }
+ *             ],
+ *             "total_size": 1
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Article) AdminList(c *gin.Context) {
+	req := articleValidator.AdminListReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	articles, totalSize, err := ctl.articleService.AdminList(req.Status, req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "admin article list", err)
+		return
+	}
+
+	ctl.transform.AdminListReply(c, articles, totalSize)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Article
+ * @api {get} /article/:id/revisions 文章历史版本列表
+ * @apiName Article.ListRevisions
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {number{1..}} page 页码
+ * @apiParam {number{1..50}} page_size 数据分页大小
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 7,
+ *                     "article_id": 14,
+ *                     "title": "文章标题",
+ *                     "created_at": 1625798089
+ *                 }
+ *             ],
+ *             "total_size": 1
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Article) ListRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.FailMsg(c, "id must be greater than 0")
+		return
+	}
+
+	req := articleValidator.RevisionListReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	revisions, totalSize, err := ctl.articleService.ListRevisions(id, req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "article revision list", err, apierr.ErrArticleNoExist)
+		return
+	}
+
+	ctl.transform.RevisionListReply(c, revisions, totalSize)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Article
+ * @api {get} /article/:id/revisions/diff 对比文章的两个版本
+ * @apiName Article.DiffRevisions
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {number{0..}} from=0 旧版本id,0表示文章当前内容
+ * @apiParam {number{0..}} to=0 新版本id,0表示文章当前内容
+ *
+ * @apiSuccess {string} title 标题的统一格式差异,没有变化时为空
+ * @apiSuccess {string} preview_ctx 预览内容的统一格式差异,没有变化时为空
+ * @apiSuccess {string} content 正文的统一格式差异,没有变化时为空
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "diff": {
+ *                 "title": "",
+ *                 "preview_ctx": "",
+ *                 "content": "--- revision#7\n+++ current\n@@ -1 +1 @@\n-旧内容\n+新内容\n"
+ *             }
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 两个版本的差异过大
+ *     {
+ *       "code": 4103,
+ *       "msg": "两个版本的差异过大，无法对比",
+ *       "data": {}
+ *     }
+ */
+func (ctl *Article) DiffRevisions(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.FailMsg(c, "id must be greater than 0")
+		return
+	}
+
+	req := articleValidator.RevisionDiffReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	diff, err := ctl.articleService.DiffRevisions(id, req.From, req.To)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "article revision diff", err,
+			apierr.ErrArticleNoExist, apierr.ErrArticleRevisionNoExist, apierr.ErrArticleRevisionDiffTooLarge)
+		return
+	}
+
+	response.Success(c, map[string]interface{}{"diff": diff})
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Article
+ * @api {post} /article/:id/revisions/restore 恢复文章的历史版本
+ * @apiName Article.RestoreRevision
+ * @apiDescription 使用历史版本的标题、预览内容和正文覆盖文章，被覆盖的内容会保存为新的历史版本
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {number{1..}} revision_id 历史版本id
+ *
+ * @apiErrorExample {json} 历史版本不存在
+ *     {
+ *       "code": 4102,
+ *       "msg": "文章历史版本不存在",
+ *       "data": {}
+ *     }
+ */
+func (ctl *Article) RestoreRevision(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.FailMsg(c, "id must be greater than 0")
+		return
+	}
+
+	req := articleValidator.RevisionRestoreReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.articleService.RestoreRevision(id, req.RevisionId); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "restore article revision", err, apierr.ErrArticleNoExist, apierr.ErrArticleRevisionNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
 func (ctl *Article) updateInfo(c *gin.Context) {
 	req := articleValidator.UpdateInfoReq{}
 	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
@@ -357,9 +676,10 @@ func (ctl *Article) updateInfo(c *gin.Context) {
 		response.CopierErrAndLog(c, ctl.logger, err)
 		return
 	}
+	article.TagIds = req.TagIds
 
 	if err := ctl.articleService.UpdateInfo(article); err != nil {
-		response.CheckErrAndLog(c, ctl.logger, "update article info", err, apierr.ErrArticleNoExist)
+		response.CheckErrAndLog(c, ctl.logger, "update article info", err, apierr.ErrArticleNoExist, apierr.ErrTagNoExist)
 		return
 	}
 
@@ -389,3 +709,18 @@ func (ctl *Article) updateWeight(c *gin.Context) {
 	return
 }
 
+func (ctl *Article) updateStatus(c *gin.Context) {
+	req := articleValidator.UpdateStatusReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.articleService.UpdateStatus(req.Id, req.Status, req.PublishAt); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "update article status", err, apierr.ErrArticleNoExist, apierr.ErrArticlePublishAt)
+		return
+	}
+
+	response.Success(c, nil)
+	return
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/app/validator/auditValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"time"
+)
+
+type Audit struct {
+	auditService IAuditService
+	transform    transform.Audit
+	logger       *logger.CustomLogger
+}
+
+func NewAudit(auditService IAuditService, logger *logger.CustomLogger) Audit {
+	return Audit{
+		auditService: auditService,
+		transform:    transform.NewAudit(logger),
+		logger:       logger,
+	}
+}
+
+type IAuditService interface {
+	List(filter model.AuditFilter, page, pageSize int) ([]model.AuditLog, int64, error)
+	Export(filter model.AuditFilter, fn func([]model.AuditLog) error) error
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Audit
+ * @api {get} /audit_logs 审计日志列表
+ * @apiName Audit.List
+ * @apiDescription 管理员写操作的记录，按时间倒序；日志异步写入，操作后通常1秒内可以查询到
+ *
+ * @apiParam {number{1..}} page 页码
+ * @apiParam {number{1..50}} page_size 数据分页大小
+ * @apiParam {number} [actor_id] 操作人id
+ * @apiParam {string} [action] 操作需要的权限名,如article:delete
+ * @apiParam {string} [resource] 资源名,如article
+ * @apiParam {number} [target_id] 操作对象id
+ * @apiParam {number} [created_from] 操作时间起始时间戳
+ * @apiParam {number} [created_to] 操作时间截止时间戳
+ *
+ * @apiSuccess {object} before 操作前的记录,新建时为null
+ * @apiSuccess {object} after 操作后的记录,删除后为null
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 52,
+ *                     "actor_id": 1,
+ *                     "action": "tag:update",
+ *                     "resource": "tag",
+ *                     "method": "PUT",
+ *                     "path": "/api/v1/tag",
+ *                     "target_id": 3,
+ *                     "before": {"id": 3, "name": "Go", "created_at": 1625798089, "updated_at": 1625798089},
+ *                     "after": {"id": 3, "name": "Golang", "created_at": 1625798089, "updated_at": 1625900000},
+ *                     "ip": "127.0.0.1",
+ *                     "created_at": 1625900000
+ *                 }
+ *             ],
+ *             "total_size": 1
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Audit) List(c *gin.Context) {
+	req := auditValidator.ListReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	filter, ok := ctl.bindFilter(c)
+	if !ok {
+		return
+	}
+
+	logs, totalSize, err := ctl.auditService.List(filter, req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "audit log list", err)
+		return
+	}
+
+	ctl.transform.ListReply(c, logs, totalSize)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Audit
+ * @api {get} /audit_logs/export 导出审计日志
+ * @apiName Audit.Export
+ * @apiDescription 导出符合条件的全部审计日志为csv文件
+ *
+ * @apiParam {number} [actor_id] 操作人id
+ * @apiParam {string} [action] 操作需要的权限名,如article:delete
+ * @apiParam {string} [resource] 资源名,如article
+ * @apiParam {number} [target_id] 操作对象id
+ * @apiParam {number} [created_from] 操作时间起始时间戳
+ * @apiParam {number} [created_to] 操作时间截止时间戳
+ *
+ * @apiSuccessExample {csv} Success-Response:
+ *     id,时间,操作人id,操作,资源,请求方法,请求路径,操作对象id,ip,操作前,操作后
+ *     52,2021-07-10 14:53:20,1,tag:update,tag,PUT,/api/v1/tag,3,127.0.0.1,"{""id"":3,""name"":""Go""}","{""id"":3,""name"":""Golang""}"
+ */
+func (ctl *Audit) Export(c *gin.Context) {
+	filter, ok := ctl.bindFilter(c)
+	if !ok {
+		return
+	}
+
+	w := ctl.transform.CsvWriter(c, "audit_log_"+time.Now().Format("20060102150405")+".csv")
+	err := ctl.auditService.Export(filter, func(logs []model.AuditLog) error {
+		return ctl.transform.CsvRows(w, logs)
+	})
+	if err != nil {
+		// 响应头已经发送，只能记录日志
+		ctl.logger.Sugar().Errorf("export audit logs err: %s", err)
+	}
+}
+
+// bindFilter 解析筛选条件，参数错误时响应错误并返回false
+func (ctl *Audit) bindFilter(c *gin.Context) (model.AuditFilter, bool) {
+	req := auditValidator.FilterReq{}
+	filter := model.AuditFilter{}
+
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return filter, false
+	}
+
+	if err := copier.Copy(&filter, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return filter, false
+	}
+
+	return filter, true
+}
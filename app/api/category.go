@@ -23,6 +23,16 @@ import (
  *     }
  */
 
+/**
+ * @apiDefine CategoryNoExist 分类不存在
+ * @apiErrorExample {json} 分类不存在
+ *     {
+ *       "code": 3002,
+ *       "msg": "分类不存在",
+ *       "data": {}
+ *     }
+ */
+
 type Category struct {
 	categoryService ICategoryService
 	transform   transform.Category
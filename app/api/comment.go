@@ -0,0 +1,255 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/app/validator/commentValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"strconv"
+)
+
+/**
+ * @apiDefine CommentNoExist 评论不存在
+ * @apiErrorExample {json} 评论不存在
+ *     {
+ *       "code": 5002,
+ *       "msg": "评论不存在",
+ *       "data": {}
+ *     }
+ */
+
+type Comment struct {
+	commentService ICommentService
+	transform      transform.Comment
+	logger         *logger.CustomLogger
+}
+
+func NewComment(commentService ICommentService, logger *logger.CustomLogger) Comment {
+	return Comment{
+		commentService: commentService,
+		transform:      transform.NewComment(logger),
+		logger:         logger,
+	}
+}
+
+type ICommentService interface {
+	Create(comment model.Comment) (int64, error)
+	List(articleId int64, withHidden bool, page, pageSize int) ([]model.Comment, int64, error)
+	GetSumByArticle(articleId int64) (int64, error)
+	UpdateStatus(id int64, status int8) error
+	Delete(id int64) error
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Comment
+ * @api {post} /article/:id/comments 发表评论
+ * @apiName Comment.Create
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {number{1..}} parent_id 回复的评论id,发表顶级评论时不传
+ * @apiParam {string{1..1024}} content 评论内容
+ *
+ * @apiSuccess {string} id 创建的评论id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "id": 23,
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse CommentNoExist
+ */
+func (ctl *Comment) Create(c *gin.Context) {
+	articleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || articleId <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	req := commentValidator.CreateReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	comment := model.Comment{
+		ArticleId: articleId,
+		UserId:    c.GetInt64("userId"),
+		ParentId:  req.ParentId,
+		Content:   req.Content,
+	}
+
+	id, err := ctl.commentService.Create(comment)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "createComment", err, apierr.ErrArticleNoExist, apierr.ErrCommentNoExist)
+		return
+	}
+
+	response.Success(c, map[string]int64{"id": id})
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Comment
+ * @api {get} /article/:id/comments 文章评论分页列表
+ * @apiName Comment.List
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {number{1..}} page 页码
+ * @apiParam {number{1..50}} page_size 顶级评论的分页大小
+ *
+ * @apiSuccess {number} total_size 顶级评论总数
+ * @apiSuccess {number} comment_count 文章评论总数(包括回复)
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 23,
+ *                     "user_id": 14,
+ *                     "user_name": "mittacy",
+ *                     "content": "评论内容",
+ *                     "status": 1,
+ *                     "created_at": 1625798089,
+ *                     "replies": [
+ *                         {
+ *                             "id": 24,
+ *                             "user_id": 15,
+ *                             "user_name": "blog",
+ *                             "root_id": 23,
+ *                             "parent_id": 23,
+ *                             "content": "回复内容",
+ *                             "status": 1,
+ *                             "created_at": 1625798833
+ *                         }
+ *                     ]
+ *                 }
+ *             ],
+ *             "total_size": 1,
+ *             "comment_count": 2
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Comment) List(c *gin.Context) {
+	ctl.list(c, false)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Comment
+ * @api {get} /article/:id/comments/all 管理员查看文章全部评论(包括被隐藏的评论)
+ * @apiName Comment.ListAll
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {number{1..}} page 页码
+ * @apiParam {number{1..50}} page_size 顶级评论的分页大小
+ */
+func (ctl *Comment) ListAll(c *gin.Context) {
+	ctl.list(c, true)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Comment
+ * @api {put} /comment 更新评论
+ * @apiName Comment.Update
+ *
+ * @apiParam {number=1(评论状态)} update_type 更新类型
+ * @apiParam {number{1..}} id 评论id
+ * @apiParam {number=1(正常),2(隐藏)} status 评论状态,update_type=1时必须
+ *
+ * @apiUse CommentNoExist
+ */
+func (ctl *Comment) Update(c *gin.Context) {
+	req := commentValidator.UpdateReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	switch req.UpdateType {
+	case 1:
+		ctl.updateStatus(c)
+	}
+
+	return
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Comment
+ * @api {delete} /comment/:id 删除评论及其下的回复
+ * @apiName Comment.Delete
+ *
+ * @apiParam {number{1..}} id 评论id
+ *
+ * @apiUse CommentNoExist
+ */
+func (ctl *Comment) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.commentService.Delete(id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "deleteComment", err, apierr.ErrCommentNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+func (ctl *Comment) list(c *gin.Context, withHidden bool) {
+	articleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || articleId <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	req := commentValidator.ListReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	comments, totalSize, err := ctl.commentService.List(articleId, withHidden, req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "comment list", err)
+		return
+	}
+
+	commentCount, err := ctl.commentService.GetSumByArticle(articleId)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "comment count", err)
+		return
+	}
+
+	ctl.transform.ListReply(c, comments, totalSize, commentCount)
+}
+
+func (ctl *Comment) updateStatus(c *gin.Context) {
+	req := commentValidator.UpdateStatusReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.commentService.UpdateStatus(req.Id, req.Status); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "update comment status", err, apierr.ErrCommentNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
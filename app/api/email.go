@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/apierr"
 	"github.com/mittacy/blogBack/app/transform"
 	"github.com/mittacy/blogBack/app/validator/emailValidator"
 	"github.com/mittacy/blogBack/pkg/logger"
@@ -23,7 +24,7 @@ func NewEmail(emailService IEmailService, logger *logger.CustomLogger) Email {
 }
 
 type IEmailService interface {
-	SendRegisterCode(email string) error
+	SendRegisterCode(email, ip string) error
 }
 
 /**
@@ -31,9 +32,29 @@ type IEmailService interface {
  * @apiGroup Email
  * @api {get} /email/register_code 获取邮箱验证码
  * @apiName Email.GetRegisterCode
+ * @apiDescription 同一邮箱在冷却时间内不能重复发送，同一邮箱和同一ip在统计窗口内的发送次数有上限；
+ * 每个验证码的校验次数有上限，超过后需要重新获取
  *
  * @apiParam {string} email 邮箱
  *
+ * @apiErrorExample {json} 发送过于频繁
+ *     {
+ *       "code": 1101,
+ *       "msg": "验证码发送过于频繁，请稍后再试",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} 邮箱发送次数过多
+ *     {
+ *       "code": 1102,
+ *       "msg": "该邮箱获取验证码次数过多，请稍后再试",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} ip发送次数过多
+ *     {
+ *       "code": 1103,
+ *       "msg": "获取验证码次数过多，请稍后再试",
+ *       "data": {}
+ *     }
  */
 func (ctl *Email) GetRegisterCode(c *gin.Context) {
 	d := emailValidator.RegisterCodeReq{}
@@ -42,8 +63,9 @@ func (ctl *Email) GetRegisterCode(c *gin.Context) {
 		return
 	}
 
-	if err := ctl.emailService.SendRegisterCode(d.Email); err != nil {
-		response.CheckErrAndLog(c, ctl.logger, "getEmailCode", err)
+	if err := ctl.emailService.SendRegisterCode(d.Email, c.ClientIP()); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "getEmailCode", err,
+			apierr.ErrEmailCodeCooldown, apierr.ErrEmailCodeEmailLimit, apierr.ErrEmailCodeIpLimit)
 		return
 	}
 
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"net/http"
+	"strconv"
+)
+
+type Feed struct {
+	feedService IFeedService
+	logger      *logger.CustomLogger
+}
+
+func NewFeed(feedService IFeedService, logger *logger.CustomLogger) Feed {
+	return Feed{
+		feedService: feedService,
+		logger:      logger,
+	}
+}
+
+type IFeedService interface {
+	RSS() ([]byte, error)
+	Atom() ([]byte, error)
+	CategoryRSS(categoryId int64) ([]byte, error)
+}
+
+const (
+	rssContentType  = "application/rss+xml; charset=utf-8"
+	atomContentType = "application/atom+xml; charset=utf-8"
+)
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Feed
+ * @api {get} /feed.xml 全站RSS 2.0订阅源
+ * @apiName Feed.RSS
+ *
+ * @apiSuccessExample {xml} Success-Response:
+ *     <?xml version="1.0" encoding="UTF-8"?>
+ *     <rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+ *       <channel>
+ *         <title>blog</title>
+ *         <link>https://blog.example.com</link>
+ *         <lastBuildDate>Fri, 09 Jul 2021 10:34:49 +0800</lastBuildDate>
+ *         <item>
+ *           <title>文章标题</title>
+ *           <link>https://blog.example.com/article/14</link>
+ *           <guid isPermaLink="true">https://blog.example.com/article/14</guid>
+ *           <description>文章预览</description>
+ *           <category>Golang</category>
+ *           <pubDate>Fri, 09 Jul 2021 10:34:49 +0800</pubDate>
+ *         </item>
+ *       </channel>
+ *     </rss>
+ */
+func (ctl *Feed) RSS(c *gin.Context) {
+	data, err := ctl.feedService.RSS()
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "rss feed", err)
+		return
+	}
+
+	c.Data(http.StatusOK, rssContentType, data)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Feed
+ * @api {get} /atom.xml 全站Atom订阅源
+ * @apiName Feed.Atom
+ */
+func (ctl *Feed) Atom(c *gin.Context) {
+	data, err := ctl.feedService.Atom()
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "atom feed", err)
+		return
+	}
+
+	c.Data(http.StatusOK, atomContentType, data)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Feed
+ * @api {get} /category/:id/feed.xml 分类RSS 2.0订阅源
+ * @apiName Feed.CategoryRSS
+ *
+ * @apiParam {number{1..}} id 分类id
+ *
+ * @apiUse CategoryNoExist
+ */
+func (ctl *Feed) CategoryRSS(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	data, err := ctl.feedService.CategoryRSS(id)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "category rss feed", err, apierr.ErrCategoryNoExist)
+		return
+	}
+
+	c.Data(http.StatusOK, rssContentType, data)
+}
@@ -0,0 +1,371 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/app/validator/rbacValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"strconv"
+)
+
+/**
+ * @apiDefine RoleNameExist 角色名已存在
+ * @apiErrorExample {json} 角色名已存在
+ *     {
+ *       "code": 9001,
+ *       "msg": "角色名已存在",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine RoleNoExist 角色不存在
+ * @apiErrorExample {json} 角色不存在
+ *     {
+ *       "code": 9002,
+ *       "msg": "角色不存在",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine PermissionNoExist 权限不存在
+ * @apiErrorExample {json} 权限不存在
+ *     {
+ *       "code": 9003,
+ *       "msg": "权限不存在",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine RoleBuiltin 内置角色不能删除或改名
+ * @apiErrorExample {json} 内置角色不能删除或改名
+ *     {
+ *       "code": 9004,
+ *       "msg": "内置角色不能删除或改名",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine RoleGrantDenied 不能授予或收回自己没有的角色和权限
+ * @apiErrorExample {json} 不能授予或收回自己没有的角色和权限
+ *     {
+ *       "code": 9006,
+ *       "msg": "不能授予或收回自己没有的角色和权限",
+ *       "data": {}
+ *     }
+ */
+
+type Rbac struct {
+	rbacService IRbacService
+	transform   transform.Rbac
+	logger      *logger.CustomLogger
+}
+
+func NewRbac(rbacService IRbacService, logger *logger.CustomLogger) Rbac {
+	return Rbac{
+		rbacService: rbacService,
+		transform:   transform.NewRbac(logger),
+		logger:      logger,
+	}
+}
+
+type IRbacService interface {
+	ListRoles() ([]model.Role, error)
+	ListPermissions() ([]model.Permission, error)
+	CreateRole(operatorId int64, role model.Role) (int64, error)
+	UpdateRole(operatorId int64, role model.Role) error
+	DeleteRole(operatorId, id int64) error
+	ListUserRoles(userId int64) ([]model.Role, error)
+	SetUserRoles(operatorId, userId int64, roleIds []int64) error
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {get} /permissions 权限列表
+ * @apiName Rbac.ListPermissions
+ *
+ * @apiSuccess {number} id 权限id
+ * @apiSuccess {string} name 权限名,格式:资源:操作
+ * @apiSuccess {string} description 权限描述
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 6,
+ *                     "name": "article:delete",
+ *                     "description": "删除文章"
+ *                 }
+ *             ]
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Rbac) ListPermissions(c *gin.Context) {
+	permissions, err := ctl.rbacService.ListPermissions()
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "list permissions", err)
+		return
+	}
+
+	ctl.transform.PermissionsReply(c, permissions)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {get} /roles 角色列表
+ * @apiName Rbac.ListRoles
+ *
+ * @apiSuccess {number} id 角色id
+ * @apiSuccess {string} name 角色名,super_admin为内置的超级管理员,拥有所有权限
+ * @apiSuccess {string} description 角色描述
+ * @apiSuccess {string[]} permissions 角色拥有的权限名
+ * @apiSuccess {number} created_at 创建时间
+ * @apiSuccess {number} updated_at 修改时间
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 2,
+ *                     "name": "editor",
+ *                     "description": "编辑",
+ *                     "permissions": ["article:create", "article:update"],
+ *                     "created_at": 1626072372,
+ *                     "updated_at": 1626072372
+ *                 }
+ *             ]
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Rbac) ListRoles(c *gin.Context) {
+	roles, err := ctl.rbacService.ListRoles()
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "list roles", err)
+		return
+	}
+
+	ctl.transform.RolesReply(c, roles)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {post} /role 创建角色
+ * @apiName Rbac.CreateRole
+ * @apiDescription 只能授予自己拥有的权限
+ *
+ * @apiParam {string{1..32}} name 角色名
+ * @apiParam {string{..255}} [description] 角色描述
+ * @apiParam {string[]} [permissions] 权限名
+ *
+ * @apiSuccess {number} id 创建的角色id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "id": 2
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse RoleNameExist
+ * @apiUse PermissionNoExist
+ * @apiUse RoleGrantDenied
+ */
+func (ctl *Rbac) CreateRole(c *gin.Context) {
+	req := rbacValidator.CreateRoleReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	role := model.Role{}
+	if err := copier.Copy(&role, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	id, err := ctl.rbacService.CreateRole(c.GetInt64("userId"), role)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "create role", err,
+			apierr.ErrRoleNameExist, apierr.ErrPermissionNoExist, apierr.ErrRoleGrantDenied)
+		return
+	}
+
+	response.Success(c, map[string]int64{"id": id})
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {put} /role 修改角色
+ * @apiName Rbac.UpdateRole
+ * @apiDescription 权限全量覆盖，超级管理员角色不能改名，修改前后的权限都必须是自己拥有的权限，超级管理员角色只有超级管理员可以修改
+ *
+ * @apiParam {number{1..}} id 角色id
+ * @apiParam {string{1..32}} name 角色名
+ * @apiParam {string{..255}} [description] 角色描述
+ * @apiParam {string[]} [permissions] 权限名
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse RoleNoExist
+ * @apiUse RoleNameExist
+ * @apiUse PermissionNoExist
+ * @apiUse RoleBuiltin
+ * @apiUse RoleGrantDenied
+ */
+func (ctl *Rbac) UpdateRole(c *gin.Context) {
+	req := rbacValidator.UpdateRoleReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	role := model.Role{}
+	if err := copier.Copy(&role, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	if err := ctl.rbacService.UpdateRole(c.GetInt64("userId"), role); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "update role", err,
+			apierr.ErrRoleNoExist, apierr.ErrRoleNameExist, apierr.ErrPermissionNoExist, apierr.ErrRoleBuiltin, apierr.ErrRoleGrantDenied)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {delete} /role/:id 删除角色
+ * @apiName Rbac.DeleteRole
+ * @apiDescription 同时移除该角色的所有分配，超级管理员角色不能删除，角色的权限必须都是自己拥有的权限
+ *
+ * @apiParam {number{1..}} id 角色id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse RoleNoExist
+ * @apiUse RoleBuiltin
+ * @apiUse RoleGrantDenied
+ */
+func (ctl *Rbac) DeleteRole(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.rbacService.DeleteRole(c.GetInt64("userId"), id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "delete role", err, apierr.ErrRoleNoExist, apierr.ErrRoleBuiltin, apierr.ErrRoleGrantDenied)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {get} /user_roles 查询用户的角色
+ * @apiName Rbac.ListUserRoles
+ *
+ * @apiParam {number{1..}} user_id 用户id
+ *
+ * @apiSuccess {number} id 角色id
+ * @apiSuccess {string} name 角色名
+ * @apiSuccess {string} description 角色描述
+ * @apiSuccess {string[]} permissions 角色拥有的权限名
+ */
+func (ctl *Rbac) ListUserRoles(c *gin.Context) {
+	req := rbacValidator.UserRolesReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	roles, err := ctl.rbacService.ListUserRoles(req.UserId)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "list user roles", err)
+		return
+	}
+
+	ctl.transform.RolesReply(c, roles)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Rbac
+ * @api {put} /user_roles 设置用户的角色
+ * @apiName Rbac.SetUserRoles
+ * @apiDescription 全量覆盖用户的角色，用户必须存在且未注销，不能移除最后一个超级管理员。新增和移除的角色的权限必须都是自己拥有的权限，超级管理员角色只有超级管理员可以授予和收回
+ *
+ * @apiParam {number{1..}} user_id 用户id
+ * @apiParam {number[]} [role_ids] 角色id,为空时清空用户的角色
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 用户不存在
+ *     {
+ *       "code": 2002,
+ *       "msg": "用户不存在",
+ *       "data": {}
+ *     }
+ * @apiUse RoleNoExist
+ * @apiErrorExample {json} 不能移除最后一个超级管理员
+ *     {
+ *       "code": 9005,
+ *       "msg": "不能移除最后一个超级管理员",
+ *       "data": {}
+ *     }
+ * @apiUse RoleGrantDenied
+ */
+func (ctl *Rbac) SetUserRoles(c *gin.Context) {
+	req := rbacValidator.SetUserRolesReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.rbacService.SetUserRoles(c.GetInt64("userId"), req.UserId, req.RoleIds); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "set user roles", err,
+			apierr.ErrUserNoExist, apierr.ErrRoleNoExist, apierr.ErrRoleLastSuperAdmin, apierr.ErrRoleGrantDenied)
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/app/validator/sessionValidator"
+	"github.com/mittacy/blogBack/pkg/jwt"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"strconv"
+)
+
+/**
+ * @apiDefine RefreshTokenInvalid 刷新token无效或已过期
+ * @apiErrorExample {json} 刷新token无效或已过期
+ *     {
+ *       "code": 1003,
+ *       "msg": "刷新token无效或已过期",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine RefreshTokenReused 刷新token被重复使用
+ * @apiErrorExample {json} 刷新token被重复使用
+ *     {
+ *       "code": 1004,
+ *       "msg": "刷新token已被使用，会话已注销，请重新登录",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine SessionNoExist 会话不存在
+ * @apiErrorExample {json} 会话不存在
+ *     {
+ *       "code": 1005,
+ *       "msg": "会话不存在",
+ *       "data": {}
+ *     }
+ */
+
+type Session struct {
+	sessionService ISessionService
+	transform      transform.Session
+	logger         *logger.CustomLogger
+}
+
+func NewSession(sessionService ISessionService, logger *logger.CustomLogger) Session {
+	return Session{
+		sessionService: sessionService,
+		transform:      transform.NewSession(logger),
+		logger:         logger,
+	}
+}
+
+type ISessionService interface {
+	Refresh(refreshToken, ip string) (*jwt.TokenPair, error)
+	List(userId int64) ([]jwt.Session, error)
+	Revoke(userId int64, sessionId string) error
+	Logout(accessToken string) error
+	RevokeUser(userId int64) error
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Session
+ * @api {post} /session/refresh 刷新登录token
+ * @apiName Session.Refresh
+ * @apiDescription 使用刷新token换取新的访问token和刷新token，旧的刷新token立即失效；
+ * 重复使用已失效的刷新token会被视为token泄露，注销整个会话
+ *
+ * @apiParam {string} refresh_token 刷新token
+ *
+ * @apiSuccess {string} token 访问token,同时写入cookie access_token
+ * @apiSuccess {number} expire_at 访问token过期时间戳
+ * @apiSuccess {string} refresh_token 新的刷新token
+ * @apiSuccess {number} refresh_expire_at 刷新token过期时间戳
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *           "token": "xxx",
+ *           "expire_at": 1626158772,
+ *           "refresh_token": "xxx",
+ *           "refresh_expire_at": 1628749872
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse RefreshTokenInvalid
+ * @apiUse RefreshTokenReused
+ */
+func (ctl *Session) Refresh(c *gin.Context) {
+	req := sessionValidator.RefreshReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	pair, err := ctl.sessionService.Refresh(req.RefreshToken, c.ClientIP())
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "refresh token", err, apierr.ErrRefreshTokenInvalid, apierr.ErrRefreshTokenReused)
+		return
+	}
+
+	ctl.transform.TokenReply(c, pair)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Session
+ * @api {get} /sessions 登录设备列表
+ * @apiName Session.List
+ *
+ * @apiSuccess {string} id 会话id
+ * @apiSuccess {string} user_agent 登录设备
+ * @apiSuccess {string} ip 最近使用的ip
+ * @apiSuccess {number} created_at 登录时间
+ * @apiSuccess {number} last_used_at 最近刷新时间
+ * @apiSuccess {bool} current 是否为当前设备
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": "5f0c7e6d2b1a4c3e9f8d7a6b5c4d3e2f",
+ *                     "user_agent": "Mozilla/5.0 ...",
+ *                     "ip": "127.0.0.1",
+ *                     "created_at": 1626072372,
+ *                     "last_used_at": 1626158772,
+ *                     "current": true
+ *                 }
+ *             ]
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Session) List(c *gin.Context) {
+	sessions, err := ctl.sessionService.List(c.GetInt64("userId"))
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "list sessions", err)
+		return
+	}
+
+	ctl.transform.SessionsReply(c, sessions, c.GetString("sessionId"))
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Session
+ * @api {delete} /sessions/:id 注销登录设备
+ * @apiName Session.Revoke
+ * @apiDescription 该设备的刷新token和访问token立即失效
+ *
+ * @apiParam {string} id 会话id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse SessionNoExist
+ */
+func (ctl *Session) Revoke(c *gin.Context) {
+	if err := ctl.sessionService.Revoke(c.GetInt64("userId"), c.Param("id")); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "revoke session", err, apierr.ErrSessionNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Session
+ * @api {delete} /session 退出登录
+ * @apiName Session.Logout
+ * @apiDescription 当前访问token加入黑名单，所属会话的刷新token失效，并清除cookie access_token；
+ * 访问token已过期时同样可以退出
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Session) Logout(c *gin.Context) {
+	if accessToken, err := c.Cookie("access_token"); err == nil && accessToken != "" {
+		if err := ctl.sessionService.Logout(accessToken); err != nil {
+			response.CheckErrAndLog(c, ctl.logger, "logout", err)
+			return
+		}
+	}
+
+	c.SetCookie("access_token", "", -1, "/", "", false, true)
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Session
+ * @api {delete} /user/:id/sessions 注销用户所有的会话
+ * @apiName Session.RevokeUser
+ * @apiDescription 管理员操作，该用户已签发的访问token和刷新token全部失效
+ *
+ * @apiParam {number{1..}} id 用户id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Session) RevokeUser(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.sessionService.RevokeUser(id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "revoke user sessions", err, apierr.ErrUserNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type Site struct {
+	siteService ISiteService
+	logger      *logger.CustomLogger
+}
+
+func NewSite(siteService ISiteService, logger *logger.CustomLogger) Site {
+	return Site{
+		siteService: siteService,
+		logger:      logger,
+	}
+}
+
+type ISiteService interface {
+	Sitemap() ([]byte, error)
+	SitemapPage(page int) ([]byte, error)
+	Robots() string
+}
+
+const xmlContentType = "application/xml; charset=utf-8"
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Site
+ * @api {get} /sitemap.xml 站点sitemap
+ * @apiName Site.Sitemap
+ * @apiDescription 包括首页、分类和文章，地址数超过50000时返回sitemap索引，各个sitemap的地址为/sitemap/:page.xml
+ *
+ * @apiSuccessExample {xml} Success-Response:
+ *     <?xml version="1.0" encoding="UTF-8"?>
+ *     <urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+ *       <url>
+ *         <loc>https://blog.example.com/article/14</loc>
+ *         <lastmod>2021-07-09T10:34:49+08:00</lastmod>
+ *       </url>
+ *     </urlset>
+ */
+func (ctl *Site) Sitemap(c *gin.Context) {
+	data, err := ctl.siteService.Sitemap()
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "sitemap", err)
+		return
+	}
+
+	c.Data(http.StatusOK, xmlContentType, data)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Site
+ * @api {get} /sitemap/:page.xml sitemap索引中的第page个sitemap
+ * @apiName Site.SitemapPage
+ *
+ * @apiParam {number{1..}} page 页码
+ */
+func (ctl *Site) SitemapPage(c *gin.Context) {
+	page, err := strconv.Atoi(strings.TrimSuffix(c.Param("page"), ".xml"))
+	if err != nil || page <= 0 {
+		response.FailMsg(c, "page必须大于0")
+		return
+	}
+
+	data, err := ctl.siteService.SitemapPage(page)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "sitemap page", err, apierr.ErrSitemapNoExist)
+		return
+	}
+
+	c.Data(http.StatusOK, xmlContentType, data)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Site
+ * @api {get} /robots.txt robots.txt
+ * @apiName Site.Robots
+ * @apiDescription 规则在配置文件robots中设置，末尾附带sitemap地址
+ */
+func (ctl *Site) Robots(c *gin.Context) {
+	c.String(http.StatusOK, ctl.siteService.Robots())
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/app/validator/statValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"strconv"
+	"time"
+)
+
+type Stat struct {
+	statService IStatService
+	transform   transform.Stat
+	logger      *logger.CustomLogger
+}
+
+func NewStat(statService IStatService, logger *logger.CustomLogger) Stat {
+	return Stat{
+		statService: statService,
+		transform:   transform.NewStat(logger),
+		logger:      logger,
+	}
+}
+
+type IStatService interface {
+	ListDaily(target string, id int64, start, end time.Time) ([]model.DailyStat, error)
+}
+
+const (
+	statDefaultDays = 30  // 默认查询最近30天
+	statMaxDays     = 366 // 单次最多查询的天数
+)
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Stat
+ * @api {get} /article/:id/stats 文章每日访问统计
+ * @apiName Stat.ArticleDaily
+ *
+ * @apiParam {number{1..}} id 文章id
+ * @apiParam {string} start_date 开始日期,格式:2006-01-02,默认为30天前
+ * @apiParam {string} end_date 结束日期(包括),格式:2006-01-02,默认为今天
+ *
+ * @apiSuccess {string} date 日期
+ * @apiSuccess {number} pv 访问量,已过滤爬虫和窗口期内的重复访问
+ * @apiSuccess {number} uv 独立访客数
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "date": "2021-07-09",
+ *                     "pv": 120,
+ *                     "uv": 87
+ *                 }
+ *             ]
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Stat) ArticleDaily(c *gin.Context) {
+	ctl.daily(c, model.StatTargetArticle)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Stat
+ * @api {get} /category/:id/stats 分类每日访问统计
+ * @apiName Stat.CategoryDaily
+ *
+ * @apiParam {number{1..}} id 分类id
+ * @apiParam {string} start_date 开始日期,格式:2006-01-02,默认为30天前
+ * @apiParam {string} end_date 结束日期(包括),格式:2006-01-02,默认为今天
+ *
+ * @apiSuccess {string} date 日期
+ * @apiSuccess {number} pv 分类下所有文章的访问量
+ * @apiSuccess {number} uv 分类的独立访客数
+ */
+func (ctl *Stat) CategoryDaily(c *gin.Context) {
+	ctl.daily(c, model.StatTargetCategory)
+}
+
+func (ctl *Stat) daily(c *gin.Context, target string) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	req := statValidator.DailyReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	now := time.Now()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if req.EndDate != "" {
+		end, _ = time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	}
+	start := end.AddDate(0, 0, -(statDefaultDays - 1))
+	if req.StartDate != "" {
+		start, _ = time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	}
+
+	if start.After(end) {
+		response.FailMsg(c, "开始日期不能晚于结束日期")
+		return
+	}
+	if start.AddDate(0, 0, statMaxDays).Before(end.AddDate(0, 0, 1)) {
+		response.FailMsg(c, "查询范围不能超过366天")
+		return
+	}
+
+	stats, err := ctl.statService.ListDaily(target, id, start, end)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "stat daily", err)
+		return
+	}
+
+	ctl.transform.DailyReply(c, stats)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/app/validator/tagValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"strconv"
+)
+
+/**
+ * @apiDefine TagNameExist 标签名已存在
+ * @apiErrorExample {json} 标签名已存在
+ *     {
+ *       "code": 6001,
+ *       "msg": "标签名已存在",
+ *       "data": {}
+ *     }
+ */
+
+type Tag struct {
+	tagService ITagService
+	transform  transform.Tag
+	logger     *logger.CustomLogger
+}
+
+func NewTag(tagService ITagService, logger *logger.CustomLogger) Tag {
+	return Tag{
+		tagService: tagService,
+		transform:  transform.NewTag(logger),
+		logger:     logger,
+	}
+}
+
+type ITagService interface {
+	Create(tag model.Tag) (int64, error)
+	Delete(id int64) error
+	UpdateName(tag model.Tag) error
+	List(page, pageSize int) ([]model.Tag, int64, error)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Tag
+ * @api {post} /tag 创建文章标签
+ * @apiName Tag.Create
+ *
+ * @apiParam {string{1..16}} name 标签名
+ *
+ * @apiSuccess {string} id 创建的标签id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "id": 2,
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse TagNameExist
+ *
+ */
+func (ctl *Tag) Create(c *gin.Context) {
+	req := tagValidator.CreateReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	tag := model.Tag{}
+	if err := copier.Copy(&tag, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	tagId, err := ctl.tagService.Create(tag)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "createTag", err, apierr.ErrTagNameExist)
+		return
+	}
+
+	response.Success(c, map[string]int64{"id": tagId})
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Tag
+ * @api {delete} /tag/:id 删除文章标签
+ * @apiName Tag.Delete
+ *
+ * @apiParam {number} id 标签id
+ *
+ * @apiErrorExample {json} 标签不存在
+ *     {
+ *       "code": 6002,
+ *       "msg": "标签不存在",
+ *       "data": {}
+ *     }
+ *
+ */
+func (ctl *Tag) Delete(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.tagService.Delete(id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "deleteTag", err, apierr.ErrTagNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Tag
+ * @api {put} /tag 更新标签
+ * @apiName Tag.Update
+ *
+ * @apiParam {number=1(标签名)} update_type 更新类型
+ * @apiParam {number} id 标签id
+ * @apiParam {string{1..16}} name 标签新名字,update_type=1时必须
+ *
+ * @apiUse TagNameExist
+ *
+ */
+func (ctl *Tag) Update(c *gin.Context) {
+	req := tagValidator.UpdateReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	switch req.UpdateType {
+	case 1:
+		ctl.updateName(c)
+	}
+
+	return
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Tag
+ * @api {get} /tags 获取标签列表
+ * @apiName Tag.List
+ *
+ * @apiParam {number{1..}} [page=1] 页码
+ * @apiParam {number{1..50}} [page_size=50] 请求的数据分页大小
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "total_size": 2,
+ *             "list": [
+ *                 {
+ *                     "id": 2,
+ *                     "name": "并发",
+ *                     "article_count": 3
+ *                 },
+ *                 {
+ *                     "id": 3,
+ *                     "name": "算法",
+ *                     "article_count": 1
+ *                 }
+ *             ]
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *Tag) List(c *gin.Context) {
+	req := tagValidator.ListReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	tags, count, err := ctl.tagService.List(req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "tagsList", err)
+		return
+	}
+
+	ctl.transform.ListReply(c, tags, count)
+}
+
+func (ctl *Tag) updateName(c *gin.Context) {
+	req := tagValidator.UpdateNameReq{}
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	tag := model.Tag{}
+	if err := copier.Copy(&tag, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	if err := ctl.tagService.UpdateName(tag); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "updateTagName", err, apierr.ErrTagNoExist, apierr.ErrTagNameExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
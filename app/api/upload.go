@@ -0,0 +1,128 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/transform"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"io/ioutil"
+	"net/http"
+)
+
+/**
+ * @apiDefine UploadTooLarge 文件过大
+ * @apiErrorExample {json} 文件过大
+ *     {
+ *       "code": 8001,
+ *       "msg": "文件过大",
+ *       "data": {}
+ *     }
+ */
+
+/**
+ * @apiDefine UploadType 不支持的文件类型
+ * @apiErrorExample {json} 不支持的文件类型
+ *     {
+ *       "code": 8002,
+ *       "msg": "不支持的文件类型",
+ *       "data": {}
+ *     }
+ */
+
+type Upload struct {
+	uploadService IUploadService
+	transform     transform.Upload
+	logger        *logger.CustomLogger
+}
+
+func NewUpload(uploadService IUploadService, logger *logger.CustomLogger) Upload {
+	return Upload{
+		uploadService: uploadService,
+		transform:     transform.NewUpload(logger),
+		logger:        logger,
+	}
+}
+
+type IUploadService interface {
+	MaxSize() int64
+	Upload(userId int64, name string, data []byte) (*model.Upload, error)
+}
+
+// uploadFormOverhead multipart表单中除文件外的其他内容允许的大小
+const uploadFormOverhead = 1 << 20
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup Upload
+ * @api {post} /upload 上传图片或附件
+ * @apiName Upload.Upload
+ *
+ * @apiParam {file} file 文件,使用multipart/form-data上传,类型根据文件内容判断
+ *
+ * @apiSuccess {number} id 文件id
+ * @apiSuccess {string} hash 文件内容的sha256,内容相同的文件只保存一份
+ * @apiSuccess {string} name 原文件名
+ * @apiSuccess {string} mime_type 文件类型
+ * @apiSuccess {number} size 文件大小,单位:字节
+ * @apiSuccess {string} url 文件地址
+ * @apiSuccess {string} thumb_url 缩略图地址,非图片时为空
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "id": 1,
+ *             "hash": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+ *             "name": "cover.png",
+ *             "mime_type": "image/png",
+ *             "size": 20480,
+ *             "url": "/uploads/files/9f/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png",
+ *             "thumb_url": "/uploads/thumbs/9f/9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08.png"
+ *         },
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse UploadTooLarge
+ * @apiUse UploadType
+ */
+func (ctl *Upload) Upload(c *gin.Context) {
+	maxSize := ctl.uploadService.MaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+uploadFormOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		if err.Error() == "http: request body too large" {
+			response.FailErr(c, apierr.ErrUploadTooLarge)
+			return
+		}
+		response.FailMsg(c, "请选择上传的文件")
+		return
+	}
+	if fileHeader.Size > maxSize {
+		response.FailErr(c, apierr.ErrUploadTooLarge)
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "open upload file", err)
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "read upload file", err)
+		return
+	}
+
+	upload, err := ctl.uploadService.Upload(c.GetInt64("userId"), fileHeader.Filename, data)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "upload", err, apierr.ErrUploadTooLarge, apierr.ErrUploadType)
+		return
+	}
+
+	ctl.transform.UploadReply(c, upload)
+}
@@ -7,30 +7,55 @@ import (
 	"github.com/mittacy/blogBack/app/model"
 	"github.com/mittacy/blogBack/app/transform"
 	"github.com/mittacy/blogBack/app/validator/userValidator"
+	"github.com/mittacy/blogBack/pkg/jwt"
 	"github.com/mittacy/blogBack/pkg/logger"
 	"github.com/mittacy/blogBack/pkg/response"
 	"strconv"
 )
 
 type User struct {
-	userService IUserService
-	transform   transform.User
-	logger      *logger.CustomLogger
+	userService      IUserService
+	roleService      IUserRoleService
+	transform        transform.User
+	sessionTransform transform.Session
+	logger           *logger.CustomLogger
 }
 
-func NewUser(userService IUserService, logger *logger.CustomLogger) User {
+func NewUser(userService IUserService, roleService IUserRoleService, logger *logger.CustomLogger) User {
 	return User{
-		userService: userService,
-		transform: transform.NewUser(logger),
-		logger:    logger,
+		userService:      userService,
+		roleService:      roleService,
+		transform:        transform.NewUser(roleService, logger),
+		sessionTransform: transform.NewSession(logger),
+		logger:           logger,
 	}
 }
 
 type IUserService interface {
 	Register(user model.User, code string) (int64, error)
 	GetUserInfo(id int64) (*model.User, error)
-	LoginByName(name, password string) (string, error)
-	LoginByEmail(email, password string) (string, error)
+	LoginByName(name, password string, device model.Device) (*jwt.TokenPair, error)
+	LoginByEmail(email, password string, device model.Device) (*jwt.TokenPair, error)
+	ForgotPassword(email, ip string) error
+	ResetPassword(token, newPassword string) error
+	Unlock(userId int64) error
+	UpdateProfile(user model.User) error
+	UpdatePassword(userId int64, sessionId, oldPassword, newPassword string) error
+	Delete(userId int64, password string) error
+	SendChangeEmailCode(userId int64, email, ip string) error
+	ChangeEmail(userId int64, email, code string) error
+	UpdatePrivacy(userId int64, privacy int) error
+	AdminList(filter model.UserFilter, page, pageSize int) ([]model.User, int64, error)
+	AdminGet(id int64) (*model.User, error)
+	Ban(operatorId, userId int64, reason string, expireAt int64) error
+	Unban(userId int64) error
+	ForceResetPassword(operatorId, userId int64) error
+}
+
+// IUserRoleService 权限校验和用户角色查询
+type IUserRoleService interface {
+	transform.IAuthorizer
+	ListUserRoles(userId int64) ([]model.Role, error)
 }
 
 /**
@@ -75,6 +100,12 @@ type IUserService interface {
  *       "msg": "验证码错误",
  *       "data": {}
  *     }
+ * @apiErrorExample {json} 验证码错误次数过多
+ *     {
+ *       "code": 1104,
+ *       "msg": "验证码错误次数过多，请重新获取",
+ *       "data": {}
+ *     }
  *
  */
 func (ctl *User) Register(c *gin.Context) {
@@ -92,7 +123,7 @@ func (ctl *User) Register(c *gin.Context) {
 
 	userId, err := ctl.userService.Register(user, register.Code)
 	if err != nil {
-		response.CheckErrAndLog(c, ctl.logger, "registerUser", err, apierr.ErrUserNameExist, apierr.ErrUserEmailExist, apierr.ErrRegisterCode)
+		response.CheckErrAndLog(c, ctl.logger, "registerUser", err, apierr.ErrUserNameExist, apierr.ErrUserEmailExist, apierr.ErrRegisterCode, apierr.ErrEmailCodeAttempts)
 		return
 	}
 
@@ -102,7 +133,9 @@ func (ctl *User) Register(c *gin.Context) {
 /**
  * @apiVersion 0.1.0
  * @apiGroup User
- * @api {post} /session/user/login 用户登录
+ * @api {post} /session/login 登录
+ * @apiDescription 普通用户和管理员使用同一个登录接口，管理权限由用户被分配的角色决定；
+ * 每次密码错误后需要等待的时间成倍增加，同一账号连续失败多次后账号被临时锁定并发送邮件通知，同一ip失败多次后被临时限制登录
  * @apiName User.Login
  *
  * @apiParam {number} login_type 登录方式(1:昵称 2:邮箱)
@@ -110,13 +143,19 @@ func (ctl *User) Register(c *gin.Context) {
  * @apiParam {string} email 邮箱,login_type=2时必须
  * @apiParam {string{2..20}} password 用户密码
  *
- * @apiSuccess {string} token 登录身份token
+ * @apiSuccess {string} token 访问token,同时写入cookie access_token
+ * @apiSuccess {number} expire_at 访问token过期时间戳
+ * @apiSuccess {string} refresh_token 刷新token,访问token过期后用于换取新的token
+ * @apiSuccess {number} refresh_expire_at 刷新token过期时间戳
  *
  * @apiSuccessExample {json} Success-Response:
  *     {
  *         "code": 0,
  *         "data": {
- *           "token": "xxx"
+ *           "token": "xxx",
+ *           "expire_at": 1626158772,
+ *           "refresh_token": "xxx",
+ *           "refresh_expire_at": 1628749872
  *         },
  *         "msg": "success"
  *     }
@@ -127,6 +166,30 @@ func (ctl *User) Register(c *gin.Context) {
  *       "msg": "账号或密码错误",
  *       "data": {}
  *     }
+ * @apiErrorExample {json} 登录失败后需要等待:
+ *     {
+ *       "code": 1006,
+ *       "msg": "登录失败，请稍后再试",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} 账号被锁定:
+ *     {
+ *       "code": 1007,
+ *       "msg": "登录失败次数过多，账号已被临时锁定",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} ip被限制登录:
+ *     {
+ *       "code": 1008,
+ *       "msg": "登录失败次数过多，请稍后再试",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} 账号被封禁:
+ *     {
+ *       "code": 2005,
+ *       "msg": "账号已被封禁",
+ *       "data": {}
+ *     }
  *
  */
 func (ctl *User) Login(c *gin.Context) {
@@ -136,25 +199,27 @@ func (ctl *User) Login(c *gin.Context) {
 		return
 	}
 
-	var token string
+	var token *jwt.TokenPair
 	var err error
+	device := model.Device{Ip: c.ClientIP(), UserAgent: c.Request.UserAgent()}
 
 	switch userLogin.LoginType {
 	case 1:
-		token, err = ctl.userService.LoginByName(userLogin.Name, userLogin.Password)
+		token, err = ctl.userService.LoginByName(userLogin.Name, userLogin.Password, device)
 	case 2:
-		token, err = ctl.userService.LoginByEmail(userLogin.Email, userLogin.Password)
+		token, err = ctl.userService.LoginByEmail(userLogin.Email, userLogin.Password, device)
 	default:
 		response.FailMsg(c, "update_type param err")
 		return
 	}
 
 	if err != nil {
-		response.CheckErrAndLog(c, ctl.logger, "userLogin", err, apierr.ErrUserOrPassword)
+		response.CheckErrAndLog(c, ctl.logger, "userLogin", err, apierr.ErrUserOrPassword,
+			apierr.ErrLoginTooFrequent, apierr.ErrAccountLocked, apierr.ErrLoginIpLocked, apierr.ErrUserBanned)
 		return
 	}
 
-	response.Success(c, map[string]string{"token": token})
+	ctl.sessionTransform.TokenReply(c, token)
 }
 
 /**
@@ -162,17 +227,41 @@ func (ctl *User) Login(c *gin.Context) {
  * @apiGroup User
  * @api {get} /user/{id} 获取用户信息
  * @apiName User.GetInfo
+ * @apiDescription 本人和拥有user:view_private权限的管理员返回完整信息；
+ * 其他调用者只返回公开信息，不包含邮箱和登录时间，被用户隐私设置隐藏的字段不返回
  *
  * @apiSuccess {number} id 用户id
  * @apiSuccess {string} name 用户昵称
  * @apiSuccess {string} gender 用户性别(1保密,5男,10女)
  * @apiSuccess {string} introduce 个人简介
  * @apiSuccess {string} github github地址
- * @apiSuccess {string} email 邮箱地址
+ * @apiSuccess {string} avatar 头像地址
+ * @apiSuccess {number} article_count 已发布的文章数
+ * @apiSuccess {number} comment_count 评论数
  * @apiSuccess {string} created_at 创建时间
- * @apiSuccess {string} login_at 最后登录时间
+ * @apiSuccess {string} email 邮箱地址，仅本人和管理员可见
+ * @apiSuccess {string} login_at 最后登录时间，仅本人和管理员可见
+ * @apiSuccess {object} privacy 隐私设置，仅本人和管理员可见
  *
- * @apiSuccessExample {json} Success-Response:
+ * @apiSuccessExample {json} 公开信息:
+ * {
+ *     "code": 0,
+ *     "data": {
+ *         "user": {
+ *             "id": 14,
+ *             "name": "mittacy",
+ *             "gender": 5,
+ *             "introduce": "介绍",
+ *             "avatar": "https://blog.example.com/uploads/avatar.png",
+ *             "article_count": 12,
+ *             "comment_count": 30,
+ *             "created_at": 1625025011
+ *         }
+ *     },
+ *     "msg": "success"
+ * }
+ *
+ * @apiSuccessExample {json} 本人或管理员:
  * {
  *     "code": 0,
  *     "data": {
@@ -182,10 +271,19 @@ func (ctl *User) Login(c *gin.Context) {
  *             "gender": 5,
  *             "introduce": "介绍",
  *             "github": "https://www.github.com",
+ *             "avatar": "https://blog.example.com/uploads/avatar.png",
  *             "email": "mail@mittacy.com",
- *             "created_at": "2021-06-30T03:50:11Z",
- *             "login_at": "2021-06-30T03:50:11Z"
- *         },
+ *             "article_count": 12,
+ *             "comment_count": 30,
+ *             "created_at": 1625025011,
+ *             "login_at": 1625025011,
+ *             "privacy": {
+ *                 "hide_gender": false,
+ *                 "hide_introduce": false,
+ *                 "hide_github": true,
+ *                 "hide_counts": false
+ *             }
+ *         }
  *     },
  *     "msg": "success"
  * }
@@ -214,3 +312,615 @@ func (ctl *User) GetInfo(c *gin.Context) {
 
 	ctl.transform.GetReply(c, user)
 }
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {post} /password/forgot 忘记密码
+ * @apiName User.ForgotPassword
+ * @apiDescription 向邮箱发送重置密码链接，链接中的token有效期内只能使用一次；
+ * 邮箱未注册时同样返回成功；发送频率和次数限制与注册验证码相同
+ *
+ * @apiParam {string} email 注册邮箱
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 发送过于频繁
+ *     {
+ *       "code": 1101,
+ *       "msg": "验证码发送过于频繁，请稍后再试",
+ *       "data": {}
+ *     }
+ */
+func (ctl *User) ForgotPassword(c *gin.Context) {
+	req := userValidator.ForgotPasswordReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.ForgotPassword(req.Email, c.ClientIP()); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "forgot password", err,
+			apierr.ErrEmailCodeCooldown, apierr.ErrEmailCodeEmailLimit, apierr.ErrEmailCodeIpLimit)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {post} /password/reset 重置密码
+ * @apiName User.ResetPassword
+ * @apiDescription 使用重置密码邮件中的token设置新密码，成功后该用户所有登录设备需要重新登录
+ *
+ * @apiParam {string} token 重置密码邮件中的token
+ * @apiParam {string{8..20}} password 新密码
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 重置密码链接无效或已过期
+ *     {
+ *       "code": 2003,
+ *       "msg": "重置密码链接无效或已过期",
+ *       "data": {}
+ *     }
+ */
+func (ctl *User) ResetPassword(c *gin.Context) {
+	req := userValidator.ResetPasswordReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.ResetPassword(req.Token, req.Password); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "reset password", err, apierr.ErrPasswordResetToken)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {delete} /user/:id/lock 解除账号锁定
+ * @apiName User.Unlock
+ * @apiDescription 管理员操作，解除因多次登录失败被临时锁定的账号
+ *
+ * @apiParam {number{1..}} id 用户id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *User) Unlock(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.userService.Unlock(id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "unlock user", err, apierr.ErrUserNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {put} /user/me 修改个人资料
+ * @apiName User.UpdateProfile
+ *
+ * @apiParam {number=1,5,10} gender 用户性别(1保密,5男,10女)
+ * @apiParam {string{..255}} introduce="空" 个人介绍
+ * @apiParam {string} github github地址
+ * @apiParam {string{..255}} avatar="空" 头像地址
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *User) UpdateProfile(c *gin.Context) {
+	req := userValidator.UpdateProfileReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	user := model.User{}
+	if err := copier.Copy(&user, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+	user.Id = c.GetInt64("userId")
+
+	if err := ctl.userService.UpdateProfile(user); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "update profile", err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {put} /user/me/password 修改密码
+ * @apiName User.UpdatePassword
+ * @apiDescription 修改成功后除当前设备外的其他登录设备需要重新登录
+ *
+ * @apiParam {string} old_password 当前密码
+ * @apiParam {string{8..20}} password 新密码
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 当前密码错误
+ *     {
+ *       "code": 2004,
+ *       "msg": "当前密码错误",
+ *       "data": {}
+ *     }
+ */
+func (ctl *User) UpdatePassword(c *gin.Context) {
+	req := userValidator.UpdatePasswordReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.UpdatePassword(c.GetInt64("userId"), c.GetString("sessionId"), req.OldPassword, req.Password); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "update password", err, apierr.ErrOldPassword)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {delete} /user/me 注销账号
+ * @apiName User.Delete
+ * @apiDescription 注销后用户名、邮箱、密码和个人资料被匿名化，发表的评论保留并显示为匿名用户，
+ * 所有登录设备失效且不可恢复
+ *
+ * @apiParam {string} password 当前密码
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 当前密码错误
+ *     {
+ *       "code": 2004,
+ *       "msg": "当前密码错误",
+ *       "data": {}
+ *     }
+ */
+func (ctl *User) Delete(c *gin.Context) {
+	req := userValidator.DeleteReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.Delete(c.GetInt64("userId"), req.Password); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "delete user", err, apierr.ErrOldPassword)
+		return
+	}
+
+	c.SetCookie("access_token", "", -1, "/", "", false, true)
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {post} /user/me/email/code 获取修改邮箱验证码
+ * @apiName User.SendChangeEmailCode
+ * @apiDescription 向新邮箱发送验证码，发送频率和次数限制与注册验证码相同
+ *
+ * @apiParam {string} email 新邮箱
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 邮箱被注册
+ *     {
+ *       "code": 1,
+ *       "msg": "邮箱已注册",
+ *       "data": {}
+ *     }
+ */
+func (ctl *User) SendChangeEmailCode(c *gin.Context) {
+	req := userValidator.ChangeEmailCodeReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.SendChangeEmailCode(c.GetInt64("userId"), req.Email, c.ClientIP()); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "send change email code", err, apierr.ErrUserEmailExist,
+			apierr.ErrEmailCodeCooldown, apierr.ErrEmailCodeEmailLimit, apierr.ErrEmailCodeIpLimit)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {put} /user/me/email 修改邮箱
+ * @apiName User.ChangeEmail
+ * @apiDescription 修改成功后向旧邮箱发送通知，旧邮箱不能再用于登录
+ *
+ * @apiParam {string} email 新邮箱
+ * @apiParam {string} code 新邮箱收到的验证码
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 验证码错误
+ *     {
+ *       "code": 1,
+ *       "msg": "验证码不正确",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} 邮箱被注册
+ *     {
+ *       "code": 1,
+ *       "msg": "邮箱已注册",
+ *       "data": {}
+ *     }
+ */
+func (ctl *User) ChangeEmail(c *gin.Context) {
+	req := userValidator.ChangeEmailReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.ChangeEmail(c.GetInt64("userId"), req.Email, req.Code); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "change email", err, apierr.ErrUserEmailExist,
+			apierr.ErrRegisterCode, apierr.ErrEmailCodeAttempts)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {put} /user/me/privacy 修改隐私设置
+ * @apiName User.UpdatePrivacy
+ * @apiDescription 被隐藏的信息不会出现在其他用户查看的个人资料中，邮箱和登录时间始终不公开
+ *
+ * @apiParam {bool} hide_gender=false 隐藏性别
+ * @apiParam {bool} hide_introduce=false 隐藏个人介绍
+ * @apiParam {bool} hide_github=false 隐藏github地址
+ * @apiParam {bool} hide_counts=false 隐藏文章数和评论数
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *User) UpdatePrivacy(c *gin.Context) {
+	req := userValidator.PrivacyReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	privacy := 0
+	if req.HideGender {
+		privacy |= model.UserPrivacyHideGender
+	}
+	if req.HideIntroduce {
+		privacy |= model.UserPrivacyHideIntroduce
+	}
+	if req.HideGithub {
+		privacy |= model.UserPrivacyHideGithub
+	}
+	if req.HideCounts {
+		privacy |= model.UserPrivacyHideCounts
+	}
+
+	if err := ctl.userService.UpdatePrivacy(c.GetInt64("userId"), privacy); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "update privacy", err)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {get} /users/admin 管理员查询用户列表
+ * @apiName User.AdminList
+ *
+ * @apiParam {number{1..}} page 页码
+ * @apiParam {number{1..50}} page_size 数据分页大小
+ * @apiParam {string{..64}} [keyword] 用户名或邮箱,模糊匹配
+ * @apiParam {number=1(正常),2(封禁中),3(已注销)} [status] 账号状态,传空则为全部
+ * @apiParam {number} [created_from] 注册时间起始时间戳
+ * @apiParam {number} [created_to] 注册时间截止时间戳
+ * @apiParam {number} [login_from] 最后登录时间起始时间戳
+ * @apiParam {number} [login_to] 最后登录时间截止时间戳
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "list": [
+ *                 {
+ *                     "id": 237,
+ *                     "name": "mittacy",
+ *                     "email": "mittacy@example.com",
+ *                     "avatar": "",
+ *                     "banned": false,
+ *                     "ban_expire_at": 0,
+ *                     "created_at": 1625798089,
+ *                     "login_at": 1625900000,
+ *                     "deleted_at": 0
+ *                 }
+ *             ],
+ *             "total_size": 1
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *User) AdminList(c *gin.Context) {
+	req := userValidator.AdminListReq{}
+	if err := c.ShouldBindQuery(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	filter := model.UserFilter{}
+	if err := copier.Copy(&filter, &req); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	users, totalSize, err := ctl.userService.AdminList(filter, req.Page, req.PageSize)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "admin user list", err)
+		return
+	}
+
+	ctl.transform.AdminListReply(c, users, totalSize)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {get} /user/:id/admin 管理员查询用户详情
+ * @apiName User.AdminGet
+ * @apiDescription 返回完整资料、封禁信息和角色，包括已注销的用户
+ *
+ * @apiParam {number{1..}} id 用户id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {
+ *             "user": {
+ *                 "id": 237,
+ *                 "name": "mittacy",
+ *                 "gender": 5,
+ *                 "introduce": "个人介绍",
+ *                 "github": "https://github.com/mittacy",
+ *                 "avatar": "",
+ *                 "email": "mittacy@example.com",
+ *                 "views": 0,
+ *                 "article_count": 3,
+ *                 "comment_count": 12,
+ *                 "created_at": 1625798089,
+ *                 "updated_at": 1625798089,
+ *                 "login_at": 1625900000,
+ *                 "privacy": {
+ *                     "hide_gender": false,
+ *                     "hide_introduce": false,
+ *                     "hide_github": false,
+ *                     "hide_counts": false
+ *                 },
+ *                 "banned": true,
+ *                 "banned_at": 1625900100,
+ *                 "ban_expire_at": 1626500000,
+ *                 "ban_reason": "发布广告",
+ *                 "deleted_at": 0,
+ *                 "roles": []
+ *             }
+ *         },
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *User) AdminGet(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	user, err := ctl.userService.AdminGet(id)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "admin get user", err, apierr.ErrUserNoExist)
+		return
+	}
+
+	roles, err := ctl.roleService.ListUserRoles(id)
+	if err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "admin get user roles", err)
+		return
+	}
+
+	ctl.transform.AdminGetReply(c, user, roles)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {put} /user/:id/ban 封禁用户
+ * @apiName User.Ban
+ * @apiDescription 封禁期间用户不能登录，已登录的设备全部下线；重复封禁时覆盖原来的原因和到期时间；
+ * 只能封禁拥有的角色都是自己可以授予的角色的用户，不能封禁最后一个可用的超级管理员
+ *
+ * @apiParam {number{1..}} id 用户id
+ * @apiParam {string{1..255}} reason 封禁原因
+ * @apiParam {number} [expire_at] 封禁到期时间戳,不传为永久封禁
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiErrorExample {json} 不能封禁自己
+ *     {
+ *       "code": 2006,
+ *       "msg": "不能封禁自己的账号",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} 到期时间错误
+ *     {
+ *       "code": 2007,
+ *       "msg": "封禁到期时间必须晚于当前时间",
+ *       "data": {}
+ *     }
+ * @apiErrorExample {json} 不能封禁最后一个超级管理员
+ *     {
+ *       "code": 9005,
+ *       "msg": "不能移除最后一个超级管理员",
+ *       "data": {}
+ *     }
+ * @apiUse RoleGrantDenied
+ */
+func (ctl *User) Ban(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	req := userValidator.BanReq{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.ValidateErr(c, err)
+		return
+	}
+
+	if err := ctl.userService.Ban(c.GetInt64("userId"), id, req.Reason, req.ExpireAt); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "ban user", err, apierr.ErrUserNoExist, apierr.ErrUserBanSelf, apierr.ErrUserBanExpire,
+			apierr.ErrRoleGrantDenied, apierr.ErrRoleLastSuperAdmin)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {delete} /user/:id/ban 解除封禁
+ * @apiName User.Unban
+ *
+ * @apiParam {number{1..}} id 用户id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ */
+func (ctl *User) Unban(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.userService.Unban(id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "unban user", err, apierr.ErrUserNoExist)
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+/**
+ * @apiVersion 0.1.0
+ * @apiGroup User
+ * @api {post} /user/:id/password/reset 强制重置密码
+ * @apiName User.ForceResetPassword
+ * @apiDescription 清空用户当前密码并注销所有登录设备，向用户邮箱发送重置密码链接，用户重置密码后才能再次登录；
+ * 只能操作拥有的角色都是自己可以授予的角色的用户
+ *
+ * @apiParam {number{1..}} id 用户id
+ *
+ * @apiSuccessExample {json} Success-Response:
+ *     {
+ *         "code": 0,
+ *         "data": {},
+ *         "msg": "success"
+ *     }
+ *
+ * @apiUse RoleGrantDenied
+ */
+func (ctl *User) ForceResetPassword(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		response.FailMsg(c, "id必须大于0")
+		return
+	}
+
+	if err := ctl.userService.ForceResetPassword(c.GetInt64("userId"), id); err != nil {
+		response.CheckErrAndLog(c, ctl.logger, "force reset password", err, apierr.ErrUserNoExist, apierr.ErrRoleGrantDenied)
+		return
+	}
+
+	response.Success(c, nil)
+}
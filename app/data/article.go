@@ -2,6 +2,7 @@
 package data
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/gomodule/redigo/redis"
 	"github.com/mittacy/blogBack/apierr"
@@ -9,9 +10,12 @@ import (
 	"github.com/mittacy/blogBack/app/service"
 	"github.com/mittacy/blogBack/pkg/logger"
 	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/mittacy/blogBack/utils"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"strconv"
+	"time"
 )
 
 // 实现service层中的data接口
@@ -32,7 +36,72 @@ func NewArticle(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger)
 	}
 }
 
+func NewCommentArticle(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.ICommentArticleData {
+	r := cache.ConnRedisByPool(cacheConn, "article")
+
+	return &Article{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+func NewArticleSchedule(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.IArticleScheduleData {
+	r := cache.ConnRedisByPool(cacheConn, "article")
+
+	return &Article{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+func NewArticleView(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.IArticleViewData {
+	r := cache.ConnRedisByPool(cacheConn, "article")
+
+	return &Article{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+func NewFeedArticle(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.IFeedArticleData {
+	r := cache.ConnRedisByPool(cacheConn, "article")
+
+	return &Article{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+func NewSiteArticle(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.ISiteArticleData {
+	r := cache.ConnRedisByPool(cacheConn, "article")
+
+	return &Article{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+func NewTagArticle(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.ITagArticleData {
+	r := cache.ConnRedisByPool(cacheConn, "article")
+
+	return &Article{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+// Insert 创建文章，只有已发布的文章计入分类和标签的文章数
+// @param article 文章信息
+// @return error
 func (ctl *Article) Insert(article *model.Article) error {
+	published := article.Status == model.ArticleStatusPublished
+
 	tx := ctl.db.Begin()
 
 	// 创建文章
@@ -41,56 +110,324 @@ func (ctl *Article) Insert(article *model.Article) error {
 		return err
 	}
 
-	// 分类文章+1
-	category := model.Category{Id: article.CategoryId}
+	// 校验分类存在，已发布的文章分类文章+1
+	if err := ctl.checkCategory(tx, article.CategoryId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if published {
+		if err := ctl.updateCategoryCount(tx, article.CategoryId, 1); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
-	result := tx.Model(&category).Update("article_count", gorm.Expr("article_count + ?", 1))
+	// 关联标签，已发布的文章标签文章+1
+	if err := ctl.attachTags(tx, article.Id, article.TagIds, published); err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	if result.Error != nil || result.RowsAffected == 0 {
+	// 记录引用的上传文件
+	if err := saveUploadRefs(tx, article.Id, article.Picture, article.Content); err != nil {
 		tx.Rollback()
-		if result.Error != nil {
-			return result.Error
-		}
-		if result.RowsAffected == 0 {
-			return apierr.ErrCategoryNoExist
-		}
+		return err
 	}
 
 	tx.Commit()
 
-	if err := ctl.cache.Del(ctl.cacheSumKey(), ctl.cacheSumByCategoryKey(article.CategoryId)); err != nil {
-		ctl.logger.CacheErrLog(err)
-	}
+	ctl.expireSum(0, []int64{article.CategoryId}, article.TagIds)
 
 	return nil
 }
 
 func (ctl *Article) Delete(id int64) error {
-	article, err := ctl.Get(id)
+	article, err := ctl.GetIgnoreStatus(id)
+	if err != nil {
+		return err
+	}
+
+	tagIds, err := ctl.GetTagIds(id)
 	if err != nil {
 		return err
 	}
 
+	tx := ctl.db.Begin()
+
 	// 删除文章
-	article.Deleted = model.ArticleDeletedYes
+	if err := tx.Model(article).Update("deleted", model.ArticleDeletedYes).Error; err != nil {
+		tx.Rollback()
+		return errors.WithStack(err)
+	}
+
+	// 已发布的文章分类减1
+	published := article.Status == model.ArticleStatusPublished
+	if published {
+		if err := ctl.updateCategoryCount(tx, article.CategoryId, -1); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 
-	if err := ctl.UpdateById(article, []string{"deleted"}); err != nil {
+	// 解除标签关联，已发布的文章标签文章减1
+	if err := ctl.detachTags(tx, id, tagIds, published); err != nil {
+		tx.Rollback()
 		return err
 	}
 
-	// 分类减1
-	category := model.Category{Id: article.CategoryId}
-	if err := ctl.db.Model(&category).Update("article_count", gorm.Expr("article_count - ?", 1)); err != nil {
-		ctl.logger.Sugar().Errorf("update category articleCount err: %s", err.Error)
+	// 删除的文章及其历史版本不再引用上传文件
+	if err := tx.Where("article_id = ?", id).Delete(&model.UploadRef{}).Error; err != nil {
+		tx.Rollback()
+		return errors.WithStack(err)
 	}
 
-	if err = ctl.cache.Del(ctl.cacheSumKey()); err != nil {
-		ctl.logger.CacheErrLog(err)
+	tx.Commit()
+
+	ctl.expireSum(id, []int64{article.CategoryId}, tagIds)
+
+	return nil
+}
+
+// replaceTags 在事务中重新设置文章的标签，已发布的文章维护各标签的文章数
+// 旧标签在事务中加锁读取，防止并发修改时文章数错乱
+// @param tx 事务
+// @param articleId 文章id
+// @param tagIds 新的标签id
+// @param published 文章是否已发布
+// @return []int64 新增和移除的标签id
+// @return error
+func (ctl *Article) replaceTags(tx *gorm.DB, articleId int64, tagIds []int64, published bool) ([]int64, error) {
+	oldTagIds, err := ctl.lockTagIds(tx, articleId)
+	if err != nil {
+		return nil, err
+	}
+
+	newSet := make(map[int64]bool, len(tagIds))
+	for _, v := range tagIds {
+		newSet[v] = true
+	}
+	oldSet := make(map[int64]bool, len(oldTagIds))
+	for _, v := range oldTagIds {
+		oldSet[v] = true
+	}
+
+	var addIds, removeIds []int64
+	for v := range newSet {
+		if !oldSet[v] {
+			addIds = append(addIds, v)
+		}
+	}
+	for v := range oldSet {
+		if !newSet[v] {
+			removeIds = append(removeIds, v)
+		}
+	}
+
+	if err := ctl.detachTags(tx, articleId, removeIds, published); err != nil {
+		return nil, err
+	}
+	if err := ctl.attachTags(tx, articleId, addIds, published); err != nil {
+		return nil, err
+	}
+
+	return append(addIds, removeIds...), nil
+}
+
+// GetTagIds 查询文章关联的标签id
+// @param articleId 文章id
+// @return []int64 标签id
+// @return error
+func (ctl *Article) GetTagIds(articleId int64) ([]int64, error) {
+	tagIds := []int64{}
+
+	err := ctl.db.Model(&model.ArticleTag{}).Where("article_id = ?", articleId).Pluck("tag_id", &tagIds).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return tagIds, nil
+}
+
+// lockTagIds 在事务中加锁查询文章关联的标签id
+func (ctl *Article) lockTagIds(tx *gorm.DB, articleId int64) ([]int64, error) {
+	tagIds := []int64{}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Model(&model.ArticleTag{}).
+		Where("article_id = ?", articleId).Pluck("tag_id", &tagIds).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return tagIds, nil
+}
+
+// attachTags 在事务中关联文章和标签，counted为true时让标签文章数+1
+func (ctl *Article) attachTags(tx *gorm.DB, articleId int64, tagIds []int64, counted bool) error {
+	tagIds = uniqueIds(tagIds)
+	if len(tagIds) == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&model.Tag{}).Where("id in ?", tagIds).Count(&count).Error; err != nil {
+		return errors.WithStack(err)
 	}
+	if count != int64(len(tagIds)) {
+		return apierr.ErrTagNoExist
+	}
+
+	if counted {
+		if err := ctl.updateTagsCount(tx, tagIds, 1); err != nil {
+			return err
+		}
+	}
+
+	articleTags := make([]model.ArticleTag, 0, len(tagIds))
+	for _, tagId := range tagIds {
+		articleTags = append(articleTags, model.ArticleTag{ArticleId: articleId, TagId: tagId})
+	}
+	if err := tx.Create(&articleTags).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// detachTags 在事务中解除文章和标签的关联，counted为true时让标签文章数-1
+func (ctl *Article) detachTags(tx *gorm.DB, articleId int64, tagIds []int64, counted bool) error {
+	if len(tagIds) == 0 {
+		return nil
+	}
+
+	err := tx.Where("article_id = ? and tag_id in ?", articleId, tagIds).Delete(&model.ArticleTag{}).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if counted {
+		return ctl.updateTagsCount(tx, tagIds, -1)
+	}
+
+	return nil
+}
+
+// checkCategory 在事务中校验分类存在
+func (ctl *Article) checkCategory(tx *gorm.DB, categoryId int64) error {
+	var count int64
+	if err := tx.Model(&model.Category{}).Where("id = ?", categoryId).Count(&count).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if count == 0 {
+		return apierr.ErrCategoryNoExist
+	}
+
+	return nil
+}
+
+// updateCategoryCount 在事务中让分类文章数加上delta
+func (ctl *Article) updateCategoryCount(tx *gorm.DB, categoryId int64, delta int) error {
+	err := tx.Model(&model.Category{Id: categoryId}).Update("article_count", gorm.Expr("article_count + ?", delta)).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// updateTagsCount 在事务中让各标签文章数加上delta
+func (ctl *Article) updateTagsCount(tx *gorm.DB, tagIds []int64, delta int) error {
+	err := tx.Model(&model.Tag{}).Where("id in ?", tagIds).Update("article_count", gorm.Expr("article_count + ?", delta)).Error
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// UpdateInfo 更新文章内容，并在同一事务中保存被覆盖的旧内容作为历史版本
+// article.TagIds不为nil时在同一事务中重新设置文章的标签，已发布的文章维护分类和各标签的文章数
+// @param article 文章信息
+// @param updateFields 更新字段
+// @return error
+func (ctl *Article) UpdateInfo(article *model.Article, updateFields []string) error {
+	var changedCategoryIds, changedTagIds []int64
+
+	err := ctl.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定文章，防止并发修改时历史版本和文章数错乱
+		old := model.Article{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "category_id", "title", "preview_ctx", "content", "status").
+			Where("id = ? and deleted = ?", article.Id, model.ArticleDeletedNo).First(&old).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierr.ErrArticleNoExist
+			}
+			return errors.WithStack(err)
+		}
+
+		// 保存历史版本
+		revision := model.ArticleRevision{
+			ArticleId:  old.Id,
+			Title:      old.Title,
+			PreviewCtx: old.PreviewCtx,
+			Content:    old.Content,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		// 更新文章
+		if err := tx.Select(updateFields).Updates(article).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		// 记录新内容引用的上传文件
+		if err := saveUploadRefs(tx, article.Id, article.Content); err != nil {
+			return err
+		}
+
+		// 修改分类时校验新分类存在，已发布的文章把文章数从旧分类移到新分类
+		published := old.Status == model.ArticleStatusPublished
+		if containsField(updateFields, "category_id") && article.CategoryId != old.CategoryId {
+			if err := ctl.checkCategory(tx, article.CategoryId); err != nil {
+				return err
+			}
+			if published {
+				if err := ctl.updateCategoryCount(tx, old.CategoryId, -1); err != nil {
+					return err
+				}
+				if err := ctl.updateCategoryCount(tx, article.CategoryId, 1); err != nil {
+					return err
+				}
+			}
+			changedCategoryIds = []int64{old.CategoryId, article.CategoryId}
+		}
+
+		// 未传标签时不修改文章的标签
+		if article.TagIds == nil {
+			return nil
+		}
+		changedTagIds, err = ctl.replaceTags(tx, article.Id, article.TagIds, published)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	ctl.expireSum(article.Id, changedCategoryIds, changedTagIds)
 
 	return nil
 }
 
+// containsField 判断更新字段中是否包含field
+func containsField(fields []string, field string) bool {
+	for _, v := range fields {
+		if v == field {
+			return true
+		}
+	}
+	return false
+}
+
 func (ctl *Article) UpdateById(article *model.Article, updateFields []string) error {
 	if err := ctl.db.Select(updateFields).Updates(article).Error; err != nil {
 		return errors.WithStack(err)
@@ -99,62 +436,81 @@ func (ctl *Article) UpdateById(article *model.Article, updateFields []string) er
 	if err := ctl.cache.Del(ctl.cacheByIdKey(article.Id)); err != nil {
 		ctl.logger.CacheErrLog(err)
 	}
+	ctl.expireFeed()
 
 	return nil
 }
 
 func (ctl *Article) Get(id int64) (*model.Article, error) {
-	// todo 解决查询和更新view+1清空缓存的冲突
-	///*
-	// * 1. 从 redis 读取
-	// * 2. 不存在 -> 数据库查询, 存入缓存
-	// * 3. 返回
-	// */
-	//cacheKey := ctl.cacheByIdKey(id)
-	//article := &model.Article{}
-	//
-	//// 从缓存库查询
-	//cacheData, err := redis.Bytes(ctl.cache.Do("get", cacheKey))
-	//
-	//if err != nil && !errors.Is(err, redis.ErrNil) {
-	//	return nil, errors.WithStack(err)
-	//}
-	//
-	//// 反序列化失败，重新从DB查询并缓存
-	//if err = json.Unmarshal(cacheData, article); err != nil {
-	//	err = redis.ErrNil
-	//}
-	//
-	//
-	//// 不存在/反序列化 失败，从数据库查询并存入redis
-	//if errors.Is(err, redis.ErrNil) {
-	//	article, err = ctl.GetFromDB(id)
-	//	if err != nil {
-	//		return nil, errors.WithStack(err)
-	//	}
-	//
-	//	// json序列化失败，记录日志，但可以返回成功
-	//	cacheData, err = json.Marshal(article)
-	//	if err != nil {
-	//		ctl.logger.JsonMarshalErrLog(err)
-	//		return article, nil
-	//	}
-	//
-	//	// 缓存不成功记录日志，但可以返回成功
-	//	if err = ctl.cache.CacheString(ctl.cacheByIdKey(id), string(cacheData)); err != nil {
-	//		ctl.logger.CacheErrLog(err)
-	//		return article, nil
-	//	}
-	//}
-	//
-	//// 返回结果
-	//return article, nil
-	return ctl.GetFromDB(id)
+	/*
+	 * 1. 从 redis 读取
+	 * 2. 不存在 -> 数据库查询, 存入缓存
+	 * 3. 返回
+	 * 阅读量先累加在redis中，由后台任务批量写入数据库后再清除缓存，不会与缓存冲突
+	 */
+	cacheKey := ctl.cacheByIdKey(id)
+	article := &model.Article{}
+
+	// 从缓存库查询
+	cacheData, err := redis.Bytes(ctl.cache.Do("get", cacheKey))
+
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return nil, errors.WithStack(err)
+	}
+
+	// 反序列化失败，重新从DB查询并缓存
+	if err == nil {
+		if err = json.Unmarshal(cacheData, article); err != nil {
+			err = redis.ErrNil
+		}
+	}
+
+	// 不存在/反序列化 失败，从数据库查询并存入redis
+	if errors.Is(err, redis.ErrNil) {
+		article, err = ctl.GetFromDB(id)
+		if err != nil {
+			return nil, err
+		}
+
+		// json序列化失败，记录日志，但可以返回成功
+		cacheData, err = json.Marshal(article)
+		if err != nil {
+			ctl.logger.JsonMarshalErrLog(err)
+			return article, nil
+		}
+
+		// 缓存不成功记录日志，但可以返回成功
+		if err = ctl.cache.CacheString(cacheKey, string(cacheData)); err != nil {
+			ctl.logger.CacheErrLog(err)
+			return article, nil
+		}
+	}
+
+	// 返回结果
+	return article, nil
 }
 
 func (ctl *Article) GetFromDB(id int64) (*model.Article, error) {
 	article := model.Article{Id: id}
 
+	err := ctl.db.Where("deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).First(&article).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierr.ErrArticleNoExist
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return &article, nil
+}
+
+// GetIgnoreStatus 查询任意状态的未删除文章，不涉及缓存，供管理员使用
+// @param id 文章id
+// @return *model.Article
+// @return error
+func (ctl *Article) GetIgnoreStatus(id int64) (*model.Article, error) {
+	article := model.Article{Id: id}
+
 	if err := ctl.db.Where("deleted = ?", model.ArticleDeletedNo).First(&article).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, apierr.ErrArticleNoExist
@@ -165,6 +521,138 @@ func (ctl *Article) GetFromDB(id int64) (*model.Article, error) {
 	return &article, nil
 }
 
+// UpdateStatus 更新文章状态和发布时间，文章进入或离开已发布状态时在同一事务中维护分类和各标签的文章数
+// 文章是否可见发生变化，需要清除各个计数缓存
+// @param article 文章信息，需要包含id、status、publish_at
+// @return error
+func (ctl *Article) UpdateStatus(article *model.Article) error {
+	old := model.Article{}
+	var tagIds []int64
+
+	err := ctl.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定文章，防止与定时发布并发时重复计数
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "category_id", "status").
+			Where("id = ? and deleted = ?", article.Id, model.ArticleDeletedNo).First(&old).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierr.ErrArticleNoExist
+			}
+			return errors.WithStack(err)
+		}
+
+		err = tx.Model(&model.Article{Id: article.Id}).
+			Updates(map[string]interface{}{"status": article.Status, "publish_at": article.PublishAt}).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if tagIds, err = ctl.lockTagIds(tx, article.Id); err != nil {
+			return err
+		}
+
+		wasPublished := old.Status == model.ArticleStatusPublished
+		isPublished := article.Status == model.ArticleStatusPublished
+		if wasPublished == isPublished {
+			return nil
+		}
+
+		delta := 1
+		if wasPublished {
+			delta = -1
+		}
+		if err := ctl.updateCategoryCount(tx, old.CategoryId, delta); err != nil {
+			return err
+		}
+		if len(tagIds) > 0 {
+			return ctl.updateTagsCount(tx, tagIds, delta)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctl.expireSum(article.Id, []int64{old.CategoryId}, tagIds)
+
+	return nil
+}
+
+// PublishScheduled 发布所有已到发布时间的定时文章，并在同一事务中让分类和各标签的文章数增加
+// @param now 当前时间戳
+// @return []model.Article 本次发布的文章
+// @return error
+func (ctl *Article) PublishScheduled(now int64) ([]model.Article, error) {
+	var articles []model.Article
+	var tagIds []int64
+
+	err := ctl.db.Transaction(func(tx *gorm.DB) error {
+		// 锁定到期的定时文章，防止与管理员修改状态并发时覆盖状态或重复计数
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("status = ? and publish_at <= ? and deleted = ?", model.ArticleStatusScheduled, now, model.ArticleDeletedNo).
+			Find(&articles).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		if len(articles) == 0 {
+			return nil
+		}
+
+		ids := make([]int64, 0, len(articles))
+		categoryCounts := make(map[int64]int)
+		for i := 0; i < len(articles); i++ {
+			ids = append(ids, articles[i].Id)
+			categoryCounts[articles[i].CategoryId]++
+			articles[i].Status = model.ArticleStatusPublished
+		}
+
+		err = tx.Model(&model.Article{}).Where("id in ?", ids).Update("status", model.ArticleStatusPublished).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for categoryId, count := range categoryCounts {
+			if err := ctl.updateCategoryCount(tx, categoryId, count); err != nil {
+				return err
+			}
+		}
+
+		var tagCounts []struct {
+			TagId int64
+			Count int
+		}
+		err = tx.Model(&model.ArticleTag{}).Select("tag_id, count(*) as count").Where("article_id in ?", ids).
+			Group("tag_id").Scan(&tagCounts).Error
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for _, v := range tagCounts {
+			if err := ctl.updateTagsCount(tx, []int64{v.TagId}, v.Count); err != nil {
+				return err
+			}
+			tagIds = append(tagIds, v.TagId)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(articles) == 0 {
+		return articles, nil
+	}
+
+	categoryIds := make([]int64, 0, len(articles))
+	for _, v := range articles {
+		categoryIds = append(categoryIds, v.CategoryId)
+	}
+	ctl.expireSum(0, categoryIds, tagIds)
+
+	return articles, nil
+}
+
 func (ctl *Article) GetSum() (int64, error) {
 	/*
 	 * 1. 从 redis 读取
@@ -227,11 +715,43 @@ func (ctl *Article) GetSumByCategory(categoryId int64) (int64, error) {
 	return count, nil
 }
 
+func (ctl *Article) GetSumByTag(tagId int64) (int64, error) {
+	/*
+	 * 1. 从 redis 读取
+	 * 2. 不存在 => 数据库查询，存入 redis
+	 * 3. 返回
+	 */
+	// 从缓存库查询
+	count, err := redis.Int64(ctl.cache.Do("get", ctl.cacheSumByTagKey(tagId)))
+
+	// 缓存查询出错
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return 0, errors.WithStack(err)
+	}
+
+	// 不存在, 从数据库查询并存入redis
+	if errors.Is(err, redis.ErrNil) {
+		count, err = ctl.GetSumByTagFromDB(tagId)
+		if err != nil {
+			return 0, err
+		}
+
+		// 缓存不成功只记录错误日志，但可以返回成功
+		if err = ctl.cache.CacheString(ctl.cacheSumByTagKey(tagId), strconv.FormatInt(count, 10)); err != nil {
+			ctl.logger.CacheErrLog(err)
+		}
+	}
+
+	// 返回
+	return count, nil
+}
+
 func (ctl *Article) GetSumFromDB() (int64, error) {
 	article := model.Article{}
 	var count int64
 
-	err := ctl.db.Model(&article).Select("count(*)").Where("deleted = ?", model.ArticleDeletedNo).Find(&count).Error
+	err := ctl.db.Model(&article).Select("count(*)").
+		Where("deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).Find(&count).Error
 
 	if err != nil {
 		return 0, err
@@ -245,7 +765,23 @@ func (ctl *Article) GetSumByCategoryFromDB(categoryId int64) (int64, error) {
 	var count int64
 
 	err := ctl.db.Model(&article).Select("count(*)").
-		Where("category_id = ? and deleted = ?", categoryId, model.ArticleDeletedNo).Find(&count).Error
+		Where("category_id = ? and deleted = ? and status = ?", categoryId, model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Find(&count).Error
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (ctl *Article) GetSumByTagFromDB(tagId int64) (int64, error) {
+	var count int64
+
+	err := ctl.db.Model(&model.Article{}).Select("count(*)").
+		Joins("join article_tag on article_tag.article_id = article.id").
+		Where("article_tag.tag_id = ? and article.deleted = ? and article.status = ?", tagId, model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Find(&count).Error
 
 	if err != nil {
 		return 0, err
@@ -258,7 +794,7 @@ func (ctl *Article) List(selectFields []string, page, pageSize int) ([]model.Art
 	startIndex := (page - 1) * pageSize
 	var articles []model.Article
 
-	err := ctl.db.Select(selectFields).Where("deleted != ?", 1).
+	err := ctl.db.Select(selectFields).Where("deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).
 		Offset(startIndex).Limit(pageSize).Order("created_at desc").Find(&articles).Error
 	if err != nil {
 		return nil, err
@@ -271,7 +807,8 @@ func (ctl *Article) ListByCategory(selectFields []string, categoryId int64, page
 	startIndex := (page - 1) * pageSize
 	var articles []model.Article
 
-	err := ctl.db.Select(selectFields).Where("category_id = ? and deleted = ?", categoryId, model.ArticleDeletedNo).
+	err := ctl.db.Select(selectFields).
+		Where("category_id = ? and deleted = ? and status = ?", categoryId, model.ArticleDeletedNo, model.ArticleStatusPublished).
 		Offset(startIndex).Limit(pageSize).Order("created_at desc").Find(&articles).Error
 	if err != nil {
 		return nil, err
@@ -280,10 +817,19 @@ func (ctl *Article) ListByCategory(selectFields []string, categoryId int64, page
 	return articles, nil
 }
 
-func (ctl *Article) ListByWeight(selectFields []string, count int) ([]model.Article, error) {
+func (ctl *Article) ListByTag(selectFields []string, tagId int64, page, pageSize int) ([]model.Article, error) {
+	startIndex := (page - 1) * pageSize
 	var articles []model.Article
-	err := ctl.db.Select(selectFields).Where("weight > 0").Order("weight desc, created_at desc").Limit(count).Find(&articles).Error
 
+	// 关联查询时字段需要带上表名，避免歧义
+	fields := make([]string, 0, len(selectFields))
+	for _, v := range selectFields {
+		fields = append(fields, "article."+v)
+	}
+
+	err := ctl.db.Select(fields).Joins("join article_tag on article_tag.article_id = article.id").
+		Where("article_tag.tag_id = ? and article.deleted = ? and article.status = ?", tagId, model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Offset(startIndex).Limit(pageSize).Order("article.created_at desc").Find(&articles).Error
 	if err != nil {
 		return nil, err
 	}
@@ -291,19 +837,405 @@ func (ctl *Article) ListByWeight(selectFields []string, count int) ([]model.Arti
 	return articles, nil
 }
 
-func (ctl *Article) IncrView(id int64) error {
-	article := model.Article{Id: id}
+// ListByStatus 分页查询指定状态的文章，供管理员使用
+// @param selectFields 查询字段
+// @param status 文章状态，为0时查询全部状态
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.Article
+// @return error
+func (ctl *Article) ListByStatus(selectFields []string, status int8, page, pageSize int) ([]model.Article, error) {
+	startIndex := (page - 1) * pageSize
+	var articles []model.Article
+
+	err := ctl.scopeStatus(status).Select(selectFields).
+		Offset(startIndex).Limit(pageSize).Order("created_at desc").Find(&articles).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return articles, nil
+}
+
+// GetSumByStatus 查询指定状态的文章数，不涉及缓存
+// @param status 文章状态，为0时查询全部状态
+// @return int64
+// @return error
+func (ctl *Article) GetSumByStatus(status int8) (int64, error) {
+	var count int64
+
+	if err := ctl.scopeStatus(status).Model(&model.Article{}).Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+func (ctl *Article) scopeStatus(status int8) *gorm.DB {
+	db := ctl.db.Where("deleted = ?", model.ArticleDeletedNo)
+	if status > 0 {
+		db = db.Where("status = ?", status)
+	}
+	return db
+}
 
-	if err := ctl.db.Model(&article).Update("views", gorm.Expr("views + ?", 1)).Error; err != nil {
+// expireSum 清除文章详情和文章数的缓存
+// @param id 文章id，为0时不清除文章详情缓存
+// @param categoryIds 分类id
+// @param tagIds 标签id
+func (ctl *Article) expireSum(id int64, categoryIds []int64, tagIds []int64) {
+	keys := []interface{}{ctl.cacheSumKey()}
+	if id > 0 {
+		keys = append(keys, ctl.cacheByIdKey(id))
+	}
+	for _, v := range uniqueIds(categoryIds) {
+		keys = append(keys, ctl.cacheSumByCategoryKey(v))
+	}
+	for _, v := range uniqueIds(tagIds) {
+		keys = append(keys, ctl.cacheSumByTagKey(v))
+	}
+
+	if err := ctl.cache.Del(keys...); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+	ctl.expireFeed()
+}
+
+// GetFeed 查询缓存的订阅源
+// @param name 订阅源名
+// @return []byte 订阅源文档，缓存不存在时返回nil
+// @return error
+func (ctl *Article) GetFeed(name string) ([]byte, error) {
+	key, err := ctl.cacheFeedKey(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := redis.Bytes(ctl.cache.Do("get", key))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return data, nil
+}
+
+// CacheFeed 缓存订阅源
+// @param name 订阅源名
+// @param data 订阅源文档
+func (ctl *Article) CacheFeed(name string, data []byte) error {
+	key, err := ctl.cacheFeedKey(name)
+	if err != nil {
 		return err
 	}
 
-	// todo 解决查询和更新view+1清空缓存的冲突
-	//ctl.cache.Del(ctl.cacheByIdKey(id))
+	if err := ctl.cache.CacheString(key, string(data)); err != nil {
+		return errors.WithStack(err)
+	}
 
 	return nil
 }
 
+// ListAll 查询所有对外可见的文章，用于生成sitemap
+// @param selectFields 查询的字段
+// @return []model.Article 按id升序
+// @return error
+func (ctl *Article) ListAll(selectFields []string) ([]model.Article, error) {
+	var articles []model.Article
+
+	err := ctl.db.Select(selectFields).Where("deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Order("id asc").Find(&articles).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return articles, nil
+}
+
+// GetSitemap 查询缓存的sitemap
+// @param name sitemap名
+// @return []byte sitemap文档，缓存不存在时返回nil
+// @return error
+func (ctl *Article) GetSitemap(name string) ([]byte, error) {
+	data, err := redis.Bytes(ctl.cache.Do("get", ctl.cacheSitemapKey(name)))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return data, nil
+}
+
+// CacheSitemap 缓存sitemap，过期后下次访问时重新生成
+// @param name sitemap名
+// @param data sitemap文档
+func (ctl *Article) CacheSitemap(name string, data []byte) error {
+	if err := ctl.cache.CacheString(ctl.cacheSitemapKey(name), string(data)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// expireFeed 让所有订阅源缓存失效
+// 分类订阅源数量不固定，通过递增版本号使旧缓存失效，旧缓存到期后自动删除
+func (ctl *Article) expireFeed() {
+	if _, err := ctl.cache.Do("incr", ctl.cacheFeedVersionKey()); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+}
+
+// ExpireSumByTag 让标签的文章数缓存失效
+// @param tagId 标签id
+func (ctl *Article) ExpireSumByTag(tagId int64) {
+	if err := ctl.cache.Del(ctl.cacheSumByTagKey(tagId)); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+}
+
+func (ctl *Article) ListByWeight(selectFields []string, count int) ([]model.Article, error) {
+	var articles []model.Article
+	err := ctl.db.Select(selectFields).
+		Where("weight > 0 and deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Order("weight desc, created_at desc").Limit(count).Find(&articles).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// IncrView 文章阅读量+1，先累加在redis中，由FlushViews批量写入数据库
+func (ctl *Article) IncrView(id int64) error {
+	if _, err := ctl.cache.Do("hincrby", ctl.cacheViewsPendingKey(), id, 1); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// GetPendingViews 查询文章还未写入数据库的阅读量
+// @param id 文章id
+// @return int64 未写入的阅读量
+// @return error
+func (ctl *Article) GetPendingViews(id int64) (int64, error) {
+	var sum int64
+
+	// 正在写入数据库的阅读量也未体现在缓存的文章中
+	for _, key := range []string{ctl.cacheViewsPendingKey(), ctl.cacheViewsFlushingKey()} {
+		views, err := redis.Int64(ctl.cache.Do("hget", key, id))
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			return 0, errors.WithStack(err)
+		}
+		sum += views
+	}
+
+	return sum, nil
+}
+
+// releaseLockScript 锁的值与加锁时的随机串一致时才删除锁
+// KEYS[1] 锁的键
+// ARGV[1] 加锁时的随机串
+var releaseLockScript = redis.NewScript(1, `
+if redis.call('get', KEYS[1]) == ARGV[1] then
+	return redis.call('del', KEYS[1])
+end
+return 0
+`)
+
+// releaseViewsLock 释放写入阅读量的锁，释放失败时锁在有效期后自动过期
+// @param lockKey 锁的键
+// @param lockToken 加锁时的随机串
+func (ctl *Article) releaseViewsLock(lockKey, lockToken string) {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		ctl.logger.CacheErrLog(err)
+		return
+	}
+	defer conn.Close()
+
+	if _, err := releaseLockScript.Do(conn, lockKey, lockToken); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+}
+
+// startFlushViewsScript 将累加中的阅读量整体转移为待写入的批次，并为批次分配id
+// 上次写入失败遗留的批次优先写入，沿用原来的批次id；没有需要写入的阅读量时返回nil
+// KEYS[1] 累加中的阅读量 KEYS[2] 待写入的阅读量 KEYS[3] 批次id
+// ARGV[1] 新的批次id
+var startFlushViewsScript = redis.NewScript(3, `
+if redis.call('exists', KEYS[2]) == 0 then
+	if redis.call('exists', KEYS[1]) == 0 then
+		return false
+	end
+	redis.call('rename', KEYS[1], KEYS[2])
+	redis.call('set', KEYS[3], ARGV[1])
+end
+local id = redis.call('get', KEYS[3])
+if not id then
+	redis.call('set', KEYS[3], ARGV[1])
+	id = ARGV[1]
+end
+return id
+`)
+
+// FlushViews 将redis中累加的阅读量批量写入数据库，并清除对应文章的缓存
+// 批次id与阅读量在同一事务中写入，批次已写入时只删除redis中的数据，重试不会重复累加
+// @return int 写入的文章数
+// @return error
+func (ctl *Article) FlushViews() (int, error) {
+	// 多个实例同时运行时，只允许一个实例写入
+	// 锁的值为随机串，写入超过锁的有效期后不会释放其他实例重新获取的锁
+	lockKey := ctl.cacheViewsLockKey()
+	lockToken, err := utils.RandToken(16)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	_, err = redis.String(ctl.cache.Do("set", lockKey, lockToken, "nx", "ex", 60))
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return 0, nil
+		}
+		return 0, errors.WithStack(err)
+	}
+	defer ctl.releaseViewsLock(lockKey, lockToken)
+
+	flushId, err := ctl.startFlushViews()
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			// 没有需要写入的阅读量
+			return 0, nil
+		}
+		return 0, errors.WithStack(err)
+	}
+
+	flushingKey := ctl.cacheViewsFlushingKey()
+	views, err := redis.Int64Map(ctl.cache.Do("hgetall", flushingKey))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	ids := make([]int64, 0, len(views))
+	counts := make(map[int64]int64, len(views))
+	for k, v := range views {
+		id, err := strconv.ParseInt(k, 10, 64)
+		if err != nil || v <= 0 {
+			continue
+		}
+		ids = append(ids, id)
+		counts[id] = v
+	}
+
+	applied := false
+	err = ctl.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&model.ArticleViewFlush{}).Where("id = ?", flushId).Count(&count).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		// 批次已写入，上次删除redis中的数据失败
+		if count > 0 {
+			return nil
+		}
+
+		for _, id := range ids {
+			// UpdateColumn 不会修改updated_at
+			err := tx.Model(&model.Article{Id: id}).UpdateColumn("views", gorm.Expr("views + ?", counts[id])).Error
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+
+		if err := tx.Create(&model.ArticleViewFlush{Id: flushId}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		// 清除过期的批次记录
+		expired := time.Now().Unix() - model.ArticleViewFlushKeep
+		if err := tx.Where("created_at < ?", expired).Delete(&model.ArticleViewFlush{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		applied = true
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// 写入成功后删除转移的数据，并让文章缓存失效
+	keys := make([]interface{}, 0, len(ids)+2)
+	keys = append(keys, flushingKey, ctl.cacheViewsFlushIdKey())
+	for _, id := range ids {
+		keys = append(keys, ctl.cacheByIdKey(id))
+	}
+	if err := ctl.cache.Del(keys...); err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	if !applied {
+		return 0, nil
+	}
+	return len(ids), nil
+}
+
+// startFlushViews 转移累加中的阅读量，返回批次id，没有需要写入的阅读量时返回redis.ErrNil
+func (ctl *Article) startFlushViews() (string, error) {
+	newId, err := utils.RandToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	return redis.String(startFlushViewsScript.Do(conn, ctl.cacheViewsPendingKey(), ctl.cacheViewsFlushingKey(),
+		ctl.cacheViewsFlushIdKey(), newId))
+}
+
+// cacheViewsPendingKey 累加中的阅读量 hash: 文章id => 阅读量
+func (ctl *Article) cacheViewsPendingKey() string {
+	return fmt.Sprintf("%s:views:pending", ctl.cache.CachePrefixKey())
+}
+
+// cacheViewsFlushingKey 正在写入数据库的阅读量 hash
+func (ctl *Article) cacheViewsFlushingKey() string {
+	return fmt.Sprintf("%s:views:flushing", ctl.cache.CachePrefixKey())
+}
+
+// cacheViewsFlushIdKey 正在写入数据库的阅读量的批次id
+func (ctl *Article) cacheViewsFlushIdKey() string {
+	return fmt.Sprintf("%s:views:flushing:id", ctl.cache.CachePrefixKey())
+}
+
+func (ctl *Article) cacheViewsLockKey() string {
+	return fmt.Sprintf("%s:views:lock", ctl.cache.CachePrefixKey())
+}
+
+func (ctl *Article) cacheSitemapKey(name string) string {
+	return fmt.Sprintf("%s:sitemap:%s", ctl.cache.CachePrefixKey(), name)
+}
+
+func (ctl *Article) cacheFeedVersionKey() string {
+	return fmt.Sprintf("%s:feed:version", ctl.cache.CachePrefixKey())
+}
+
+func (ctl *Article) cacheFeedKey(name string) (string, error) {
+	version, err := redis.Int64(ctl.cache.Do("get", ctl.cacheFeedVersionKey()))
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return "", errors.WithStack(err)
+	}
+
+	return fmt.Sprintf("%s:feed:v%d:%s", ctl.cache.CachePrefixKey(), version, name), nil
+}
+
 func (ctl *Article) cacheByIdKey(id int64) string {
 	return fmt.Sprintf("%s:id#%d", ctl.cache.CachePrefixKey(), id)
 }
@@ -316,3 +1248,21 @@ func (ctl *Article) cacheSumByCategoryKey(categoryId int64) string {
 	return fmt.Sprintf("%s:sum:categoryId#%d", ctl.cache.CachePrefixKey(), categoryId)
 }
 
+func (ctl *Article) cacheSumByTagKey(tagId int64) string {
+	return fmt.Sprintf("%s:sum:tagId#%d", ctl.cache.CachePrefixKey(), tagId)
+}
+
+// uniqueIds id去重
+func uniqueIds(ids []int64) []int64 {
+	set := make(map[int64]bool, len(ids))
+	res := make([]int64, 0, len(ids))
+
+	for _, v := range ids {
+		if !set[v] {
+			set[v] = true
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 实现service层中的data接口
+
+type ArticleRevision struct {
+	db     *gorm.DB
+	logger *logger.CustomLogger
+}
+
+func NewArticleRevision(db *gorm.DB, logger *logger.CustomLogger) service.IArticleRevisionData {
+	return &ArticleRevision{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Get 查询文章的某个历史版本
+// @param articleId 文章id
+// @param id 版本id
+// @return *model.ArticleRevision
+// @return error
+func (ctl *ArticleRevision) Get(articleId, id int64) (*model.ArticleRevision, error) {
+	revision := model.ArticleRevision{}
+
+	if err := ctl.db.Where("id = ? and article_id = ?", id, articleId).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierr.ErrArticleRevisionNoExist
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return &revision, nil
+}
+
+// List 分页查询文章的历史版本，新版本在前
+// @param selectFields 查询字段
+// @param articleId 文章id
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.ArticleRevision
+// @return error
+func (ctl *ArticleRevision) List(selectFields []string, articleId int64, page, pageSize int) ([]model.ArticleRevision, error) {
+	startIndex := (page - 1) * pageSize
+	var revisions []model.ArticleRevision
+
+	err := ctl.db.Select(selectFields).Where("article_id = ?", articleId).
+		Offset(startIndex).Limit(pageSize).Order("id desc").Find(&revisions).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return revisions, nil
+}
+
+// GetSum 查询文章的历史版本数
+// @param articleId 文章id
+// @return int64
+// @return error
+func (ctl *ArticleRevision) GetSum(articleId int64) (int64, error) {
+	var count int64
+
+	if err := ctl.db.Model(&model.ArticleRevision{}).Where("article_id = ?", articleId).Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
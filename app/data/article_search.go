@@ -0,0 +1,227 @@
+package data
+
+import (
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 实现service层中的data接口
+
+// ArticleSearch 基于Mysql FULLTEXT索引的文章搜索，索引由Mysql维护
+type ArticleSearch struct {
+	db     *gorm.DB
+	logger *logger.CustomLogger
+}
+
+func NewArticleSearch(db *gorm.DB, logger *logger.CustomLogger) service.IArticleSearcher {
+	return &ArticleSearch{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const articleMatchSql = "MATCH (title, preview_ctx, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+func (ctl *ArticleSearch) Search(selectFields []string, q string, page, pageSize int) ([]model.Article, int64, error) {
+	startIndex := (page - 1) * pageSize
+	var (
+		articles []model.Article
+		count    int64
+	)
+
+	err := ctl.db.Model(&model.Article{}).Where("deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Where(articleMatchSql, q).Count(&count).Error
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	if count == 0 {
+		return []model.Article{}, 0, nil
+	}
+
+	// 按相关度排序，相关度相同时新文章在前
+	err = ctl.db.Select(selectFields).Where("deleted = ? and status = ?", model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Where(articleMatchSql, q).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                articleMatchSql + " desc, created_at desc",
+			Vars:               []interface{}{q},
+			WithoutParentheses: true,
+		}}).
+		Offset(startIndex).Limit(pageSize).Find(&articles).Error
+	if err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+
+	return articles, count, nil
+}
+
+func (ctl *ArticleSearch) Index(article model.Article) error {
+	return nil
+}
+
+func (ctl *ArticleSearch) Remove(id int64) error {
+	return nil
+}
+
+// ArticleMemorySearch 进程内的倒排索引，用于测试或没有全文索引的环境
+type ArticleMemorySearch struct {
+	mu    sync.RWMutex
+	docs  map[int64]model.Article
+	index map[string]map[int64]float64 // 词 => 文章id => 加权词频
+}
+
+func NewArticleMemorySearch() service.IArticleSearcher {
+	return &ArticleMemorySearch{
+		docs:  make(map[int64]model.Article, 0),
+		index: make(map[string]map[int64]float64, 0),
+	}
+}
+
+// 各字段命中时的权重
+const (
+	searchWeightTitle   = 3.0
+	searchWeightPreview = 2.0
+	searchWeightContent = 1.0
+)
+
+func (ctl *ArticleMemorySearch) Search(selectFields []string, q string, page, pageSize int) ([]model.Article, int64, error) {
+	ctl.mu.RLock()
+	defer ctl.mu.RUnlock()
+
+	// 相关度: 每个词的加权词频 * 逆文档频率 之和
+	scores := make(map[int64]float64, 0)
+	for _, term := range tokenize(q) {
+		postings, ok := ctl.index[term]
+		if !ok {
+			continue
+		}
+
+		idf := math.Log(1 + float64(len(ctl.docs))/float64(len(postings)))
+		for id, tf := range postings {
+			scores[id] += tf * idf
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ctl.docs[ids[i]].CreatedAt > ctl.docs[ids[j]].CreatedAt
+	})
+
+	start := (page - 1) * pageSize
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + pageSize
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	articles := make([]model.Article, 0, end-start)
+	for _, id := range ids[start:end] {
+		articles = append(articles, ctl.docs[id])
+	}
+
+	return articles, int64(len(ids)), nil
+}
+
+func (ctl *ArticleMemorySearch) Index(article model.Article) error {
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+
+	ctl.remove(article.Id)
+
+	if article.Deleted == model.ArticleDeletedYes {
+		return nil
+	}
+
+	ctl.docs[article.Id] = article
+
+	fields := []struct {
+		text   string
+		weight float64
+	}{
+		{article.Title, searchWeightTitle},
+		{article.PreviewCtx, searchWeightPreview},
+		{article.Content, searchWeightContent},
+	}
+	for _, field := range fields {
+		for _, term := range tokenize(field.text) {
+			if _, ok := ctl.index[term]; !ok {
+				ctl.index[term] = make(map[int64]float64, 0)
+			}
+			ctl.index[term][article.Id] += field.weight
+		}
+	}
+
+	return nil
+}
+
+func (ctl *ArticleMemorySearch) Remove(id int64) error {
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+
+	ctl.remove(id)
+	return nil
+}
+
+func (ctl *ArticleMemorySearch) remove(id int64) {
+	if _, ok := ctl.docs[id]; !ok {
+		return
+	}
+
+	delete(ctl.docs, id)
+	for term, postings := range ctl.index {
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(ctl.index, term)
+		}
+	}
+}
+
+// tokenize 分词: 字母数字连续串作为一个词，中日韩文字按单字切分
+// @param text 文本
+// @return []string 小写的词
+func tokenize(text string) []string {
+	var (
+		terms []string
+		word  strings.Builder
+	)
+
+	flush := func() {
+		if word.Len() > 0 {
+			terms = append(terms, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return terms
+}
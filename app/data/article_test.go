@@ -0,0 +1,366 @@
+package data
+
+import (
+	"fmt"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTestDB 创建内存sqlite数据库并建好文章相关的表，每个测试独立
+func newTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite err: %s", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql.DB err: %s", err)
+	}
+	// 内存数据库在最后一个连接关闭时销毁
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(&model.Article{}, &model.Category{}, &model.Tag{}, &model.ArticleTag{}, &model.ArticleRevision{},
+		&model.ArticleViewFlush{}, &model.UploadRef{})
+	if err != nil {
+		t.Fatalf("migrate err: %s", err)
+	}
+
+	return db
+}
+
+// newTestArticle 创建分类1、标签1~3，返回文章数据层
+func newTestArticle(t *testing.T) (*Article, *gorm.DB) {
+	data, db, _ := newTestArticleWithRedis(t)
+	return data, db
+}
+
+func newTestArticleWithRedis(t *testing.T) (*Article, *gorm.DB, *miniredis.Miniredis) {
+	db := newTestDB(t)
+	pool, s := newTestRedisPool(t)
+
+	if err := db.Create(&model.Category{Id: 1, Name: "go"}).Error; err != nil {
+		t.Fatalf("create category err: %s", err)
+	}
+	for i := int64(1); i <= 3; i++ {
+		if err := db.Create(&model.Tag{Id: i, Name: fmt.Sprintf("tag%d", i)}).Error; err != nil {
+			t.Fatalf("create tag err: %s", err)
+		}
+	}
+
+	return NewArticle(db, pool, nil).(*Article), db, s
+}
+
+func articleTagIds(t *testing.T, db *gorm.DB, articleId int64) []int64 {
+	ids := []int64{}
+	if err := db.Model(&model.ArticleTag{}).Where("article_id = ?", articleId).Pluck("tag_id", &ids).Error; err != nil {
+		t.Fatalf("query article tags err: %s", err)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// tagCounts 返回标签1~3的文章数
+func tagCounts(t *testing.T, db *gorm.DB) []int {
+	tags := []model.Tag{}
+	if err := db.Order("id").Find(&tags).Error; err != nil {
+		t.Fatalf("query tags err: %s", err)
+	}
+	counts := make([]int, 0, len(tags))
+	for _, v := range tags {
+		counts = append(counts, v.ArticleCount)
+	}
+	return counts
+}
+
+func countRevisions(t *testing.T, db *gorm.DB, articleId int64) int64 {
+	var count int64
+	if err := db.Model(&model.ArticleRevision{}).Where("article_id = ?", articleId).Count(&count).Error; err != nil {
+		t.Fatalf("count revisions err: %s", err)
+	}
+	return count
+}
+
+var testArticleFields = []string{"category_id", "title", "preview_ctx", "content", "content_html", "toc"}
+
+func TestArticleUpdateInfoTags(t *testing.T) {
+	data, db := newTestArticle(t)
+
+	article := model.Article{CategoryId: 1, Title: "old", Status: model.ArticleStatusPublished, TagIds: []int64{1, 2}}
+	if err := data.Insert(&article); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	update := model.Article{Id: article.Id, CategoryId: 1, Title: "new", TagIds: []int64{2, 3}}
+	if err := data.UpdateInfo(&update, testArticleFields); err != nil {
+		t.Fatalf("UpdateInfo() error = %v", err)
+	}
+
+	if got := articleTagIds(t, db, article.Id); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("article tags = %v, want [2 3]", got)
+	}
+	if got := tagCounts(t, db); !reflect.DeepEqual(got, []int{0, 1, 1}) {
+		t.Errorf("tag article counts = %v, want [0 1 1]", got)
+	}
+	if got := countRevisions(t, db, article.Id); got != 1 {
+		t.Errorf("revisions = %d, want 1", got)
+	}
+
+	// 未传标签时不修改文章的标签
+	update = model.Article{Id: article.Id, CategoryId: 1, Title: "newer"}
+	if err := data.UpdateInfo(&update, testArticleFields); err != nil {
+		t.Fatalf("UpdateInfo() without tags error = %v", err)
+	}
+	if got := articleTagIds(t, db, article.Id); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("article tags without tags = %v, want [2 3]", got)
+	}
+}
+
+func TestArticleUpdateInfoRollback(t *testing.T) {
+	data, db := newTestArticle(t)
+
+	article := model.Article{CategoryId: 1, Title: "old", Status: model.ArticleStatusPublished, TagIds: []int64{1, 2}}
+	if err := data.Insert(&article); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	// 标签99不存在，修改标签失败时文章内容、历史版本和标签文章数都要回滚
+	update := model.Article{Id: article.Id, CategoryId: 1, Title: "new", TagIds: []int64{2, 99}}
+	if err := data.UpdateInfo(&update, testArticleFields); err != apierr.ErrTagNoExist {
+		t.Fatalf("UpdateInfo() error = %v, want ErrTagNoExist", err)
+	}
+
+	got := model.Article{}
+	if err := db.First(&got, article.Id).Error; err != nil {
+		t.Fatalf("query article err: %s", err)
+	}
+	if got.Title != "old" {
+		t.Errorf("title = %q, want old", got.Title)
+	}
+	if ids := articleTagIds(t, db, article.Id); !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("article tags = %v, want [1 2]", ids)
+	}
+	if counts := tagCounts(t, db); !reflect.DeepEqual(counts, []int{1, 1, 0}) {
+		t.Errorf("tag article counts = %v, want [1 1 0]", counts)
+	}
+	if n := countRevisions(t, db, article.Id); n != 0 {
+		t.Errorf("revisions = %d, want 0", n)
+	}
+}
+
+func categoryCount(t *testing.T, db *gorm.DB, categoryId int64) int {
+	category := model.Category{}
+	if err := db.First(&category, categoryId).Error; err != nil {
+		t.Fatalf("query category err: %s", err)
+	}
+	return category.ArticleCount
+}
+
+func TestArticleCountsPublishedOnly(t *testing.T) {
+	data, db := newTestArticle(t)
+
+	// checkCounts 校验分类1和标签1~3的文章数
+	checkCounts := func(step string, wantCategory int, wantTags []int) {
+		t.Helper()
+		if got := categoryCount(t, db, 1); got != wantCategory {
+			t.Errorf("%s: category article count = %d, want %d", step, got, wantCategory)
+		}
+		if got := tagCounts(t, db); !reflect.DeepEqual(got, wantTags) {
+			t.Errorf("%s: tag article counts = %v, want %v", step, got, wantTags)
+		}
+	}
+
+	draft := model.Article{CategoryId: 1, Title: "draft", Status: model.ArticleStatusDraft, TagIds: []int64{1, 2}}
+	if err := data.Insert(&draft); err != nil {
+		t.Fatalf("Insert() draft error = %v", err)
+	}
+	checkCounts("insert draft", 0, []int{0, 0, 0})
+
+	published := model.Article{CategoryId: 1, Title: "published", Status: model.ArticleStatusPublished, TagIds: []int64{2}}
+	if err := data.Insert(&published); err != nil {
+		t.Fatalf("Insert() published error = %v", err)
+	}
+	checkCounts("insert published", 1, []int{0, 1, 0})
+
+	statusTests := []struct {
+		step      string
+		status    int8
+		wantCount int
+		wantTags  []int
+	}{
+		{"publish draft", model.ArticleStatusPublished, 2, []int{1, 2, 0}},
+		{"publish again", model.ArticleStatusPublished, 2, []int{1, 2, 0}},
+		{"archive", model.ArticleStatusArchived, 1, []int{0, 1, 0}},
+		{"archived to draft", model.ArticleStatusDraft, 1, []int{0, 1, 0}},
+	}
+	for _, tt := range statusTests {
+		if err := data.UpdateStatus(&model.Article{Id: draft.Id, Status: tt.status}); err != nil {
+			t.Fatalf("%s: UpdateStatus() error = %v", tt.step, err)
+		}
+		checkCounts(tt.step, tt.wantCount, tt.wantTags)
+	}
+
+	scheduled := model.Article{CategoryId: 1, Title: "scheduled", Status: model.ArticleStatusScheduled, PublishAt: 100, TagIds: []int64{2, 3}}
+	if err := data.Insert(&scheduled); err != nil {
+		t.Fatalf("Insert() scheduled error = %v", err)
+	}
+	checkCounts("insert scheduled", 1, []int{0, 1, 0})
+
+	if articles, err := data.PublishScheduled(50); err != nil || len(articles) != 0 {
+		t.Fatalf("PublishScheduled() before publish_at = %d articles, %v", len(articles), err)
+	}
+	if articles, err := data.PublishScheduled(200); err != nil || len(articles) != 1 {
+		t.Fatalf("PublishScheduled() = %d articles, %v, want 1 article", len(articles), err)
+	}
+	checkCounts("publish scheduled", 2, []int{0, 2, 1})
+	if articles, err := data.PublishScheduled(200); err != nil || len(articles) != 0 {
+		t.Fatalf("PublishScheduled() again = %d articles, %v", len(articles), err)
+	}
+	checkCounts("publish scheduled again", 2, []int{0, 2, 1})
+
+	// 已发布的文章修改分类，文章数移到新分类
+	if err := db.Create(&model.Category{Id: 2, Name: "rust"}).Error; err != nil {
+		t.Fatalf("create category err: %s", err)
+	}
+	update := model.Article{Id: scheduled.Id, CategoryId: 2, Title: "moved"}
+	if err := data.UpdateInfo(&update, testArticleFields); err != nil {
+		t.Fatalf("UpdateInfo() error = %v", err)
+	}
+	checkCounts("move category", 1, []int{0, 2, 1})
+	if got := categoryCount(t, db, 2); got != 1 {
+		t.Errorf("move category: new category article count = %d, want 1", got)
+	}
+
+	if err := data.Delete(draft.Id); err != nil {
+		t.Fatalf("Delete() draft error = %v", err)
+	}
+	checkCounts("delete draft", 1, []int{0, 2, 1})
+
+	if err := data.Delete(published.Id); err != nil {
+		t.Fatalf("Delete() published error = %v", err)
+	}
+	checkCounts("delete published", 0, []int{0, 1, 1})
+}
+
+func TestArticleInsertDraftCheckExists(t *testing.T) {
+	data, _ := newTestArticle(t)
+
+	tests := []struct {
+		name    string
+		article model.Article
+		want    error
+	}{
+		{"category not exist", model.Article{CategoryId: 99, Status: model.ArticleStatusDraft}, apierr.ErrCategoryNoExist},
+		{"tag not exist", model.Article{CategoryId: 1, Status: model.ArticleStatusDraft, TagIds: []int64{1, 99}}, apierr.ErrTagNoExist},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := data.Insert(&tt.article); err != tt.want {
+				t.Errorf("Insert() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// articleViews 返回文章1、2的阅读量
+func articleViews(t *testing.T, db *gorm.DB) []int64 {
+	views := []int64{}
+	if err := db.Model(&model.Article{}).Order("id").Pluck("views", &views).Error; err != nil {
+		t.Fatalf("query views err: %s", err)
+	}
+	return views
+}
+
+func newTestViewsArticle(t *testing.T) (*Article, *gorm.DB, *miniredis.Miniredis) {
+	data, db, s := newTestArticleWithRedis(t)
+	for i := int64(1); i <= 2; i++ {
+		if err := db.Create(&model.Article{Id: i, CategoryId: 1, Status: model.ArticleStatusPublished}).Error; err != nil {
+			t.Fatalf("create article err: %s", err)
+		}
+	}
+	return data, db, s
+}
+
+func TestArticleFlushViews(t *testing.T) {
+	data, db, s := newTestViewsArticle(t)
+
+	if n, err := data.FlushViews(); n != 0 || err != nil {
+		t.Fatalf("FlushViews() without views = %d, %v, want 0, nil", n, err)
+	}
+
+	for _, id := range []int64{1, 1, 1, 2, 2} {
+		if err := data.IncrView(id); err != nil {
+			t.Fatalf("IncrView() error = %v", err)
+		}
+	}
+
+	if n, err := data.FlushViews(); n != 2 || err != nil {
+		t.Fatalf("FlushViews() = %d, %v, want 2, nil", n, err)
+	}
+	if got := articleViews(t, db); !reflect.DeepEqual(got, []int64{3, 2}) {
+		t.Errorf("views = %v, want [3 2]", got)
+	}
+	for _, key := range []string{data.cacheViewsPendingKey(), data.cacheViewsFlushingKey(), data.cacheViewsFlushIdKey()} {
+		if s.Exists(key) {
+			t.Errorf("key %s still exists after flush", key)
+		}
+	}
+
+	if n, err := data.FlushViews(); n != 0 || err != nil {
+		t.Fatalf("FlushViews() again = %d, %v, want 0, nil", n, err)
+	}
+	if got := articleViews(t, db); !reflect.DeepEqual(got, []int64{3, 2}) {
+		t.Errorf("views after flushing again = %v, want [3 2]", got)
+	}
+}
+
+func TestArticleFlushViewsRetry(t *testing.T) {
+	data, db, s := newTestViewsArticle(t)
+
+	// 批次已写入数据库，但删除redis中的批次失败
+	s.HSet(data.cacheViewsFlushingKey(), "1", "5")
+	s.Set(data.cacheViewsFlushIdKey(), "batch-1")
+	if err := db.Create(&model.ArticleViewFlush{Id: "batch-1"}).Error; err != nil {
+		t.Fatalf("create flush err: %s", err)
+	}
+
+	if n, err := data.FlushViews(); n != 0 || err != nil {
+		t.Fatalf("FlushViews() applied batch = %d, %v, want 0, nil", n, err)
+	}
+	if got := articleViews(t, db); !reflect.DeepEqual(got, []int64{0, 0}) {
+		t.Errorf("views after applied batch = %v, want [0 0]", got)
+	}
+	if s.Exists(data.cacheViewsFlushingKey()) || s.Exists(data.cacheViewsFlushIdKey()) {
+		t.Error("applied batch still exists after flush")
+	}
+
+	// 批次写入数据库失败，遗留的批次优先写入，新的阅读量留到下次
+	s.HSet(data.cacheViewsFlushingKey(), "1", "4")
+	s.Set(data.cacheViewsFlushIdKey(), "batch-2")
+	if err := data.IncrView(2); err != nil {
+		t.Fatalf("IncrView() error = %v", err)
+	}
+
+	if n, err := data.FlushViews(); n != 1 || err != nil {
+		t.Fatalf("FlushViews() leftover batch = %d, %v, want 1, nil", n, err)
+	}
+	if got := articleViews(t, db); !reflect.DeepEqual(got, []int64{4, 0}) {
+		t.Errorf("views after leftover batch = %v, want [4 0]", got)
+	}
+
+	if n, err := data.FlushViews(); n != 1 || err != nil {
+		t.Fatalf("FlushViews() pending = %d, %v, want 1, nil", n, err)
+	}
+	if got := articleViews(t, db); !reflect.DeepEqual(got, []int64{4, 1}) {
+		t.Errorf("views after pending = %v, want [4 1]", got)
+	}
+}
@@ -0,0 +1,178 @@
+package data
+
+import (
+	"encoding/json"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 实现service层中的data接口
+
+type Audit struct {
+	db     *gorm.DB
+	logger *logger.CustomLogger
+}
+
+func NewAudit(db *gorm.DB, logger *logger.CustomLogger) service.IAuditData {
+	return &Audit{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func NewAuditWrite(db *gorm.DB, logger *logger.CustomLogger) service.IAuditWriteData {
+	return &Audit{
+		db:     db,
+		logger: logger,
+	}
+}
+
+const auditExportBatch = 500
+
+// Snapshot 查询记录当前的快照，用户快照附带角色id，角色快照附带权限id
+// @param resource 资源名，model.AuditResources中的键
+// @param id 记录id
+// @return string 记录的json，资源无法识别或记录不存在时为空
+// @return error
+func (ctl *Audit) Snapshot(resource string, id int64) (string, error) {
+	table, ok := model.AuditResources[resource]
+	if !ok || id <= 0 {
+		return "", nil
+	}
+
+	row := map[string]interface{}{}
+	if err := ctl.db.Table(table).Where("id = ?", id).Take(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil
+		}
+		return "", errors.WithStack(err)
+	}
+
+	for _, v := range model.AuditHiddenColumns {
+		delete(row, v)
+	}
+
+	switch table {
+	case "user":
+		roleIds := []int64{}
+		if err := ctl.db.Model(&model.UserRole{}).Where("user_id = ?", id).Pluck("role_id", &roleIds).Error; err != nil {
+			return "", errors.WithStack(err)
+		}
+		row["role_ids"] = roleIds
+	case "role":
+		permissionIds := []int64{}
+		if err := ctl.db.Model(&model.RolePermission{}).Where("role_id = ?", id).Pluck("permission_id", &permissionIds).Error; err != nil {
+			return "", errors.WithStack(err)
+		}
+		row["permission_ids"] = permissionIds
+	}
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return string(data), nil
+}
+
+// Insert 批量写入审计日志
+// @param logs 审计日志
+// @return error
+func (ctl *Audit) Insert(logs []model.AuditLog) error {
+	if err := ctl.db.Create(&logs).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// ListByFilter 按筛选条件分页查询审计日志，按时间倒序
+// @param filter 筛选条件
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.AuditLog
+// @return error
+func (ctl *Audit) ListByFilter(filter model.AuditFilter, page, pageSize int) ([]model.AuditLog, error) {
+	startIndex := (page - 1) * pageSize
+	var logs []model.AuditLog
+
+	err := ctl.scopeFilter(filter).Offset(startIndex).Limit(pageSize).Order("id desc").Find(&logs).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return logs, nil
+}
+
+// GetSumByFilter 查询符合筛选条件的审计日志数
+// @param filter 筛选条件
+// @return int64
+// @return error
+func (ctl *Audit) GetSumByFilter(filter model.AuditFilter) (int64, error) {
+	var count int64
+	if err := ctl.scopeFilter(filter).Model(&model.AuditLog{}).Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// EachByFilter 分批遍历符合筛选条件的审计日志，按时间倒序，用于导出
+// @param filter 筛选条件
+// @param fn 处理每一批日志，返回错误时停止遍历
+// @return error
+func (ctl *Audit) EachByFilter(filter model.AuditFilter, fn func([]model.AuditLog) error) error {
+	for beforeId := int64(0); ; {
+		db := ctl.scopeFilter(filter)
+		if beforeId > 0 {
+			db = db.Where("id < ?", beforeId)
+		}
+
+		var logs []model.AuditLog
+		if err := db.Order("id desc").Limit(auditExportBatch).Find(&logs).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		if err := fn(logs); err != nil {
+			return err
+		}
+
+		if len(logs) < auditExportBatch {
+			return nil
+		}
+		beforeId = logs[len(logs)-1].Id
+	}
+}
+
+// scopeFilter 审计日志的筛选条件
+// @param filter 筛选条件
+// @return *gorm.DB
+func (ctl *Audit) scopeFilter(filter model.AuditFilter) *gorm.DB {
+	db := ctl.db
+	if filter.ActorId > 0 {
+		db = db.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		db = db.Where("action = ?", filter.Action)
+	}
+	if filter.Resource != "" {
+		db = db.Where("resource = ?", filter.Resource)
+	}
+	if filter.TargetId > 0 {
+		db = db.Where("target_id = ?", filter.TargetId)
+	}
+	if filter.CreatedFrom > 0 {
+		db = db.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if filter.CreatedTo > 0 {
+		db = db.Where("created_at <= ?", filter.CreatedTo)
+	}
+
+	return db
+}
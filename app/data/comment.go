@@ -0,0 +1,262 @@
+package data
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// 实现service层中的data接口
+
+type Comment struct {
+	db     *gorm.DB
+	cache  cache.CustomRedis
+	logger *logger.CustomLogger
+}
+
+func NewComment(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.ICommentData {
+	r := cache.ConnRedisByPool(cacheConn, "comment")
+
+	return &Comment{
+		db:     db,
+		cache:  r,
+		logger: logger,
+	}
+}
+
+// Insert 创建评论
+// @param comment 评论信息
+// @return error
+func (ctl *Comment) Insert(comment *model.Comment) error {
+	if err := ctl.db.Create(comment).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := ctl.cache.Del(ctl.cacheSumByArticleKey(comment.ArticleId)); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	return nil
+}
+
+// Get 查询未删除的评论
+// @param id 评论id
+// @return *model.Comment
+// @return error
+func (ctl *Comment) Get(id int64) (*model.Comment, error) {
+	comment := model.Comment{Id: id}
+
+	if err := ctl.db.Where("deleted = ?", model.CommentDeletedNo).First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierr.ErrCommentNoExist
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return &comment, nil
+}
+
+// UpdateById 更新评论
+// @param comment 评论信息，必须包含id和article_id
+// @param updateFields 更新字段
+// @return error
+func (ctl *Comment) UpdateById(comment *model.Comment, updateFields []string) error {
+	if err := ctl.db.Select(updateFields).Updates(comment).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := ctl.cache.Del(ctl.cacheSumByArticleKey(comment.ArticleId)); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	return nil
+}
+
+// Delete 删除评论及其下的所有回复
+// @param id 评论id
+// @return error
+func (ctl *Comment) Delete(id int64) error {
+	comment, err := ctl.Get(id)
+	if err != nil {
+		return err
+	}
+
+	err = ctl.db.Transaction(func(tx *gorm.DB) error {
+		ids, err := ctl.subtreeIds(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&model.Comment{}).Where("id in ?", ids).Update("deleted", model.CommentDeletedYes).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := ctl.cache.Del(ctl.cacheSumByArticleKey(comment.ArticleId)); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	return nil
+}
+
+// subtreeIds 沿parent_id逐层查询评论及其下所有层级的回复id
+// @param tx 事务
+// @param id 评论id
+// @return []int64 包括评论本身的id
+// @return error
+func (ctl *Comment) subtreeIds(tx *gorm.DB, id int64) ([]int64, error) {
+	ids := []int64{id}
+	seen := map[int64]bool{id: true}
+
+	for parentIds := []int64{id}; len(parentIds) > 0; {
+		var children []int64
+		if err := tx.Model(&model.Comment{}).Where("parent_id in ?", parentIds).Pluck("id", &children).Error; err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		parentIds = make([]int64, 0, len(children))
+		for _, v := range children {
+			if !seen[v] {
+				seen[v] = true
+				ids = append(ids, v)
+				parentIds = append(parentIds, v)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// GetSumByArticle 查询文章的评论数
+// @param articleId 文章id
+// @return int64 评论数
+// @return error
+func (ctl *Comment) GetSumByArticle(articleId int64) (int64, error) {
+	/*
+	 * 1. 从 redis 读取
+	 * 2. 不存在 => 数据库查询，存入 redis
+	 * 3. 返回
+	 */
+	// 从缓存库查询
+	count, err := redis.Int64(ctl.cache.Do("get", ctl.cacheSumByArticleKey(articleId)))
+
+	// 缓存查询出错
+	if err != nil && !errors.Is(err, redis.ErrNil) {
+		return 0, errors.WithStack(err)
+	}
+
+	// 不存在, 从数据库查询并存入redis
+	if errors.Is(err, redis.ErrNil) {
+		count, err = ctl.GetSumByArticleFromDB(articleId)
+		if err != nil {
+			return 0, err
+		}
+
+		// 缓存不成功只记录错误日志，但可以返回成功
+		if err = ctl.cache.CacheString(ctl.cacheSumByArticleKey(articleId), strconv.FormatInt(count, 10)); err != nil {
+			ctl.logger.CacheErrLog(err)
+		}
+	}
+
+	// 返回
+	return count, nil
+}
+
+// GetSumByArticleFromDB 从数据库查询文章的评论数，只统计正常显示的评论
+// @param articleId 文章id
+// @return int64 评论数
+// @return error
+func (ctl *Comment) GetSumByArticleFromDB(articleId int64) (int64, error) {
+	var count int64
+
+	err := ctl.db.Model(&model.Comment{}).Select("count(*)").
+		Where("article_id = ? and status = ? and deleted = ?", articleId, model.CommentStatusNormal, model.CommentDeletedNo).
+		Find(&count).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// GetRootSumByArticle 查询文章的顶级评论数，用于分页
+// @param articleId 文章id
+// @param withHidden 是否包含被隐藏的评论
+// @return int64 顶级评论数
+// @return error
+func (ctl *Comment) GetRootSumByArticle(articleId int64, withHidden bool) (int64, error) {
+	var count int64
+
+	err := ctl.scopeArticle(articleId, withHidden).Model(&model.Comment{}).Select("count(*)").
+		Where("root_id = 0").Find(&count).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// ListRootByArticle 分页查询文章的顶级评论
+// @param articleId 文章id
+// @param withHidden 是否包含被隐藏的评论
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.Comment
+// @return error
+func (ctl *Comment) ListRootByArticle(articleId int64, withHidden bool, page, pageSize int) ([]model.Comment, error) {
+	startIndex := (page - 1) * pageSize
+	var comments []model.Comment
+
+	err := ctl.scopeArticle(articleId, withHidden).Where("root_id = 0").
+		Offset(startIndex).Limit(pageSize).Order("created_at desc").Find(&comments).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return comments, nil
+}
+
+// ListByRoots 查询多条顶级评论下的所有回复
+// @param articleId 文章id
+// @param rootIds 顶级评论id
+// @param withHidden 是否包含被隐藏的评论
+// @return []model.Comment
+// @return error
+func (ctl *Comment) ListByRoots(articleId int64, rootIds []int64, withHidden bool) ([]model.Comment, error) {
+	var comments []model.Comment
+	if len(rootIds) == 0 {
+		return comments, nil
+	}
+
+	err := ctl.scopeArticle(articleId, withHidden).Where("root_id in ?", rootIds).
+		Order("created_at asc").Find(&comments).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return comments, nil
+}
+
+func (ctl *Comment) scopeArticle(articleId int64, withHidden bool) *gorm.DB {
+	db := ctl.db.Where("article_id = ? and deleted = ?", articleId, model.CommentDeletedNo)
+	if !withHidden {
+		db = db.Where("status = ?", model.CommentStatusNormal)
+	}
+	return db
+}
+
+func (ctl *Comment) cacheSumByArticleKey(articleId int64) string {
+	return fmt.Sprintf("%s:sum:articleId#%d", ctl.cache.CachePrefixKey(), articleId)
+}
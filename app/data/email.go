@@ -12,28 +12,78 @@ import (
 	"gopkg.in/gomail.v2"
 	"gorm.io/gorm"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // 实现service层中的data接口
 
 type Email struct {
-	db     *gorm.DB
-	cache  cache.CustomRedis
-	conf   model.EmailConfig
-	logger *logger.CustomLogger
+	db       *gorm.DB
+	cache    cache.CustomRedis
+	conf     model.EmailConfig
+	codeConf model.EmailCodeConfig
+	logger   *logger.CustomLogger
 }
 
-func NewEmail(db *gorm.DB, cacheConn *redis.Pool, conf model.EmailConfig, logger *logger.CustomLogger) service.IEmailData {
+func NewEmail(db *gorm.DB, cacheConn *redis.Pool, conf model.EmailConfig, codeConf model.EmailCodeConfig, logger *logger.CustomLogger) service.IEmailData {
 	r := cache.ConnRedisByPool(cacheConn, "email")
 
 	return &Email{
-		db:     db,
-		cache:  r,
-		conf:   conf,
-		logger: logger,
+		db:       db,
+		cache:    r,
+		conf:     conf,
+		codeConf: codeConf,
+		logger:   logger,
 	}
 }
 
+// sendLimitScript 检查并记录验证码发送次数
+// KEYS[1] 邮箱冷却键，KEYS[2] 邮箱发送次数键，KEYS[3] ip发送次数键
+// ARGV[1] 冷却时间，ARGV[2] 统计窗口，ARGV[3] 邮箱次数上限，ARGV[4] ip次数上限
+// 返回 0: 允许发送，1: 冷却中，2: 邮箱超过次数，3: ip超过次数
+var sendLimitScript = redis.NewScript(3, `
+if redis.call('exists', KEYS[1]) == 1 then
+	return 1
+end
+local emailCount = tonumber(redis.call('get', KEYS[2]) or '0')
+if emailCount >= tonumber(ARGV[3]) then
+	return 2
+end
+local ipCount = tonumber(redis.call('get', KEYS[3]) or '0')
+if ipCount >= tonumber(ARGV[4]) then
+	return 3
+end
+redis.call('setex', KEYS[1], ARGV[1], 1)
+if redis.call('incr', KEYS[2]) == 1 then
+	redis.call('expire', KEYS[2], ARGV[2])
+end
+if redis.call('incr', KEYS[3]) == 1 then
+	redis.call('expire', KEYS[3], ARGV[2])
+end
+return 0
+`)
+
+// verifyCodeScript 校验验证码并记录校验次数，超过次数后验证码失效
+// KEYS[1] 验证码键
+// ARGV[1] 提交的验证码，ARGV[2] 最多校验次数
+// 返回 1: 正确，0: 错误或不存在，-1: 超过校验次数
+var verifyCodeScript = redis.NewScript(1, `
+local code = redis.call('hget', KEYS[1], 'code')
+if not code then
+	return 0
+end
+local attempts = redis.call('hincrby', KEYS[1], 'attempts', 1)
+if attempts > tonumber(ARGV[2]) then
+	redis.call('del', KEYS[1])
+	return -1
+end
+if code == ARGV[1] then
+	return 1
+end
+return 0
+`)
+
 func (ctl *Email) GetEmailTpl(name string) (*model.EmailTpl, error) {
 	tpl := model.EmailTpl{}
 	if err := ctl.db.Where("name = ?", name).First(&tpl).Error; err != nil {
@@ -42,31 +92,186 @@ func (ctl *Email) GetEmailTpl(name string) (*model.EmailTpl, error) {
 	return &tpl, nil
 }
 
-func (ctl *Email) SaveCode(email string, code string) error {
-	const expire = 60 * 5 // 单位:秒
+// CheckSendLimit 检查邮箱的发送冷却和邮箱、ip的发送次数，允许发送时记录一次发送
+// @param email 邮箱
+// @param ip 请求ip
+// @return error 超过限制时返回对应的apierr错误
+func (ctl *Email) CheckSendLimit(email, ip string) error {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	res, err := redis.Int(sendLimitScript.Do(conn,
+		ctl.cacheCooldownKey(email), ctl.cacheEmailCountKey(email), ctl.cacheIpCountKey(ip),
+		ctl.codeConf.Cooldown, ctl.codeConf.Window, ctl.codeConf.EmailLimit, ctl.codeConf.IpLimit))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	switch res {
+	case 1:
+		return apierr.ErrEmailCodeCooldown
+	case 2:
+		return apierr.ErrEmailCodeEmailLimit
+	case 3:
+		return apierr.ErrEmailCodeIpLimit
+	}
+
+	return nil
+}
+
+// SaveCode 保存验证码，覆盖旧的验证码并重置校验次数
+// @param scene 验证码用途，不同用途的验证码互不通用
+// @param email 邮箱
+// @param code 验证码
+// @return error
+func (ctl *Email) SaveCode(scene, email string, code string) error {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	key := ctl.cacheCodeKey(scene, email)
+	if err := conn.Send("multi"); err != nil {
+		return errors.WithStack(err)
+	}
+	conn.Send("del", key)
+	conn.Send("hset", key, "code", code, "attempts", 0)
+	conn.Send("expire", key, ctl.codeConf.Expire)
+	if _, err := conn.Do("exec"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// VerifyCode 校验验证码，每次校验都计入次数，校验成功后验证码依然有效，需要调用InvalidCode使其失效
+// @param scene 验证码用途
+// @param email 邮箱
+// @param code 提交的验证码
+// @return error 验证码错误时返回apierr.ErrRegisterCode，超过校验次数时返回apierr.ErrEmailCodeAttempts
+func (ctl *Email) VerifyCode(scene, email string, code string) error {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	res, err := redis.Int(verifyCodeScript.Do(conn, ctl.cacheCodeKey(scene, email), code, ctl.codeConf.MaxAttempts))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	switch res {
+	case 0:
+		return apierr.ErrRegisterCode
+	case -1:
+		return apierr.ErrEmailCodeAttempts
+	}
 
-	if _, err := ctl.cache.Do("setex", ctl.cacheCodeKey(email), expire, code); err != nil {
-		return err
+	return nil
+}
+
+func (ctl *Email) InvalidCode(scene, email string) error {
+	if err := ctl.cache.Del(ctl.cacheCodeKey(scene, email)); err != nil {
+		return errors.WithStack(err)
 	}
+
 	return nil
 }
 
-func (ctl *Email) GetCode(email string) (string, error) {
-	code, err := redis.String(ctl.cache.Do("get", ctl.cacheCodeKey(email)))
-	if errors.Is(err, redis.ErrNil) {
-		return "", apierr.ErrRegisterCode
+// invalidResetTokensScript 删除用户所有未使用的重置密码凭证
+// KEYS[1] 用户的凭证集合键
+// ARGV[1] 凭证键前缀
+var invalidResetTokensScript = redis.NewScript(1, `
+local ids = redis.call('smembers', KEYS[1])
+for _, id in ipairs(ids) do
+	redis.call('del', ARGV[1] .. id)
+end
+redis.call('del', KEYS[1])
+return #ids
+`)
+
+// SaveResetToken 保存重置密码凭证，同时记录到用户的凭证集合，便于注销账号时使其全部失效
+// @param tokenId 凭证的随机串
+// @param userId 重置密码的用户id
+// @param expire 有效期
+// @return error
+func (ctl *Email) SaveResetToken(tokenId string, userId int64, expire time.Duration) error {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	seconds := int64(expire / time.Second)
+	userKey := ctl.cacheUserResetTokensKey(userId)
+	if err := conn.Send("multi"); err != nil {
+		return errors.WithStack(err)
 	}
-	return code, nil
+	conn.Send("setex", ctl.cacheResetTokenKey(tokenId), seconds, userId)
+	conn.Send("sadd", userKey, tokenId)
+	conn.Send("expire", userKey, seconds)
+	if _, err := conn.Do("exec"); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
 }
 
-func (ctl *Email) InvalidCode(email string) error {
-	if err := ctl.cache.Del(ctl.cacheCodeKey(email)); err != nil {
+// InvalidResetTokens 使用户所有未使用的重置密码凭证失效
+// @param userId 用户id
+// @return error
+func (ctl *Email) InvalidResetTokens(userId int64) error {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	if _, err := invalidResetTokensScript.Do(conn, ctl.cacheUserResetTokensKey(userId), ctl.cacheResetTokenKey("")); err != nil {
 		return errors.WithStack(err)
 	}
 
 	return nil
 }
 
+// TakeResetToken 取出并删除重置密码凭证，保证凭证只能使用一次
+// @param tokenId 凭证的随机串
+// @return int64 重置密码的用户id
+// @return error 凭证不存在或已过期时返回apierr.ErrPasswordResetToken
+func (ctl *Email) TakeResetToken(tokenId string) (int64, error) {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	key := ctl.cacheResetTokenKey(tokenId)
+	if err := conn.Send("multi"); err != nil {
+		return 0, errors.WithStack(err)
+	}
+	conn.Send("get", key)
+	conn.Send("del", key)
+	values, err := redis.Values(conn.Do("exec"))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	userId, err := redis.Int64(values[0], nil)
+	if err != nil {
+		if errors.Is(err, redis.ErrNil) {
+			return 0, apierr.ErrPasswordResetToken
+		}
+		return 0, errors.WithStack(err)
+	}
+
+	return userId, nil
+}
+
 func (ctl *Email) SendEmail(mailTo []string, subject string, body string) error {
 	port, err := strconv.Atoi(ctl.conf.Port)
 	if err != nil {
@@ -82,6 +287,33 @@ func (ctl *Email) SendEmail(mailTo []string, subject string, body string) error
 	return d.DialAndSend(m)
 }
 
-func (ctl *Email) cacheCodeKey(email string) string {
-	return fmt.Sprintf("%s:code#%s", ctl.cache.CachePrefixKey(), email)
+// 验证码和发送限制的键使用规范化后的邮箱，同一邮箱的大小写变体共用冷却时间、发送次数和验证码
+
+func (ctl *Email) cacheCodeKey(scene, email string) string {
+	return fmt.Sprintf("%s:code:%s#%s", ctl.cache.CachePrefixKey(), scene, normalizeEmail(email))
+}
+
+func (ctl *Email) cacheCooldownKey(email string) string {
+	return fmt.Sprintf("%s:cooldown#%s", ctl.cache.CachePrefixKey(), normalizeEmail(email))
+}
+
+func (ctl *Email) cacheEmailCountKey(email string) string {
+	return fmt.Sprintf("%s:count:email#%s", ctl.cache.CachePrefixKey(), normalizeEmail(email))
+}
+
+func (ctl *Email) cacheIpCountKey(ip string) string {
+	return fmt.Sprintf("%s:count:ip#%s", ctl.cache.CachePrefixKey(), ip)
+}
+
+func (ctl *Email) cacheResetTokenKey(tokenId string) string {
+	return fmt.Sprintf("%s:reset#%s", ctl.cache.CachePrefixKey(), tokenId)
+}
+
+func (ctl *Email) cacheUserResetTokensKey(userId int64) string {
+	return fmt.Sprintf("%s:reset:userId#%d", ctl.cache.CachePrefixKey(), userId)
+}
+
+// normalizeEmail 去掉首尾空白并转为小写
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
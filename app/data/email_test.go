@@ -0,0 +1,144 @@
+package data
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"testing"
+	"time"
+)
+
+var testEmailCodeConf = model.EmailCodeConfig{
+	Expire:      300,
+	Cooldown:    60,
+	MaxAttempts: 3,
+	Window:      3600,
+	EmailLimit:  3,
+	IpLimit:     5,
+}
+
+func newTestEmail(t *testing.T) (*Email, *miniredis.Miniredis) {
+	pool, s := newTestRedisPool(t)
+	return NewEmail(nil, pool, model.EmailConfig{}, testEmailCodeConf, nil).(*Email), s
+}
+
+func TestEmailSendLimit(t *testing.T) {
+	email, s := newTestEmail(t)
+
+	if err := email.CheckSendLimit("a@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("CheckSendLimit() error = %v", err)
+	}
+
+	// 同一邮箱的大小写变体共用冷却时间，换ip也不能绕过
+	for _, addr := range []string{"a@example.com", " A@Example.com "} {
+		if err := email.CheckSendLimit(addr, "10.0.0.2"); err != apierr.ErrEmailCodeCooldown {
+			t.Errorf("CheckSendLimit(%q) error = %v, want ErrEmailCodeCooldown", addr, err)
+		}
+	}
+	// 冷却中的请求不计入发送次数
+	if count, _ := s.Get(email.cacheEmailCountKey("a@example.com")); count != "1" {
+		t.Errorf("email count = %s, want 1", count)
+	}
+
+	for i := 2; i <= testEmailCodeConf.EmailLimit; i++ {
+		s.FastForward(60 * time.Second)
+		if err := email.CheckSendLimit("A@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("CheckSendLimit() #%d error = %v", i, err)
+		}
+	}
+
+	s.FastForward(60 * time.Second)
+	if err := email.CheckSendLimit("a@example.com", "10.0.0.3"); err != apierr.ErrEmailCodeEmailLimit {
+		t.Errorf("CheckSendLimit() over email limit error = %v, want ErrEmailCodeEmailLimit", err)
+	}
+
+	// 统计窗口过后重新计算
+	s.FastForward(3600 * time.Second)
+	if err := email.CheckSendLimit("a@example.com", "10.0.0.1"); err != nil {
+		t.Errorf("CheckSendLimit() after the window error = %v", err)
+	}
+}
+
+func TestEmailSendIpLimit(t *testing.T) {
+	email, _ := newTestEmail(t)
+
+	addrs := []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"}
+	for _, addr := range addrs {
+		if err := email.CheckSendLimit(addr, "10.0.0.1"); err != nil {
+			t.Fatalf("CheckSendLimit(%q) error = %v", addr, err)
+		}
+	}
+
+	if err := email.CheckSendLimit("f@example.com", "10.0.0.1"); err != apierr.ErrEmailCodeIpLimit {
+		t.Errorf("CheckSendLimit() over ip limit error = %v, want ErrEmailCodeIpLimit", err)
+	}
+	// 被ip限制的请求不进入冷却
+	if err := email.CheckSendLimit("f@example.com", "10.0.0.2"); err != nil {
+		t.Errorf("CheckSendLimit() other ip error = %v", err)
+	}
+}
+
+func TestEmailVerifyCode(t *testing.T) {
+	email, s := newTestEmail(t)
+
+	if err := email.SaveCode("register", "A@Example.com", "123456"); err != nil {
+		t.Fatalf("SaveCode() error = %v", err)
+	}
+	if ttl := s.TTL(email.cacheCodeKey("register", "a@example.com")); ttl != 300*time.Second {
+		t.Errorf("code ttl = %s, want 5m", ttl)
+	}
+
+	// 不同用途的验证码互不通用
+	if err := email.VerifyCode("reset", "a@example.com", "123456"); err != apierr.ErrRegisterCode {
+		t.Errorf("VerifyCode() other scene error = %v, want ErrRegisterCode", err)
+	}
+
+	if err := email.VerifyCode("register", "a@example.com", "000000"); err != apierr.ErrRegisterCode {
+		t.Errorf("VerifyCode() wrong code error = %v, want ErrRegisterCode", err)
+	}
+	// 校验成功后验证码依然有效
+	for i := 0; i < 2; i++ {
+		if err := email.VerifyCode("register", " a@example.COM", "123456"); err != nil {
+			t.Errorf("VerifyCode() error = %v", err)
+		}
+	}
+
+	// 超过校验次数后验证码失效，正确的验证码也不能通过
+	if err := email.VerifyCode("register", "a@example.com", "123456"); err != apierr.ErrEmailCodeAttempts {
+		t.Errorf("VerifyCode() over attempts error = %v, want ErrEmailCodeAttempts", err)
+	}
+	if err := email.VerifyCode("register", "a@example.com", "123456"); err != apierr.ErrRegisterCode {
+		t.Errorf("VerifyCode() after attempts error = %v, want ErrRegisterCode", err)
+	}
+
+	// 重新发送后重置校验次数
+	if err := email.SaveCode("register", "a@example.com", "654321"); err != nil {
+		t.Fatalf("SaveCode() error = %v", err)
+	}
+	if err := email.VerifyCode("register", "a@example.com", "123456"); err != apierr.ErrRegisterCode {
+		t.Errorf("VerifyCode() old code error = %v, want ErrRegisterCode", err)
+	}
+	if err := email.VerifyCode("register", "a@example.com", "654321"); err != nil {
+		t.Errorf("VerifyCode() new code error = %v", err)
+	}
+
+	if err := email.InvalidCode("register", "A@example.com"); err != nil {
+		t.Fatalf("InvalidCode() error = %v", err)
+	}
+	if err := email.VerifyCode("register", "a@example.com", "654321"); err != apierr.ErrRegisterCode {
+		t.Errorf("VerifyCode() after InvalidCode() error = %v, want ErrRegisterCode", err)
+	}
+}
+
+func TestEmailVerifyCodeExpire(t *testing.T) {
+	email, s := newTestEmail(t)
+
+	if err := email.SaveCode("register", "a@example.com", "123456"); err != nil {
+		t.Fatalf("SaveCode() error = %v", err)
+	}
+
+	s.FastForward(300 * time.Second)
+	if err := email.VerifyCode("register", "a@example.com", "123456"); err != apierr.ErrRegisterCode {
+		t.Errorf("VerifyCode() expired code error = %v, want ErrRegisterCode", err)
+	}
+}
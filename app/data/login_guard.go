@@ -0,0 +1,169 @@
+package data
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/pkg/errors"
+)
+
+// 实现service层中的data接口
+
+type LoginGuard struct {
+	cache  cache.CustomRedis
+	conf   model.LoginGuardConfig
+	logger *logger.CustomLogger
+}
+
+func NewLoginGuard(cacheConn *redis.Pool, conf model.LoginGuardConfig, logger *logger.CustomLogger) service.ILoginGuardData {
+	r := cache.ConnRedisByPool(cacheConn, "loginGuard")
+
+	return &LoginGuard{
+		cache:  r,
+		conf:   conf,
+		logger: logger,
+	}
+}
+
+// recordFailureScript 记录一次登录失败
+// KEYS[1] ip失败次数键，KEYS[2] ip锁定键，KEYS[3] 账号失败次数键，KEYS[4] 账号锁定键，KEYS[5] 账号等待键
+// ARGV[1] 统计窗口，ARGV[2] ip失败上限，ARGV[3] 账号失败上限，ARGV[4] 锁定时长，
+// ARGV[5] 等待基数(毫秒)，ARGV[6] 最长等待(毫秒)，ARGV[7] 是否记录账号失败
+// 返回 1: 账号被锁定，0: 未锁定
+var recordFailureScript = redis.NewScript(5, `
+local ipFailures = redis.call('incr', KEYS[1])
+if ipFailures == 1 then
+	redis.call('expire', KEYS[1], ARGV[1])
+end
+if ipFailures >= tonumber(ARGV[2]) then
+	redis.call('setex', KEYS[2], ARGV[4], 1)
+	redis.call('del', KEYS[1])
+end
+
+if ARGV[7] ~= '1' then
+	return 0
+end
+
+local failures = redis.call('incr', KEYS[3])
+if failures == 1 then
+	redis.call('expire', KEYS[3], ARGV[1])
+end
+if failures >= tonumber(ARGV[3]) then
+	redis.call('setex', KEYS[4], ARGV[4], 1)
+	redis.call('del', KEYS[3], KEYS[5])
+	return 1
+end
+
+local wait = math.min(tonumber(ARGV[5]) * 2 ^ (failures - 1), tonumber(ARGV[6]))
+redis.call('set', KEYS[5], 1, 'px', math.floor(wait))
+return 0
+`)
+
+// CheckIp 检查ip是否因登录失败次数过多被限制登录
+// @param ip 登录ip
+// @return error 被限制时返回apierr.ErrLoginIpLocked
+func (ctl *LoginGuard) CheckIp(ip string) error {
+	locked, err := redis.Bool(ctl.cache.Do("exists", ctl.cacheIpLockKey(ip)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if locked {
+		return apierr.ErrLoginIpLocked
+	}
+
+	return nil
+}
+
+// CheckAccount 检查账号是否被锁定或处于失败后的等待时间内
+// @param userId 用户id
+// @return error 被锁定时返回apierr.ErrAccountLocked，需要等待时返回apierr.ErrLoginTooFrequent
+func (ctl *LoginGuard) CheckAccount(userId int64) error {
+	values, err := redis.Values(ctl.cache.Do("mget", ctl.cacheUserLockKey(userId), ctl.cacheUserWaitKey(userId)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if values[0] != nil {
+		return apierr.ErrAccountLocked
+	}
+	if values[1] != nil {
+		return apierr.ErrLoginTooFrequent
+	}
+
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，ip失败次数过多时限制该ip登录，账号失败次数过多时锁定账号
+// @param userId 用户id，账号不存在时为0，只记录ip失败次数
+// @param ip 登录ip
+// @return locked 本次失败是否导致账号被锁定
+// @return err
+func (ctl *LoginGuard) RecordFailure(userId int64, ip string) (locked bool, err error) {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	recordUser := 0
+	if userId > 0 {
+		recordUser = 1
+	}
+
+	res, err := redis.Int(recordFailureScript.Do(conn,
+		ctl.cacheIpFailuresKey(ip), ctl.cacheIpLockKey(ip),
+		ctl.cacheUserFailuresKey(userId), ctl.cacheUserLockKey(userId), ctl.cacheUserWaitKey(userId),
+		ctl.conf.Window, ctl.conf.IpMaxFailures, ctl.conf.MaxFailures, ctl.conf.LockDuration,
+		ctl.conf.BackoffBase*1000, ctl.conf.BackoffMax*1000, recordUser))
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return res == 1, nil
+}
+
+// Reset 登录成功后清除账号的失败次数
+// @param userId 用户id
+// @return error
+func (ctl *LoginGuard) Reset(userId int64) error {
+	if err := ctl.cache.Del(ctl.cacheUserFailuresKey(userId), ctl.cacheUserWaitKey(userId)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Unlock 解除账号锁定，同时清除失败次数
+// @param userId 用户id
+// @return error
+func (ctl *LoginGuard) Unlock(userId int64) error {
+	if err := ctl.cache.Del(ctl.cacheUserLockKey(userId), ctl.cacheUserFailuresKey(userId), ctl.cacheUserWaitKey(userId)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (ctl *LoginGuard) cacheIpFailuresKey(ip string) string {
+	return fmt.Sprintf("%s:failures:ip#%s", ctl.cache.CachePrefixKey(), ip)
+}
+
+func (ctl *LoginGuard) cacheIpLockKey(ip string) string {
+	return fmt.Sprintf("%s:lock:ip#%s", ctl.cache.CachePrefixKey(), ip)
+}
+
+func (ctl *LoginGuard) cacheUserFailuresKey(userId int64) string {
+	return fmt.Sprintf("%s:failures:userId#%d", ctl.cache.CachePrefixKey(), userId)
+}
+
+func (ctl *LoginGuard) cacheUserLockKey(userId int64) string {
+	return fmt.Sprintf("%s:lock:userId#%d", ctl.cache.CachePrefixKey(), userId)
+}
+
+func (ctl *LoginGuard) cacheUserWaitKey(userId int64) string {
+	return fmt.Sprintf("%s:wait:userId#%d", ctl.cache.CachePrefixKey(), userId)
+}
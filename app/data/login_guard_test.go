@@ -0,0 +1,177 @@
+package data
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"testing"
+	"time"
+)
+
+// newTestRedisPool 启动miniredis并返回连接到它的连接池
+func newTestRedisPool(t *testing.T) (*redis.Pool, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr())
+	}}
+	t.Cleanup(func() { pool.Close() })
+
+	return pool, s
+}
+
+var testLoginGuardConf = model.LoginGuardConfig{
+	Window:        600,
+	MaxFailures:   5,
+	IpMaxFailures: 8,
+	LockDuration:  900,
+	BackoffBase:   1,
+	BackoffMax:    4,
+}
+
+func newTestLoginGuard(t *testing.T) (*LoginGuard, *miniredis.Miniredis) {
+	pool, s := newTestRedisPool(t)
+	return NewLoginGuard(pool, testLoginGuardConf, nil).(*LoginGuard), s
+}
+
+func TestLoginGuardBackoff(t *testing.T) {
+	guard, s := newTestLoginGuard(t)
+
+	// 每次失败后的等待时间翻倍，不超过最长等待时间
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		locked, err := guard.RecordFailure(1, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+		if locked {
+			t.Fatalf("RecordFailure() #%d locked = true, want false", i+1)
+		}
+		if ttl := s.TTL(guard.cacheUserWaitKey(1)); ttl != want {
+			t.Errorf("RecordFailure() #%d wait = %s, want %s", i+1, ttl, want)
+		}
+		if err := guard.CheckAccount(1); err != apierr.ErrLoginTooFrequent {
+			t.Errorf("CheckAccount() #%d error = %v, want ErrLoginTooFrequent", i+1, err)
+		}
+
+		s.FastForward(want)
+		if err := guard.CheckAccount(1); err != nil {
+			t.Errorf("CheckAccount() #%d after waiting error = %v", i+1, err)
+		}
+	}
+	if ttl := s.TTL(guard.cacheUserFailuresKey(1)); ttl <= 0 || ttl > 600*time.Second {
+		t.Errorf("failures ttl = %s, want within the window", ttl)
+	}
+
+	// 登录成功后重新计算
+	if err := guard.Reset(1); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if _, err := guard.RecordFailure(1, "10.0.0.1"); err != nil {
+		t.Fatalf("RecordFailure() error = %v", err)
+	}
+	if ttl := s.TTL(guard.cacheUserWaitKey(1)); ttl != time.Second {
+		t.Errorf("wait after Reset() = %s, want 1s", ttl)
+	}
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	guard, s := newTestLoginGuard(t)
+
+	for i := 1; i <= testLoginGuardConf.MaxFailures; i++ {
+		locked, err := guard.RecordFailure(1, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+		if want := i == testLoginGuardConf.MaxFailures; locked != want {
+			t.Fatalf("RecordFailure() #%d locked = %v, want %v", i, locked, want)
+		}
+	}
+
+	if err := guard.CheckAccount(1); err != apierr.ErrAccountLocked {
+		t.Errorf("CheckAccount() error = %v, want ErrAccountLocked", err)
+	}
+	if ttl := s.TTL(guard.cacheUserLockKey(1)); ttl != 900*time.Second {
+		t.Errorf("lock ttl = %s, want 15m", ttl)
+	}
+	if s.Exists(guard.cacheUserFailuresKey(1)) || s.Exists(guard.cacheUserWaitKey(1)) {
+		t.Error("failures and wait keys not cleared after lockout")
+	}
+	// 其他账号不受影响
+	if err := guard.CheckAccount(2); err != nil {
+		t.Errorf("CheckAccount() other account error = %v", err)
+	}
+
+	// 锁定到期后解除
+	s.FastForward(900 * time.Second)
+	if err := guard.CheckAccount(1); err != nil {
+		t.Errorf("CheckAccount() after lock expired error = %v", err)
+	}
+
+	// 手动解锁
+	for i := 0; i < testLoginGuardConf.MaxFailures; i++ {
+		if _, err := guard.RecordFailure(1, "10.0.0.2"); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+	if err := guard.Unlock(1); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if err := guard.CheckAccount(1); err != nil {
+		t.Errorf("CheckAccount() after Unlock() error = %v", err)
+	}
+}
+
+func TestLoginGuardIpLimit(t *testing.T) {
+	guard, s := newTestLoginGuard(t)
+
+	// 账号不存在时只记录ip失败次数
+	for i := 1; i < testLoginGuardConf.IpMaxFailures; i++ {
+		if _, err := guard.RecordFailure(0, "10.0.0.1"); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+		if err := guard.CheckIp("10.0.0.1"); err != nil {
+			t.Fatalf("CheckIp() after %d failures error = %v", i, err)
+		}
+	}
+	if s.Exists(guard.cacheUserFailuresKey(0)) || s.Exists(guard.cacheUserWaitKey(0)) {
+		t.Error("failures recorded for a missing account")
+	}
+
+	if _, err := guard.RecordFailure(0, "10.0.0.1"); err != nil {
+		t.Fatalf("RecordFailure() error = %v", err)
+	}
+	if err := guard.CheckIp("10.0.0.1"); err != apierr.ErrLoginIpLocked {
+		t.Errorf("CheckIp() error = %v, want ErrLoginIpLocked", err)
+	}
+	if err := guard.CheckIp("10.0.0.2"); err != nil {
+		t.Errorf("CheckIp() other ip error = %v", err)
+	}
+
+	s.FastForward(900 * time.Second)
+	if err := guard.CheckIp("10.0.0.1"); err != nil {
+		t.Errorf("CheckIp() after lock expired error = %v", err)
+	}
+}
+
+func TestLoginGuardWindow(t *testing.T) {
+	guard, s := newTestLoginGuard(t)
+
+	for i := 1; i < testLoginGuardConf.MaxFailures; i++ {
+		if _, err := guard.RecordFailure(1, "10.0.0.1"); err != nil {
+			t.Fatalf("RecordFailure() error = %v", err)
+		}
+	}
+
+	// 统计窗口过后失败次数重新计算
+	s.FastForward(600 * time.Second)
+	locked, err := guard.RecordFailure(1, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("RecordFailure() error = %v", err)
+	}
+	if locked {
+		t.Error("RecordFailure() after the window locked = true, want false")
+	}
+	if ttl := s.TTL(guard.cacheUserWaitKey(1)); ttl != time.Second {
+		t.Errorf("wait after the window = %s, want 1s", ttl)
+	}
+}
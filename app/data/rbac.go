@@ -0,0 +1,463 @@
+package data
+
+import (
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 角色和权限数据较少且每次管理操作都需要查询，直接使用内存缓存
+// 本实例修改后立即失效，其他实例的修改在缓存有效期后生效
+type rbacCache struct {
+	sync.RWMutex
+	roles           []model.Role         // 所有角色，包括权限名
+	roleMap         map[int64]model.Role // 角色id => 角色
+	permissions     []model.Permission   // 所有权限
+	permissionIds   map[string]int64     // 权限名 => 权限id
+	userRoles       map[int64][]int64    // 用户id => 角色id
+	roleUsers       map[int64]int        // 角色id => 分配的用户数
+	rolePermissions map[int64]map[string]bool
+	loadedAt        time.Time
+	isValid         bool
+}
+
+var rbacData rbacCache
+
+type Rbac struct {
+	db     *gorm.DB
+	expire time.Duration
+	logger *logger.CustomLogger
+}
+
+// NewRbac
+// @param expire 内存缓存有效期
+func NewRbac(db *gorm.DB, expire time.Duration, logger *logger.CustomLogger) service.IRbacData {
+	return &Rbac{
+		db:     db,
+		expire: expire,
+		logger: logger,
+	}
+}
+
+// SyncPermissions 写入数据库中不存在的权限，并确保超级管理员角色存在
+// @param permissions 代码中定义的所有权限
+func (ctl *Rbac) SyncPermissions(permissions []model.Permission) error {
+	var exists []string
+	if err := ctl.db.Model(&model.Permission{}).Pluck("name", &exists).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	existMap := make(map[string]bool, len(exists))
+	for _, v := range exists {
+		existMap[v] = true
+	}
+
+	var missing []model.Permission
+	for _, v := range permissions {
+		if !existMap[v.Name] {
+			missing = append(missing, model.Permission{Name: v.Name, Description: v.Description})
+		}
+	}
+
+	if len(missing) > 0 {
+		if err := ctl.db.Create(&missing).Error; err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	superAdmin := model.Role{}
+	err := ctl.db.Where("name = ?", model.RoleSuperAdmin).First(&superAdmin).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		superAdmin = model.Role{Name: model.RoleSuperAdmin, Description: "超级管理员，拥有所有权限"}
+		err = ctl.db.Create(&superAdmin).Error
+	}
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	ctl.ExpireRbacData()
+	return nil
+}
+
+// HasPermission 查询用户是否拥有权限，拥有超级管理员角色时拥有所有权限
+// @param userId 用户id
+// @param permission 权限名
+func (ctl *Rbac) HasPermission(userId int64, permission string) (bool, error) {
+	if err := ctl.load(); err != nil {
+		return false, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	for _, roleId := range rbacData.userRoles[userId] {
+		if rbacData.roleMap[roleId].Name == model.RoleSuperAdmin || rbacData.rolePermissions[roleId][permission] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (ctl *Rbac) ListRoles() ([]model.Role, error) {
+	if err := ctl.load(); err != nil {
+		return nil, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	return rbacData.roles, nil
+}
+
+func (ctl *Rbac) GetRole(id int64) (*model.Role, error) {
+	if err := ctl.load(); err != nil {
+		return nil, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	role, ok := rbacData.roleMap[id]
+	if !ok {
+		return nil, apierr.ErrRoleNoExist
+	}
+
+	return &role, nil
+}
+
+func (ctl *Rbac) ListPermissions() ([]model.Permission, error) {
+	if err := ctl.load(); err != nil {
+		return nil, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	return rbacData.permissions, nil
+}
+
+// ListUserRoles 查询用户被分配的角色
+func (ctl *Rbac) ListUserRoles(userId int64) ([]model.Role, error) {
+	if err := ctl.load(); err != nil {
+		return nil, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	roleIds := rbacData.userRoles[userId]
+	roles := make([]model.Role, 0, len(roleIds))
+	for _, id := range roleIds {
+		roles = append(roles, rbacData.roleMap[id])
+	}
+
+	return roles, nil
+}
+
+// CountRoleUsers 查询角色分配的用户数
+func (ctl *Rbac) CountRoleUsers(roleId int64) (int, error) {
+	if err := ctl.load(); err != nil {
+		return 0, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	return rbacData.roleUsers[roleId], nil
+}
+
+// CreateRole 创建角色
+// @param role 角色信息，包括权限名
+func (ctl *Rbac) CreateRole(role *model.Role) error {
+	permissionIds, err := ctl.permissionIds(role.Permissions)
+	if err != nil {
+		return err
+	}
+
+	if exist, err := ctl.getIdByName(role.Name); err != nil {
+		return err
+	} else if exist > 0 {
+		return apierr.ErrRoleNameExist
+	}
+
+	err = ctl.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(role).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		return ctl.savePermissions(tx, role.Id, permissionIds)
+	})
+	if err != nil {
+		return err
+	}
+
+	ctl.ExpireRbacData()
+	return nil
+}
+
+// UpdateRole 修改角色名、描述和权限，权限全量覆盖
+// @param role 角色信息，包括权限名
+func (ctl *Rbac) UpdateRole(role model.Role) error {
+	permissionIds, err := ctl.permissionIds(role.Permissions)
+	if err != nil {
+		return err
+	}
+
+	if exist, err := ctl.getIdByName(role.Name); err != nil {
+		return err
+	} else if exist > 0 && exist != role.Id {
+		return apierr.ErrRoleNameExist
+	}
+
+	err = ctl.db.Transaction(func(tx *gorm.DB) error {
+		// 内容未变化时影响行数为0，角色是否存在由调用方检查
+		if err := tx.Model(&model.Role{Id: role.Id}).Select("name", "description", "updated_at").Updates(&role).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		if err := tx.Where("role_id = ?", role.Id).Delete(&model.RolePermission{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		return ctl.savePermissions(tx, role.Id, permissionIds)
+	})
+	if err != nil {
+		return err
+	}
+
+	ctl.ExpireRbacData()
+	return nil
+}
+
+// DeleteRole 删除角色，同时删除角色的权限和分配
+func (ctl *Rbac) DeleteRole(id int64) error {
+	err := ctl.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&model.Role{Id: id})
+		if res.Error != nil {
+			return errors.WithStack(res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return apierr.ErrRoleNoExist
+		}
+
+		if err := tx.Where("role_id = ?", id).Delete(&model.RolePermission{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&model.UserRole{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctl.ExpireRbacData()
+	return nil
+}
+
+// SetUserRoles 设置用户的角色，全量覆盖
+// @param userId 用户id
+// @param roleIds 角色id，为空时清空用户的角色
+func (ctl *Rbac) SetUserRoles(userId int64, roleIds []int64) error {
+	err := ctl.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&model.UserRole{}).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		if len(roleIds) == 0 {
+			return nil
+		}
+
+		userRoles := make([]model.UserRole, 0, len(roleIds))
+		for _, id := range roleIds {
+			userRoles = append(userRoles, model.UserRole{UserId: userId, RoleId: id})
+		}
+		if err := tx.Create(&userRoles).Error; err != nil {
+			return errors.WithStack(err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctl.ExpireRbacData()
+	return nil
+}
+
+// ExistsUser 查询用户是否存在且未注销
+// @param userId 用户id
+func (ctl *Rbac) ExistsUser(userId int64) (bool, error) {
+	var count int64
+	if err := ctl.db.Model(&model.User{}).Where("id = ? and deleted_at = 0", userId).Count(&count).Error; err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return count > 0, nil
+}
+
+// CountActiveRoleUsers 查询角色分配的未注销且未封禁的用户数
+// @param roleId 角色id
+func (ctl *Rbac) CountActiveRoleUsers(roleId int64) (int64, error) {
+	var count int64
+	err := ctl.db.Table("user_role").Joins("join user on user.id = user_role.user_id").
+		Where("user_role.role_id = ? and user.deleted_at = 0", roleId).
+		Where("user.banned_at = 0 or (user.ban_expire_at > 0 and user.ban_expire_at <= ?)", time.Now().Unix()).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+func (ctl *Rbac) ExpireRbacData() {
+	rbacData.Lock()
+	rbacData.isValid = false
+	rbacData.Unlock()
+}
+
+// load 缓存失效或过期时从数据库加载所有角色、权限和分配
+func (ctl *Rbac) load() error {
+	rbacData.RLock()
+	valid := rbacData.isValid && time.Since(rbacData.loadedAt) < ctl.expire
+	rbacData.RUnlock()
+	if valid {
+		return nil
+	}
+
+	var roles []model.Role
+	var permissions []model.Permission
+	var rolePermissions []model.RolePermission
+	var userRoles []model.UserRole
+
+	if err := ctl.db.Order("id").Find(&roles).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ctl.db.Order("id").Find(&permissions).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ctl.db.Find(&rolePermissions).Error; err != nil {
+		return errors.WithStack(err)
+	}
+	if err := ctl.db.Order("role_id").Find(&userRoles).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	permissionNames := make(map[int64]string, len(permissions))
+	permissionIds := make(map[string]int64, len(permissions))
+	for _, v := range permissions {
+		permissionNames[v.Id] = v.Name
+		permissionIds[v.Name] = v.Id
+	}
+
+	rolePermissionMap := make(map[int64]map[string]bool, len(roles))
+	for _, v := range rolePermissions {
+		name, ok := permissionNames[v.PermissionId]
+		if !ok {
+			continue
+		}
+		if rolePermissionMap[v.RoleId] == nil {
+			rolePermissionMap[v.RoleId] = make(map[string]bool)
+		}
+		rolePermissionMap[v.RoleId][name] = true
+	}
+
+	roleMap := make(map[int64]model.Role, len(roles))
+	for i := range roles {
+		names := make([]string, 0, len(rolePermissionMap[roles[i].Id]))
+		for name := range rolePermissionMap[roles[i].Id] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		roles[i].Permissions = names
+		roleMap[roles[i].Id] = roles[i]
+	}
+
+	userRoleMap := make(map[int64][]int64)
+	roleUsers := make(map[int64]int, len(roles))
+	for _, v := range userRoles {
+		if _, ok := roleMap[v.RoleId]; !ok {
+			continue
+		}
+		userRoleMap[v.UserId] = append(userRoleMap[v.UserId], v.RoleId)
+		roleUsers[v.RoleId]++
+	}
+
+	rbacData.Lock()
+	rbacData.roles = roles
+	rbacData.roleMap = roleMap
+	rbacData.permissions = permissions
+	rbacData.permissionIds = permissionIds
+	rbacData.userRoles = userRoleMap
+	rbacData.roleUsers = roleUsers
+	rbacData.rolePermissions = rolePermissionMap
+	rbacData.loadedAt = time.Now()
+	rbacData.isValid = true
+	rbacData.Unlock()
+
+	return nil
+}
+
+// permissionIds 权限名转化为权限id
+func (ctl *Rbac) permissionIds(names []string) ([]int64, error) {
+	if err := ctl.load(); err != nil {
+		return nil, err
+	}
+
+	rbacData.RLock()
+	defer rbacData.RUnlock()
+
+	ids := make([]int64, 0, len(names))
+	seen := make(map[int64]bool, len(names))
+	for _, name := range names {
+		id, ok := rbacData.permissionIds[name]
+		if !ok {
+			return nil, apierr.ErrPermissionNoExist
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (ctl *Rbac) getIdByName(name string) (int64, error) {
+	role := model.Role{}
+	if err := ctl.db.Select("id").Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, nil
+		}
+		return 0, errors.WithStack(err)
+	}
+
+	return role.Id, nil
+}
+
+func (ctl *Rbac) savePermissions(tx *gorm.DB, roleId int64, permissionIds []int64) error {
+	if len(permissionIds) == 0 {
+		return nil
+	}
+
+	rolePermissions := make([]model.RolePermission, 0, len(permissionIds))
+	for _, id := range permissionIds {
+		rolePermissions = append(rolePermissions, model.RolePermission{RoleId: roleId, PermissionId: id})
+	}
+	if err := tx.Create(&rolePermissions).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
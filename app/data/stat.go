@@ -0,0 +1,163 @@
+package data
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// 实现service层中的data接口
+
+// Stat 访问统计，数据只保存在redis中
+// 每日PV使用hash: 对象id => 访问量，每日UV使用HyperLogLog
+type Stat struct {
+	cache  cache.CustomRedis
+	conf   model.ViewStatConfig
+	logger *logger.CustomLogger
+}
+
+func NewStat(cacheConn *redis.Pool, conf model.ViewStatConfig, logger *logger.CustomLogger) service.IStatData {
+	r := cache.ConnRedisByPool(cacheConn, "stat")
+
+	return &Stat{
+		cache:  r,
+		conf:   conf,
+		logger: logger,
+	}
+}
+
+func NewArticleViewTracker(cacheConn *redis.Pool, conf model.ViewStatConfig, logger *logger.CustomLogger) service.IArticleViewTracker {
+	r := cache.ConnRedisByPool(cacheConn, "stat")
+
+	return &Stat{
+		cache:  r,
+		conf:   conf,
+		logger: logger,
+	}
+}
+
+const statDateLayout = "20060102"
+
+// RecordView 记录一次文章访问
+// @param articleId 文章id
+// @param categoryId 文章分类id
+// @param visitor 访问者标识
+// @param now 访问时间
+// @return bool 是否计入阅读量，窗口期内同一访问者的重复访问不计入
+// @return error
+func (ctl *Stat) RecordView(articleId, categoryId int64, visitor string, now time.Time) (bool, error) {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	// 窗口期内首次访问才计入阅读量
+	counted := true
+	_, err = redis.String(conn.Do("set", ctl.cacheSeenKey(articleId, visitor), 1, "nx", "ex", ctl.conf.Window))
+	if err != nil {
+		if !errors.Is(err, redis.ErrNil) {
+			return false, errors.WithStack(err)
+		}
+		counted = false
+	}
+
+	date := now.Format(statDateLayout)
+	expire := ctl.conf.RetentionDays * 24 * 3600
+
+	// UV不受窗口期影响，HyperLogLog自身去重
+	if err := conn.Send("multi"); err != nil {
+		return false, errors.WithStack(err)
+	}
+	targets := []struct {
+		target string
+		id     int64
+	}{
+		{model.StatTargetArticle, articleId},
+		{model.StatTargetCategory, categoryId},
+	}
+	for _, v := range targets {
+		uvKey := ctl.cacheUVKey(v.target, date, v.id)
+		conn.Send("pfadd", uvKey, visitor)
+		conn.Send("expire", uvKey, expire)
+
+		if counted {
+			pvKey := ctl.cachePVKey(v.target, date)
+			conn.Send("hincrby", pvKey, v.id, 1)
+			conn.Send("expire", pvKey, expire)
+		}
+	}
+	if _, err := conn.Do("exec"); err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return counted, nil
+}
+
+// ListDaily 查询对象每日的PV和UV
+// @param target 统计对象，model.StatTargetXxx
+// @param id 对象id
+// @param start 开始日期
+// @param end 结束日期(包括)
+// @return []model.DailyStat 按日期升序
+// @return error
+func (ctl *Stat) ListDaily(target string, id int64, start, end time.Time) ([]model.DailyStat, error) {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	var days []time.Time
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+
+	// 批量发送命令，减少网络往返
+	for _, day := range days {
+		date := day.Format(statDateLayout)
+		conn.Send("hget", ctl.cachePVKey(target, date), id)
+		conn.Send("pfcount", ctl.cacheUVKey(target, date, id))
+	}
+	if err := conn.Flush(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	stats := make([]model.DailyStat, 0, len(days))
+	for _, day := range days {
+		pv, err := redis.Int64(conn.Receive())
+		if err != nil && !errors.Is(err, redis.ErrNil) {
+			return nil, errors.WithStack(err)
+		}
+
+		uv, err := redis.Int64(conn.Receive())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		stats = append(stats, model.DailyStat{
+			Date: day.Format("2006-01-02"),
+			PV:   pv,
+			UV:   uv,
+		})
+	}
+
+	return stats, nil
+}
+
+func (ctl *Stat) cacheSeenKey(articleId int64, visitor string) string {
+	return fmt.Sprintf("%s:seen:articleId#%d:%s", ctl.cache.CachePrefixKey(), articleId, visitor)
+}
+
+func (ctl *Stat) cachePVKey(target, date string) string {
+	return fmt.Sprintf("%s:pv:%s:%s", ctl.cache.CachePrefixKey(), target, date)
+}
+
+func (ctl *Stat) cacheUVKey(target, date string, id int64) string {
+	return fmt.Sprintf("%s:uv:%s:%s:id#%d", ctl.cache.CachePrefixKey(), target, date, id)
+}
@@ -0,0 +1,76 @@
+package data
+
+import (
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStorage 本地文件系统存储
+type LocalStorage struct {
+	dir     string
+	baseUrl string
+}
+
+func NewLocalStorage(conf model.LocalStorageConfig) service.IStorage {
+	return &LocalStorage{
+		dir:     conf.Dir,
+		baseUrl: strings.TrimSuffix(conf.BaseUrl, "/"),
+	}
+}
+
+func (ctl *LocalStorage) Put(key string, data []byte, contentType string) error {
+	path, err := ctl.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 先写临时文件再重命名，避免读到写了一半的文件
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return errors.WithStack(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (ctl *LocalStorage) Delete(key string) error {
+	path, err := ctl.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (ctl *LocalStorage) URL(key string) string {
+	return ctl.baseUrl + "/" + key
+}
+
+// path 文件的本地路径，key不能跳出存储目录
+func (ctl *LocalStorage) path(key string) (string, error) {
+	path := filepath.Join(ctl.dir, filepath.FromSlash(key))
+
+	rel, err := filepath.Rel(ctl.dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", errors.Errorf("invalid storage key: %s", key)
+	}
+
+	return path, nil
+}
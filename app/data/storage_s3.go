@@ -0,0 +1,187 @@
+package data
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Storage S3兼容的对象存储，使用AWS Signature Version 4签名
+// 开启PathStyle后可以直接对接本地的MinIO等兼容服务
+type S3Storage struct {
+	conf   model.S3StorageConfig
+	client *http.Client
+}
+
+func NewS3Storage(conf model.S3StorageConfig) service.IStorage {
+	conf.Endpoint = strings.TrimSuffix(conf.Endpoint, "/")
+	conf.BaseUrl = strings.TrimSuffix(conf.BaseUrl, "/")
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+
+	return &S3Storage{
+		conf:   conf,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (ctl *S3Storage) Put(key string, data []byte, contentType string) error {
+	req, err := ctl.newRequest(http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return ctl.do(req)
+}
+
+func (ctl *S3Storage) Delete(key string) error {
+	req, err := ctl.newRequest(http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	return ctl.do(req)
+}
+
+func (ctl *S3Storage) URL(key string) string {
+	if ctl.conf.BaseUrl != "" {
+		return ctl.conf.BaseUrl + "/" + key
+	}
+
+	objectUrl, err := ctl.objectURL(key)
+	if err != nil {
+		return ""
+	}
+	return objectUrl.String()
+}
+
+// objectURL 对象地址，路径形式: endpoint/bucket/key，域名形式: bucket.endpoint/key
+func (ctl *S3Storage) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(ctl.conf.Endpoint)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if ctl.conf.PathStyle {
+		u.Path = "/" + ctl.conf.Bucket + "/" + key
+	} else {
+		u.Host = ctl.conf.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+
+	return u, nil
+}
+
+func (ctl *S3Storage) newRequest(method, key string, data []byte) (*http.Request, error) {
+	u, err := ctl.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	ctl.sign(req, data, time.Now().UTC())
+	return req, nil
+}
+
+func (ctl *S3Storage) do(req *http.Request) error {
+	res, err := ctl.client.Do(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer res.Body.Close()
+
+	// 删除不存在的对象也返回2xx
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return errors.Errorf("s3 %s %s: %s %s", req.Method, req.URL.Path, res.Status, msg)
+	}
+
+	return nil
+}
+
+// s3SignedHeaders 参与签名的请求头
+const s3SignedHeaders = "host;x-amz-content-sha256;x-amz-date"
+
+// sign 使用AWS Signature Version 4签名请求
+func (ctl *S3Storage) sign(req *http.Request, payload []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest := s3CanonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.RawQuery, req.URL.Host, payloadHash, amzDate)
+	scope, signature := signV4(ctl.conf.SecretKey, now, ctl.conf.Region, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		ctl.conf.AccessKey, scope, s3SignedHeaders, signature))
+}
+
+// s3CanonicalRequest 规范请求，签名的请求头为s3SignedHeaders
+func s3CanonicalRequest(method, escapedPath, rawQuery, host, payloadHash, amzDate string) string {
+	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", host, payloadHash, amzDate)
+
+	return strings.Join([]string{
+		method,
+		escapedPath,
+		rawQuery,
+		canonicalHeaders,
+		s3SignedHeaders,
+		payloadHash,
+	}, "\n")
+}
+
+// signV4 计算规范请求的Signature Version 4签名
+// @param secretKey 密钥
+// @param now 签名时间，UTC
+// @param region 区域
+// @param canonicalRequest 规范请求
+// @return string 凭证范围
+// @return string 签名
+func signV4(secretKey string, now time.Time, region, canonicalRequest string) (string, string) {
+	date := now.Format("20060102")
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, region)
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", now.Format("20060102T150405Z"), scope,
+		sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return scope, hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
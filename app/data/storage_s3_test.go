@@ -0,0 +1,288 @@
+package data
+
+import (
+	"fmt"
+	"github.com/mittacy/blogBack/app/model"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testS3AccessKey = "test-access-key"
+	testS3SecretKey = "test-secret-key"
+	testS3Bucket    = "blog"
+	testS3Region    = "us-east-1"
+)
+
+type s3Object struct {
+	data        []byte
+	contentType string
+}
+
+// fakeS3 S3的替身，校验签名后在内存中保存对象
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string]s3Object // 路径(/bucket/key) => 对象
+}
+
+func newFakeS3(t *testing.T) (*fakeS3, *httptest.Server) {
+	fake := &fakeS3{objects: map[string]s3Object{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if msg := verifyS3Signature(r, body); msg != "" {
+			t.Logf("fake s3 reject %s %s: %s", r.Method, r.URL.Path, msg)
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("<Error><Code>SignatureDoesNotMatch</Code></Error>"))
+			return
+		}
+
+		fake.Lock()
+		defer fake.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			fake.objects[r.URL.Path] = s3Object{data: body, contentType: r.Header.Get("Content-Type")}
+			w.WriteHeader(http.StatusOK)
+		case http.MethodDelete:
+			delete(fake.objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+
+	return fake, server
+}
+
+func (ctl *fakeS3) object(path string) (s3Object, bool) {
+	ctl.Lock()
+	defer ctl.Unlock()
+
+	obj, ok := ctl.objects[path]
+	return obj, ok
+}
+
+// verifyS3Signature 使用服务端收到的请求和测试密钥校验签名，返回不一致的原因
+// 规范请求和签名的计算由TestS3CanonicalRequest和TestSignV4的固定向量保证
+func verifyS3Signature(r *http.Request, body []byte) string {
+	payloadHash := sha256Hex(body)
+	if r.Header.Get("X-Amz-Content-Sha256") != payloadHash {
+		return "payload hash mismatch"
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	now, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return "invalid x-amz-date"
+	}
+
+	canonicalRequest := s3CanonicalRequest(r.Method, r.URL.EscapedPath(), r.URL.RawQuery, r.Host, payloadHash, amzDate)
+	scope, signature := signV4(testS3SecretKey, now, testS3Region, canonicalRequest)
+
+	expect := fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		testS3AccessKey, scope, s3SignedHeaders, signature)
+	if r.Header.Get("Authorization") != expect {
+		return "signature mismatch"
+	}
+
+	return ""
+}
+
+// TestSignV4 AWS文档中GET Object的示例: https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func TestSignV4(t *testing.T) {
+	canonicalRequest := "GET\n" +
+		"/test.txt\n" +
+		"\n" +
+		"host:examplebucket.s3.amazonaws.com\n" +
+		"range:bytes=0-9\n" +
+		"x-amz-content-sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n" +
+		"x-amz-date:20130524T000000Z\n" +
+		"\n" +
+		"host;range;x-amz-content-sha256;x-amz-date\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	now := time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC)
+
+	if got := sha256Hex([]byte(canonicalRequest)); got != "7344ae5b7ee6c3e7e6b0fe0640412a37625d1fbfff95c48bbb2dc43964946972" {
+		t.Fatalf("canonical request hash = %s", got)
+	}
+
+	scope, signature := signV4("wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY", now, "us-east-1", canonicalRequest)
+	if scope != "20130524/us-east-1/s3/aws4_request" {
+		t.Errorf("signV4() scope = %s", scope)
+	}
+	if signature != "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41" {
+		t.Errorf("signV4() signature = %s", signature)
+	}
+}
+
+func TestS3CanonicalRequest(t *testing.T) {
+	storage := newTestS3Storage("http://127.0.0.1:9000")
+	req, err := http.NewRequest(http.MethodPut, "http://127.0.0.1:9000/blog/files/ab/a%20b.txt", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC)
+	storage.sign(req, []byte("hello"), now)
+
+	helloHash := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if req.Header.Get("X-Amz-Content-Sha256") != helloHash || req.Header.Get("X-Amz-Date") != "20130524T000000Z" {
+		t.Errorf("sign() headers = %v", req.Header)
+	}
+
+	canonicalRequest := "PUT\n" +
+		"/blog/files/ab/a%20b.txt\n" +
+		"\n" +
+		"host:127.0.0.1:9000\n" +
+		"x-amz-content-sha256:" + helloHash + "\n" +
+		"x-amz-date:20130524T000000Z\n" +
+		"\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		helloHash
+	_, signature := signV4(testS3SecretKey, now, testS3Region, canonicalRequest)
+
+	want := "AWS4-HMAC-SHA256 Credential=" + testS3AccessKey + "/20130524/us-east-1/s3/aws4_request, " +
+		"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + signature
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("sign() Authorization = %s, want %s", got, want)
+	}
+}
+
+func newTestS3Storage(endpoint string) *S3Storage {
+	return NewS3Storage(model.S3StorageConfig{
+		Endpoint:  endpoint + "/",
+		Bucket:    testS3Bucket,
+		AccessKey: testS3AccessKey,
+		SecretKey: testS3SecretKey,
+		PathStyle: true,
+	}).(*S3Storage)
+}
+
+func TestS3StoragePut(t *testing.T) {
+	fake, server := newFakeS3(t)
+	defer server.Close()
+
+	storage := newTestS3Storage(server.URL)
+	data := []byte("hello s3")
+	if err := storage.Put("files/ab/abcdef.txt", data, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	obj, ok := fake.object("/blog/files/ab/abcdef.txt")
+	if !ok {
+		t.Fatal("object not stored at /blog/files/ab/abcdef.txt")
+	}
+	if string(obj.data) != string(data) {
+		t.Errorf("stored data = %q, want %q", obj.data, data)
+	}
+	if obj.contentType != "text/plain" {
+		t.Errorf("stored content type = %q, want %q", obj.contentType, "text/plain")
+	}
+}
+
+func TestS3StoragePutEmpty(t *testing.T) {
+	fake, server := newFakeS3(t)
+	defer server.Close()
+
+	storage := newTestS3Storage(server.URL)
+	if err := storage.Put("files/e3/empty.txt", []byte{}, "text/plain"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if obj, ok := fake.object("/blog/files/e3/empty.txt"); !ok || len(obj.data) != 0 {
+		t.Errorf("object = %+v, %v, want empty object", obj, ok)
+	}
+}
+
+func TestS3StorageDelete(t *testing.T) {
+	fake, server := newFakeS3(t)
+	defer server.Close()
+
+	storage := newTestS3Storage(server.URL)
+	if err := storage.Put("thumbs/ab/abcdef.png", []byte("png"), "image/png"); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if err := storage.Delete("thumbs/ab/abcdef.png"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, ok := fake.object("/blog/thumbs/ab/abcdef.png"); ok {
+		t.Error("object still exists after Delete()")
+	}
+
+	// 删除不存在的对象不返回错误
+	if err := storage.Delete("thumbs/ab/abcdef.png"); err != nil {
+		t.Errorf("Delete() missing object error = %v", err)
+	}
+}
+
+func TestS3StorageErrorStatus(t *testing.T) {
+	fake, server := newFakeS3(t)
+	defer server.Close()
+
+	storage := newTestS3Storage(server.URL)
+	storage.conf.SecretKey = "wrong-secret-key"
+
+	err := storage.Put("files/ab/abcdef.txt", []byte("hello"), "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put() with wrong secret error = %v, want 403 error", err)
+	}
+	if _, ok := fake.object("/blog/files/ab/abcdef.txt"); ok {
+		t.Error("object stored with wrong signature")
+	}
+
+	if err := storage.Delete("files/ab/abcdef.txt"); err == nil {
+		t.Error("Delete() with wrong secret error = nil, want error")
+	}
+}
+
+func TestS3StorageURL(t *testing.T) {
+	tests := []struct {
+		name string
+		conf model.S3StorageConfig
+		key  string
+		want string
+	}{
+		{
+			name: "path style",
+			conf: model.S3StorageConfig{Endpoint: "http://127.0.0.1:9000/", Bucket: "blog", PathStyle: true},
+			key:  "files/ab/abcdef.jpg",
+			want: "http://127.0.0.1:9000/blog/files/ab/abcdef.jpg",
+		},
+		{
+			name: "virtual host style",
+			conf: model.S3StorageConfig{Endpoint: "https://s3.amazonaws.com", Bucket: "blog"},
+			key:  "files/ab/abcdef.jpg",
+			want: "https://blog.s3.amazonaws.com/files/ab/abcdef.jpg",
+		},
+		{
+			name: "base url",
+			conf: model.S3StorageConfig{Endpoint: "https://s3.amazonaws.com", Bucket: "blog", BaseUrl: "https://cdn.example.com/"},
+			key:  "thumbs/ab/abcdef.png",
+			want: "https://cdn.example.com/thumbs/ab/abcdef.png",
+		},
+		{
+			name: "invalid endpoint",
+			conf: model.S3StorageConfig{Endpoint: "http://[::1", Bucket: "blog", PathStyle: true},
+			key:  "files/ab/abcdef.jpg",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewS3Storage(tt.conf).URL(tt.key); got != tt.want {
+				t.Errorf("URL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
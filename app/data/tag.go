@@ -0,0 +1,160 @@
+package data
+
+import (
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// 实现service层中的data接口
+
+type Tag struct {
+	db     *gorm.DB
+	logger *logger.CustomLogger
+}
+
+func NewTag(db *gorm.DB, logger *logger.CustomLogger) service.ITagData {
+	return &Tag{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func NewArticleTag(db *gorm.DB, logger *logger.CustomLogger) service.IArticleTagData {
+	return &Tag{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (ctl *Tag) Create(tag *model.Tag) error {
+	// 查询name是否存在
+	exist, err := ctl.GetByName(tag.Name)
+	if err != nil {
+		return err
+	}
+
+	if exist != nil {
+		return apierr.ErrTagNameExist
+	}
+
+	// 创建标签
+	if err := ctl.db.Create(tag).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+func (ctl *Tag) Delete(id int64) error {
+	tx := ctl.db.Begin()
+
+	// 删除标签
+	res := tx.Delete(&model.Tag{Id: id})
+	if res.Error != nil || res.RowsAffected == 0 {
+		tx.Rollback()
+		if res.Error != nil {
+			return errors.WithStack(res.Error)
+		}
+		return apierr.ErrTagNoExist
+	}
+
+	// 删除标签与文章的关联
+	if err := tx.Where("tag_id = ?", id).Delete(&model.ArticleTag{}).Error; err != nil {
+		tx.Rollback()
+		return errors.WithStack(err)
+	}
+
+	tx.Commit()
+	return nil
+}
+
+func (ctl *Tag) UpdateNameById(tag model.Tag) error {
+	// 查询name是否存在
+	exist, err := ctl.GetByName(tag.Name)
+	if err != nil {
+		return err
+	}
+
+	if exist != nil && exist.Id != tag.Id {
+		return apierr.ErrTagNameExist
+	}
+
+	// 更新
+	res := ctl.db.Select("name").Updates(&tag)
+	if res.Error != nil {
+		return errors.WithStack(res.Error)
+	}
+	if res.RowsAffected == 0 && exist == nil {
+		return apierr.ErrTagNoExist
+	}
+
+	return nil
+}
+
+func (ctl *Tag) GetByName(name string) (*model.Tag, error) {
+	tag := model.Tag{}
+
+	if err := ctl.db.Where("name = ?", name).First(&tag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return &tag, nil
+}
+
+func (ctl *Tag) List(page, pageSize int) ([]model.Tag, error) {
+	tags := []model.Tag{}
+
+	err := ctl.db.Order("article_count desc, id asc").Offset((page - 1) * pageSize).Limit(pageSize).Find(&tags).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return tags, nil
+}
+
+func (ctl *Tag) GetSum() (int64, error) {
+	var count int64
+
+	if err := ctl.db.Model(&model.Tag{}).Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// ListByArticles 查询多篇文章的标签
+// @param articleIds 文章id
+// @return map[int64][]model.Tag 文章id => 标签
+// @return error
+func (ctl *Tag) ListByArticles(articleIds []int64) (map[int64][]model.Tag, error) {
+	res := make(map[int64][]model.Tag, len(articleIds))
+	if len(articleIds) == 0 {
+		return res, nil
+	}
+
+	var rows []struct {
+		model.Tag
+		ArticleId int64
+	}
+
+	err := ctl.db.Table("article_tag").Select("tag.id, tag.name, tag.article_count, article_tag.article_id").
+		Joins("join tag on tag.id = article_tag.tag_id").
+		Where("article_tag.article_id in ?", articleIds).
+		Order("tag.id asc").Scan(&rows).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, v := range rows {
+		res[v.ArticleId] = append(res[v.ArticleId], v.Tag)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,134 @@
+package data
+
+import (
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"regexp"
+)
+
+type Upload struct {
+	db     *gorm.DB
+	logger *logger.CustomLogger
+}
+
+func NewUpload(db *gorm.DB, logger *logger.CustomLogger) service.IUploadData {
+	return &Upload{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func NewUploadClean(db *gorm.DB, logger *logger.CustomLogger) service.IUploadCleanData {
+	return &Upload{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func (ctl *Upload) Create(upload *model.Upload) error {
+	if err := ctl.db.Create(upload).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// GetByHash 根据内容hash查询已上传的文件
+// @return *model.Upload 不存在时返回nil
+func (ctl *Upload) GetByHash(hash string) (*model.Upload, error) {
+	upload := model.Upload{}
+	if err := ctl.db.Where("hash = ?", hash).First(&upload).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	return &upload, nil
+}
+
+// Touch 更新文件的最后使用时间
+// @param id 文件id
+// @param usedAt 使用时间，时间戳
+// @return bool 记录不存在(已被清理)时返回false
+func (ctl *Upload) Touch(id, usedAt int64) (bool, error) {
+	res := ctl.db.Model(&model.Upload{}).Where("id = ?", id).Update("last_used_at", usedAt)
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// ListUsedBefore 查询最后使用时间在某个时间之前的文件，按id升序
+// @param usedBefore 最后使用时间，时间戳
+// @param afterId 从该id之后开始查询
+// @param limit 数量
+func (ctl *Upload) ListUsedBefore(usedBefore, afterId int64, limit int) ([]model.Upload, error) {
+	var uploads []model.Upload
+	if err := ctl.db.Where("last_used_at < ? and id > ?", usedBefore, afterId).
+		Order("id").Limit(limit).Find(&uploads).Error; err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return uploads, nil
+}
+
+// IsReferenced 查询文件是否被文章引用，包括文章封面、文章内容和历史版本内容，见model.UploadRef
+// @param path 文件或缩略图在存储中的路径
+func (ctl *Upload) IsReferenced(path string) (bool, error) {
+	var count int64
+	if err := ctl.db.Model(&model.UploadRef{}).Where("path = ?", path).Limit(1).Count(&count).Error; err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	return count > 0, nil
+}
+
+// DeleteUsedBefore 最后使用时间仍在某个时间之前时删除记录，查询后被重复上传命中的文件不会被删除
+// @param id 文件id
+// @param usedBefore 最后使用时间，时间戳
+// @return bool 是否删除了记录
+func (ctl *Upload) DeleteUsedBefore(id, usedBefore int64) (bool, error) {
+	res := ctl.db.Where("last_used_at < ?", usedBefore).Delete(&model.Upload{Id: id})
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+
+	return res.RowsAffected > 0, nil
+}
+
+// uploadPathReg 上传文件和缩略图的存储路径，引用地址的前缀随存储配置而变化，只匹配路径
+var uploadPathReg = regexp.MustCompile(`(?:files|thumbs)/[0-9a-f]{2}/[0-9a-f]{64}\.[a-z]+`)
+
+// saveUploadRefs 在事务中记录文章引用的上传文件，已记录的引用不会重复写入
+// 修改文章时旧内容保存为历史版本，旧内容的引用保留
+// @param tx 事务
+// @param articleId 文章id
+// @param texts 文章封面、内容等可能包含文件地址的文本
+func saveUploadRefs(tx *gorm.DB, articleId int64, texts ...string) error {
+	seen := map[string]bool{}
+	var refs []model.UploadRef
+	for _, text := range texts {
+		for _, path := range uploadPathReg.FindAllString(text, -1) {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			refs = append(refs, model.UploadRef{Path: path, ArticleId: articleId})
+		}
+	}
+
+	if len(refs) == 0 {
+		return nil
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&refs).Error; err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"fmt"
+	"github.com/mittacy/blogBack/app/model"
+	"strings"
+	"testing"
+)
+
+func TestUploadIsReferenced(t *testing.T) {
+	data, db := newTestArticle(t)
+	uploadData := NewUploadClean(db, nil).(*Upload)
+
+	hash := func(c string) string { return strings.Repeat(c, 64) }
+	picture := "files/aa/" + hash("a") + ".jpg"
+	oldImage := "files/bb/" + hash("b") + ".png"
+	oldThumb := "thumbs/bb/" + hash("b") + ".png"
+	newImage := "files/cc/" + hash("c") + ".gif"
+
+	article := model.Article{
+		CategoryId: 1,
+		Status:     model.ArticleStatusPublished,
+		Picture:    "https://cdn.example.com/" + picture,
+		Content:    fmt.Sprintf("[![a](/uploads/%s)](/uploads/%s)", oldThumb, oldImage),
+	}
+	if err := data.Insert(&article); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	// 旧内容保存为历史版本，仍被引用
+	update := model.Article{Id: article.Id, CategoryId: 1, Content: fmt.Sprintf("![b](http://127.0.0.1:9000/blog/%s)", newImage)}
+	if err := data.UpdateInfo(&update, testArticleFields); err != nil {
+		t.Fatalf("UpdateInfo() error = %v", err)
+	}
+
+	checkReferenced := func(step string, want map[string]bool) {
+		t.Helper()
+		for path, referenced := range want {
+			got, err := uploadData.IsReferenced(path)
+			if err != nil {
+				t.Fatalf("%s: IsReferenced(%s) error = %v", step, path, err)
+			}
+			if got != referenced {
+				t.Errorf("%s: IsReferenced(%s) = %v, want %v", step, path, got, referenced)
+			}
+		}
+	}
+
+	unused := "files/dd/" + hash("d") + ".zip"
+	checkReferenced("update", map[string]bool{picture: true, oldImage: true, oldThumb: true, newImage: true, unused: false})
+
+	if err := data.Delete(article.Id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	checkReferenced("delete", map[string]bool{picture: false, oldImage: false, oldThumb: false, newImage: false})
+}
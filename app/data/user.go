@@ -14,6 +14,7 @@ import (
 	"gorm.io/gorm"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // 实现service层中的data接口
@@ -34,6 +35,26 @@ func NewUser(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) se
 	}
 }
 
+func NewCommentUser(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.ICommentUserData {
+	r := cache.ConnRedisByPool(cacheConn, "user")
+
+	return &User{
+		db:    	db,
+		cache: 	r,
+		logger: logger,
+	}
+}
+
+func NewSessionUser(db *gorm.DB, cacheConn *redis.Pool, logger *logger.CustomLogger) service.ISessionUserData {
+	r := cache.ConnRedisByPool(cacheConn, "user")
+
+	return &User{
+		db:     db,
+		cache:  r,
+		logger: logger,
+	}
+}
+
 // Create 创建用户
 // @param user 用户信息
 // @return error
@@ -72,6 +93,106 @@ func (ctl *User) UpdatesById(user model.User, updateFields []string, isCleanCach
 	return nil
 }
 
+// UpdateEmail 修改邮箱，只有邮箱仍为修改前的值时才修改，由唯一索引保证新邮箱未被注册
+// @param user 用户修改前的信息
+// @param email 新邮箱
+// @return error 新邮箱已被注册时返回apierr.ErrUserEmailExist，邮箱已被并发修改时返回apierr.ErrUserNoExist
+func (ctl *User) UpdateEmail(user *model.User, email string) error {
+	res := ctl.db.Model(&model.User{}).Where("id = ? AND email = ?", user.Id, user.Email).Update("email", email)
+	if err := res.Error; err != nil {
+		if strings.Contains(err.Error(), "Duplicate") && strings.Contains(err.Error(), model.UserIdxEmail) {
+			return apierr.ErrUserEmailExist
+		}
+		return errors.WithStack(err)
+	}
+	if res.RowsAffected == 0 {
+		return apierr.ErrUserNoExist
+	}
+
+	// 清除旧邮箱到id的映射，旧邮箱不能再用于登录
+	if err := ctl.cache.Del(ctl.cacheUserKey(user.Id), ctl.cacheIdByEmailKey(user.Email), ctl.cacheIdByEmailKey(email)); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	return nil
+}
+
+// CountArticles 查询用户已发布的文章数
+// @param userId 用户id
+// @return int64
+// @return error
+func (ctl *User) CountArticles(userId int64) (int64, error) {
+	var count int64
+	err := ctl.db.Model(&model.Article{}).
+		Where("author_id = ? AND deleted = ? AND status = ?", userId, model.ArticleDeletedNo, model.ArticleStatusPublished).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// CountComments 查询用户正常显示的评论数
+// @param userId 用户id
+// @return int64
+// @return error
+func (ctl *User) CountComments(userId int64) (int64, error) {
+	var count int64
+	err := ctl.db.Model(&model.Comment{}).
+		Where("user_id = ? AND deleted = ? AND status = ?", userId, model.CommentDeletedNo, model.CommentStatusNormal).
+		Count(&count).Error
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// ListByFilter 按筛选条件分页查询用户，供管理员使用
+// @param selectFields 查询字段
+// @param filter 筛选条件
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.User
+// @return error
+func (ctl *User) ListByFilter(selectFields []string, filter model.UserFilter, page, pageSize int) ([]model.User, error) {
+	startIndex := (page - 1) * pageSize
+	var users []model.User
+
+	err := ctl.scopeFilter(filter).Select(selectFields).
+		Offset(startIndex).Limit(pageSize).Order("id desc").Find(&users).Error
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return users, nil
+}
+
+// GetSumByFilter 查询符合筛选条件的用户数
+// @param filter 筛选条件
+// @return int64
+// @return error
+func (ctl *User) GetSumByFilter(filter model.UserFilter) (int64, error) {
+	var count int64
+	if err := ctl.scopeFilter(filter).Model(&model.User{}).Count(&count).Error; err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return count, nil
+}
+
+// CleanCache 清除用户的所有缓存，包括name、email到id的映射
+// @param user 用户修改前的信息
+// @return error
+func (ctl *User) CleanCache(user *model.User) error {
+	if err := ctl.cache.Del(ctl.cacheUserKey(user.Id), ctl.cacheIdByNameKey(user.Name), ctl.cacheIdByEmailKey(user.Email)); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
 // Get 查询用户记录
 // @param id 用户id
 // @return *model.User 用户信息
@@ -293,6 +414,45 @@ func (ctl *User) CacheById(user *model.User) error {
 	return nil
 }
 
+// scopeFilter 用户列表的筛选条件
+// @param filter 筛选条件
+// @return *gorm.DB
+func (ctl *User) scopeFilter(filter model.UserFilter) *gorm.DB {
+	db := ctl.db
+	if filter.Keyword != "" {
+		pattern := "%" + likeEscaper.Replace(filter.Keyword) + "%"
+		db = db.Where("name like ? or email like ?", pattern, pattern)
+	}
+
+	now := time.Now().Unix()
+	switch filter.Status {
+	case model.UserStatusNormal:
+		db = db.Where("deleted_at = 0 and (banned_at = 0 or (ban_expire_at > 0 and ban_expire_at <= ?))", now)
+	case model.UserStatusBanned:
+		db = db.Where("deleted_at = 0 and banned_at > 0 and (ban_expire_at = 0 or ban_expire_at > ?)", now)
+	case model.UserStatusDeleted:
+		db = db.Where("deleted_at > 0")
+	}
+
+	if filter.CreatedFrom > 0 {
+		db = db.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if filter.CreatedTo > 0 {
+		db = db.Where("created_at <= ?", filter.CreatedTo)
+	}
+	if filter.LoginFrom > 0 {
+		db = db.Where("login_at >= ?", filter.LoginFrom)
+	}
+	if filter.LoginTo > 0 {
+		db = db.Where("login_at <= ?", filter.LoginTo)
+	}
+
+	return db
+}
+
+// likeEscaper 转义like查询中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // cacheUserKey 缓存用户，区分为用户id
 // @param id 用户id
 // @return string 缓存完整键
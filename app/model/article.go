@@ -1,30 +1,62 @@
 package model
 
 type Article struct {
-	Id           int64  `json:"id"`
-	Weight       int64  `json:"weight"`
-	CategoryId   int64  `json:"category_id"`
-	CategoryName string `json:"category_name" gorm:"-"`
-	Title        string `json:"title"`
-	Views        int64  `json:"views"`
-	PreviewCtx   string `json:"preview_ctx"`
-	CreatedAt    int64  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    int64  `json:"updated_at" gorm:"autoUpdateTime"`
-	Content      string `json:"content"`
-	Deleted      int8   `json:"deleted"`
-	Picture      string `json:"picture"`
-	Sentence     string `json:"sentence"`
+	Id           int64   `json:"id"`
+	Weight       int64   `json:"weight"`
+	CategoryId   int64   `json:"category_id"`
+	AuthorId     int64   `json:"author_id"` // 创建文章的用户id
+	CategoryName string  `json:"category_name" gorm:"-"`
+	Title        string  `json:"title"`
+	Views        int64   `json:"views"`
+	PreviewCtx   string  `json:"preview_ctx"`
+	CreatedAt    int64   `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    int64   `json:"updated_at" gorm:"autoUpdateTime"`
+	Content      string  `json:"content"`
+	ContentHtml  string  `json:"content_html"` // 由Content渲染并过滤后的html
+	Toc          string  `json:"toc"`          // 标题目录，json数组
+	Deleted      int8    `json:"deleted"`
+	Picture      string  `json:"picture"`
+	Sentence     string  `json:"sentence"`
+	Status       int8    `json:"status"`
+	PublishAt    int64   `json:"publish_at"`
+	TagIds       []int64 `json:"tag_ids" gorm:"-"`
+	Tags         []Tag   `json:"tags" gorm:"-"`
+
+	// 搜索结果高亮
+	TitleHighlight string `json:"title_highlight" gorm:"-"`
+	Snippet        string `json:"snippet" gorm:"-"`
 }
 
 func (*Article) TableName() string {
 	return "article"
 }
 
+// ArticleFullTextIdx 文章全文索引名，需要使用ngram解析器以支持中文
+// ALTER TABLE article ADD FULLTEXT INDEX ftidx_article (title, preview_ctx, content) WITH PARSER ngram;
+const ArticleFullTextIdx = "ftidx_article"
+
+// 文章作者，已有数据迁移，旧文章归属于第一个超级管理员:
+// ALTER TABLE article ADD author_id bigint NOT NULL DEFAULT 0, ADD KEY idx_author_id (author_id);
+// UPDATE article SET author_id = (SELECT MIN(user_id) FROM user_role ur JOIN role r ON r.id = ur.role_id AND r.name = 'super_admin');
+
+// 文章html与目录，已有数据迁移:
+// ALTER TABLE article ADD content_html mediumtext NOT NULL, ADD toc text NOT NULL;
+// 旧文章的content_html为空，查询详情时实时渲染，下次编辑保存时写入
+
 const (
 	ArticleDeletedNo  = 0
 	ArticleDeletedYes = 1
 )
 
+// 文章状态，已有数据迁移:
+// ALTER TABLE article ADD status tinyint NOT NULL DEFAULT 3, ADD publish_at bigint NOT NULL DEFAULT 0;
+const (
+	ArticleStatusDraft     = 1 // 草稿
+	ArticleStatusScheduled = 2 // 定时发布，到达publish_at后由定时任务发布
+	ArticleStatusPublished = 3 // 已发布，只有该状态的文章对外可见
+	ArticleStatusArchived  = 4 // 已归档
+)
+
 const (
 	_ = iota * 5
 	WeightLow
@@ -33,4 +65,3 @@ const (
 	WeightFour
 	WeightFive
 )
-
@@ -0,0 +1,18 @@
+package model
+
+// ArticleRevision 文章修改前的历史版本，每次修改文章内容都会保存一份被覆盖的旧内容
+type ArticleRevision struct {
+	Id         int64  `json:"id"`
+	ArticleId  int64  `json:"article_id"`
+	Title      string `json:"title"`
+	PreviewCtx string `json:"preview_ctx"`
+	Content    string `json:"content"`
+	CreatedAt  int64  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (*ArticleRevision) TableName() string {
+	return "article_revision"
+}
+
+// ArticleRevisionCurrent 对比版本时使用该id表示文章的当前内容
+const ArticleRevisionCurrent = 0
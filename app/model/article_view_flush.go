@@ -0,0 +1,16 @@
+package model
+
+// ArticleViewFlush 已写入数据库的阅读量批次，与阅读量在同一事务中写入
+// 写入成功但redis中的批次删除失败时，重试不会重复累加阅读量
+// CREATE TABLE article_view_flush (id varchar(32) NOT NULL PRIMARY KEY, created_at bigint NOT NULL, KEY idx_created_at (created_at));
+type ArticleViewFlush struct {
+	Id        string `json:"id"`
+	CreatedAt int64  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (*ArticleViewFlush) TableName() string {
+	return "article_view_flush"
+}
+
+// ArticleViewFlushKeep 批次记录的保留时间，单位秒
+const ArticleViewFlushKeep = 86400
@@ -0,0 +1,53 @@
+package model
+
+// AuditLog 管理员写操作的审计日志
+// CREATE TABLE audit_log (id bigint PRIMARY KEY AUTO_INCREMENT, actor_id bigint NOT NULL, action varchar(64) NOT NULL, resource varchar(32) NOT NULL, method varchar(8) NOT NULL, path varchar(255) NOT NULL, target_id bigint NOT NULL, before_data mediumtext NOT NULL, after_data mediumtext NOT NULL, ip varchar(64) NOT NULL, created_at bigint NOT NULL, KEY idx_actor_id (actor_id), KEY idx_target (resource, target_id), KEY idx_created_at (created_at));
+type AuditLog struct {
+	Id        int64  `json:"id"`
+	ActorId   int64  `json:"actor_id"` // 操作的管理员id
+	Action    string `json:"action"`   // 操作需要的权限名，如 PermArticleDelete
+	Resource  string `json:"resource"` // 操作的资源，AuditResources中的键，无法识别时为空
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	TargetId  int64  `json:"target_id"`                        // 操作对象的id，无法确定时为0
+	Before    string `json:"before" gorm:"column:before_data"` // 操作前的记录，json，没有时为空
+	After     string `json:"after" gorm:"column:after_data"`   // 操作后的记录，json，删除后为空
+	Ip        string `json:"ip"`
+	CreatedAt int64  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+func (*AuditLog) TableName() string {
+	return "audit_log"
+}
+
+// AuditResources 路由的第一段路径 -> 操作前后保存快照的数据表
+var AuditResources = map[string]string{
+	"category":   "category",
+	"article":    "article",
+	"comment":    "comment",
+	"tag":        "tag",
+	"upload":     "upload",
+	"user":       "user",
+	"user_roles": "user",
+	"role":       "role",
+}
+
+// AuditHiddenColumns 快照中不保存的字段
+var AuditHiddenColumns = []string{"password", "salt"}
+
+// AuditFilter 查询审计日志的筛选条件，为零值的条件不生效
+type AuditFilter struct {
+	ActorId     int64
+	Action      string
+	Resource    string
+	TargetId    int64
+	CreatedFrom int64 // 操作时间范围，时间戳
+	CreatedTo   int64
+}
+
+// AuditConfig 审计日志写入配置
+type AuditConfig struct {
+	BufferSize    int // 等待写入的日志队列长度
+	BatchSize     int // 每批写入的最多条数
+	FlushInterval int // 写入间隔，单位: 秒
+}
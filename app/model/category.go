@@ -3,9 +3,12 @@ package model
 type Category struct {
 	Id           int64  `json:"id"`
 	Name         string `json:"name"`
-	ArticleCount int    `json:"article_count"`
+	ArticleCount int    `json:"article_count"` // 已发布的文章数
 }
 
+// 文章数只统计已发布的文章，已有数据迁移:
+// UPDATE category c SET article_count = (SELECT COUNT(*) FROM article a WHERE a.category_id = c.id AND a.deleted = 0 AND a.status = 3);
+
 func (*Category) TableName() string {
 	return "category"
 }
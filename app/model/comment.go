@@ -0,0 +1,29 @@
+package model
+
+type Comment struct {
+	Id        int64     `json:"id"`
+	ArticleId int64     `json:"article_id"`
+	UserId    int64     `json:"user_id"`
+	UserName  string    `json:"user_name" gorm:"-"`
+	RootId    int64     `json:"root_id"`   // 所属顶级评论id，顶级评论为0
+	ParentId  int64     `json:"parent_id"` // 回复的评论id，顶级评论为0
+	Content   string    `json:"content"`
+	Status    int8      `json:"status"`
+	Deleted   int8      `json:"deleted"`
+	CreatedAt int64     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt int64     `json:"updated_at" gorm:"autoUpdateTime"`
+	Replies   []Comment `json:"replies" gorm:"-"`
+}
+
+func (*Comment) TableName() string {
+	return "comment"
+}
+
+const (
+	CommentDeletedNo  = 0
+	CommentDeletedYes = 1
+
+	// 评论状态
+	CommentStatusNormal = 1 // 正常显示
+	CommentStatusHidden = 2 // 被管理员隐藏
+)
@@ -1,6 +1,26 @@
 package model
 
-const EmailRegisterTplName = "register_code"
+const (
+	EmailRegisterTplName      = "register_code"  // 注册验证码模板，占位符: ${{code}}
+	EmailPasswordResetTplName = "password_reset" // 重置密码模板，占位符: ${{link}} ${{expire}}
+	EmailAccountLockedTplName = "account_locked" // 账号锁定通知模板，占位符: ${{name}} ${{ip}} ${{until}}
+	EmailChangeCodeTplName    = "change_email"   // 修改邮箱验证码模板，占位符: ${{code}}
+	EmailChangedTplName       = "email_changed"  // 邮箱已修改通知模板，发送到旧邮箱，占位符: ${{name}} ${{email}}
+)
+
+// 验证码用途，不同用途的验证码互不通用
+const (
+	EmailCodeSceneRegister    = "register"
+	EmailCodeSceneChangeEmail = "change_email" // 实际用途为 change_email:用户id，验证码只能由申请修改的用户使用
+)
+
+// 重置密码模板:
+// INSERT INTO email_tpl (name, content) VALUES ('password_reset', '<p>点击下面的链接重置密码，链接${{expire}}分钟内有效且只能使用一次：</p><p><a href="${{link}}">${{link}}</a></p><p>如果不是你本人操作，请忽略这封邮件。</p>');
+// 账号锁定通知模板:
+// INSERT INTO email_tpl (name, content) VALUES ('account_locked', '<p>${{name}}，你的账号因多次登录失败已被临时锁定，将于${{until}}自动解锁。</p><p>最近一次失败登录的ip: ${{ip}}</p><p>如果不是你本人操作，建议登录后修改密码。</p>');
+// 修改邮箱模板:
+// INSERT INTO email_tpl (name, content) VALUES ('change_email', '<p>你正在修改账号绑定的邮箱，验证码: ${{code}}</p><p>如果不是你本人操作，请忽略这封邮件。</p>');
+// INSERT INTO email_tpl (name, content) VALUES ('email_changed', '<p>${{name}}，你的账号绑定邮箱已修改为${{email}}，此邮箱将不能再用于登录。</p><p>如果不是你本人操作，请立即联系管理员。</p>');
 
 type EmailTpl struct {
 	ID      int64  `json:"id"`
@@ -15,7 +35,22 @@ type EmailConfig struct {
 	Port string `mapstructure:"port"`
 }
 
+// EmailCodeConfig 邮箱验证码发送和校验限制
+type EmailCodeConfig struct {
+	Expire      int // 验证码有效期，单位: 秒
+	Cooldown    int // 同一邮箱重新发送的间隔，单位: 秒
+	MaxAttempts int // 每个验证码最多校验次数，超过后验证码失效
+	Window      int // 发送次数的统计窗口，单位: 秒
+	EmailLimit  int // 窗口内同一邮箱最多发送次数
+	IpLimit     int // 窗口内同一ip最多发送次数
+}
+
+// PasswordResetConfig 重置密码配置
+type PasswordResetConfig struct {
+	Expire int    // 重置链接有效期，单位: 分钟
+	Link   string // 前端重置密码页面地址，token作为查询参数附加
+}
+
 func (*EmailTpl) TableName() string {
 	return "email_tpl"
 }
-
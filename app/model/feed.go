@@ -0,0 +1,14 @@
+package model
+
+// 订阅源名
+const (
+	FeedNameRSS  = "rss"
+	FeedNameAtom = "atom"
+)
+
+type FeedConfig struct {
+	Title       string `mapstructure:"title"`
+	Link        string `mapstructure:"-"` // 站点地址，取自site.link
+	Description string `mapstructure:"description"`
+	Size        int    `mapstructure:"size"` // 订阅源包含的最新文章数
+}
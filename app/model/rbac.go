@@ -0,0 +1,132 @@
+package model
+
+// Role 角色
+// CREATE TABLE role (id bigint PRIMARY KEY AUTO_INCREMENT, name varchar(32) NOT NULL, description varchar(255) NOT NULL, created_at bigint NOT NULL, updated_at bigint NOT NULL, UNIQUE KEY uidx_name (name));
+type Role struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   int64  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Permissions []string `json:"permissions" gorm:"-"` // 权限名
+}
+
+func (*Role) TableName() string {
+	return "role"
+}
+
+// Permission 权限，启动时自动写入代码中定义的所有权限
+// CREATE TABLE permission (id bigint PRIMARY KEY AUTO_INCREMENT, name varchar(64) NOT NULL, description varchar(255) NOT NULL, UNIQUE KEY uidx_name (name));
+type Permission struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (*Permission) TableName() string {
+	return "permission"
+}
+
+// RolePermission 角色拥有的权限
+// CREATE TABLE role_permission (role_id bigint NOT NULL, permission_id bigint NOT NULL, PRIMARY KEY (role_id, permission_id));
+type RolePermission struct {
+	RoleId       int64 `json:"role_id"`
+	PermissionId int64 `json:"permission_id"`
+}
+
+func (*RolePermission) TableName() string {
+	return "role_permission"
+}
+
+// UserRole 用户被分配的角色，原管理员账号的迁移见model.User
+// CREATE TABLE user_role (user_id bigint NOT NULL, role_id bigint NOT NULL, PRIMARY KEY (user_id, role_id), KEY idx_role_id (role_id));
+type UserRole struct {
+	UserId int64 `json:"user_id"`
+	RoleId int64 `json:"role_id"`
+}
+
+func (*UserRole) TableName() string {
+	return "user_role"
+}
+
+// RoleSuperAdmin 内置的超级管理员角色，隐含拥有所有权限，不能删除
+const RoleSuperAdmin = "super_admin"
+
+// 权限名，格式: 资源:操作
+const (
+	PermCategoryCreate = "category:create"
+	PermCategoryUpdate = "category:update"
+	PermCategoryDelete = "category:delete"
+
+	PermArticleCreate          = "article:create"
+	PermArticleUpdate          = "article:update"
+	PermArticleDelete          = "article:delete"
+	PermArticleListAll         = "article:list_all"
+	PermArticleRevisionList    = "article:revision_list"
+	PermArticleRevisionRestore = "article:revision_restore"
+
+	PermCommentListAll = "comment:list_all"
+	PermCommentUpdate  = "comment:update"
+	PermCommentDelete  = "comment:delete"
+
+	PermTagCreate = "tag:create"
+	PermTagUpdate = "tag:update"
+	PermTagDelete = "tag:delete"
+
+	PermStatList = "stat:list"
+
+	PermUploadCreate = "upload:create"
+
+	PermSessionRevokeUser = "session:revoke_user"
+
+	PermUserList          = "user:list"
+	PermUserBan           = "user:ban"
+	PermUserResetPassword = "user:reset_password"
+	PermUserUnlock        = "user:unlock"
+	PermUserViewPrivate   = "user:view_private"
+
+	PermRoleList   = "role:list"
+	PermRoleManage = "role:manage"
+
+	PermAuditList = "audit:list"
+)
+
+// Permissions 代码中定义的所有权限
+var Permissions = []Permission{
+	{Name: PermCategoryCreate, Description: "创建分类"},
+	{Name: PermCategoryUpdate, Description: "修改分类"},
+	{Name: PermCategoryDelete, Description: "删除分类"},
+
+	{Name: PermArticleCreate, Description: "创建文章"},
+	{Name: PermArticleUpdate, Description: "修改文章"},
+	{Name: PermArticleDelete, Description: "删除文章"},
+	{Name: PermArticleListAll, Description: "查看所有文章，包括草稿和定时发布的文章"},
+	{Name: PermArticleRevisionList, Description: "查看文章历史版本"},
+	{Name: PermArticleRevisionRestore, Description: "恢复文章历史版本"},
+
+	{Name: PermCommentListAll, Description: "查看所有评论"},
+	{Name: PermCommentUpdate, Description: "修改评论"},
+	{Name: PermCommentDelete, Description: "删除评论"},
+
+	{Name: PermTagCreate, Description: "创建标签"},
+	{Name: PermTagUpdate, Description: "修改标签"},
+	{Name: PermTagDelete, Description: "删除标签"},
+
+	{Name: PermStatList, Description: "查看访问统计"},
+
+	{Name: PermUploadCreate, Description: "上传文件"},
+
+	{Name: PermSessionRevokeUser, Description: "注销用户所有的会话"},
+
+	{Name: PermUserList, Description: "查看用户列表和用户详情"},
+	{Name: PermUserBan, Description: "封禁和解封用户"},
+	{Name: PermUserResetPassword, Description: "强制用户重置密码"},
+	{Name: PermUserUnlock, Description: "解除因登录失败被锁定的账号"},
+	{Name: PermUserViewPrivate, Description: "查看用户的邮箱、登录时间和被隐藏的资料"},
+
+	{Name: PermRoleList, Description: "查看角色和权限"},
+	{Name: PermRoleManage, Description: "管理角色和角色分配"},
+
+	{Name: PermAuditList, Description: "查看和导出审计日志"},
+}
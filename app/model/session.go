@@ -0,0 +1,7 @@
+package model
+
+// Device 登录设备信息
+type Device struct {
+	Ip        string
+	UserAgent string
+}
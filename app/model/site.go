@@ -0,0 +1,17 @@
+package model
+
+// sitemap缓存名
+const (
+	SitemapNameIndex = "index"   // /sitemap.xml，地址数不超过上限时为sitemap，否则为sitemap索引
+	SitemapNamePage  = "page#%d" // sitemap索引中的第n个sitemap
+)
+
+type RobotsConfig struct {
+	Rules []RobotsRule `mapstructure:"rules"`
+}
+
+type RobotsRule struct {
+	UserAgent string   `mapstructure:"userAgent"`
+	Allow     []string `mapstructure:"allow"`
+	Disallow  []string `mapstructure:"disallow"`
+}
@@ -0,0 +1,26 @@
+package model
+
+// 统计对象
+const (
+	StatTargetArticle  = "article"
+	StatTargetCategory = "category"
+)
+
+// Visitor 访问者，登录用户按用户id区分，游客按ip和User-Agent区分
+type Visitor struct {
+	UserId    int64
+	Ip        string
+	UserAgent string
+}
+
+// DailyStat 每日访问量(PV)与独立访客数(UV)
+type DailyStat struct {
+	Date string // 日期，格式: 2006-01-02
+	PV   int64
+	UV   int64
+}
+
+type ViewStatConfig struct {
+	Window        int64 `mapstructure:"window"`        // 同一访问者重复访问不计阅读量的窗口期，单位: 秒
+	RetentionDays int64 `mapstructure:"retentionDays"` // 每日统计数据保留天数
+}
@@ -0,0 +1,24 @@
+package model
+
+type Tag struct {
+	Id           int64  `json:"id"`
+	Name         string `json:"name"`
+	ArticleCount int    `json:"article_count"` // 已发布的文章数
+}
+
+// 文章数只统计已发布的文章，已有数据迁移:
+// UPDATE tag t SET article_count = (SELECT COUNT(*) FROM article_tag at JOIN article a ON a.id = at.article_id WHERE at.tag_id = t.id AND a.deleted = 0 AND a.status = 3);
+
+func (*Tag) TableName() string {
+	return "tag"
+}
+
+// ArticleTag 文章与标签的多对多关联
+type ArticleTag struct {
+	ArticleId int64 `json:"article_id" gorm:"primaryKey"`
+	TagId     int64 `json:"tag_id" gorm:"primaryKey"`
+}
+
+func (*ArticleTag) TableName() string {
+	return "article_tag"
+}
@@ -0,0 +1,72 @@
+package model
+
+// Upload 上传的文件，相同内容的文件只保存一份
+// CREATE TABLE upload (id bigint PRIMARY KEY AUTO_INCREMENT, user_id bigint NOT NULL, hash char(64) NOT NULL, name varchar(255) NOT NULL, mime_type varchar(128) NOT NULL, size bigint NOT NULL, path varchar(255) NOT NULL, thumb_path varchar(255) NOT NULL, created_at bigint NOT NULL, UNIQUE KEY uidx_hash (hash), KEY idx_created_at (created_at));
+// ALTER TABLE upload ADD last_used_at bigint NOT NULL DEFAULT 0, ADD KEY idx_last_used_at (last_used_at); UPDATE upload SET last_used_at = created_at;
+type Upload struct {
+	Id         int64  `json:"id"`
+	UserId     int64  `json:"user_id"`
+	Hash       string `json:"hash"` // 内容的sha256
+	Name       string `json:"name"` // 原文件名
+	MimeType   string `json:"mime_type"`
+	Size       int64  `json:"size"`
+	Path       string `json:"path"`       // 存储中的路径
+	ThumbPath  string `json:"thumb_path"` // 缩略图在存储中的路径，非图片或无法解码时为空
+	CreatedAt  int64  `json:"created_at" gorm:"autoCreateTime"`
+	LastUsedAt int64  `json:"-"` // 上传或重复上传命中的时间，清理任务根据该时间计算保留期
+	Url        string `json:"url" gorm:"-"`
+	ThumbUrl   string `json:"thumb_url" gorm:"-"`
+}
+
+func (*Upload) TableName() string {
+	return "upload"
+}
+
+// UploadRef 文章及其历史版本引用的上传文件，随文章的创建和修改写入，删除文章时删除
+// 清理任务按存储路径查询，不需要扫描文章内容
+// CREATE TABLE upload_ref (path varchar(255) NOT NULL, article_id bigint NOT NULL, PRIMARY KEY (path, article_id), KEY idx_article_id (article_id));
+// 已有数据迁移:
+// INSERT IGNORE INTO upload_ref (path, article_id) SELECT u.path, a.id FROM upload u JOIN article a ON a.deleted = 0 AND (a.picture LIKE CONCAT('%', u.path, '%') OR a.content LIKE CONCAT('%', u.path, '%'));
+// INSERT IGNORE INTO upload_ref (path, article_id) SELECT u.thumb_path, a.id FROM upload u JOIN article a ON a.deleted = 0 AND u.thumb_path <> '' AND (a.picture LIKE CONCAT('%', u.thumb_path, '%') OR a.content LIKE CONCAT('%', u.thumb_path, '%'));
+// INSERT IGNORE INTO upload_ref (path, article_id) SELECT u.path, r.article_id FROM upload u JOIN article_revision r ON r.content LIKE CONCAT('%', u.path, '%') JOIN article a ON a.id = r.article_id AND a.deleted = 0;
+// INSERT IGNORE INTO upload_ref (path, article_id) SELECT u.thumb_path, r.article_id FROM upload u JOIN article_revision r ON u.thumb_path <> '' AND r.content LIKE CONCAT('%', u.thumb_path, '%') JOIN article a ON a.id = r.article_id AND a.deleted = 0;
+type UploadRef struct {
+	Path      string `json:"path" gorm:"primaryKey"`
+	ArticleId int64  `json:"article_id" gorm:"primaryKey"`
+}
+
+func (*UploadRef) TableName() string {
+	return "upload_ref"
+}
+
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+type UploadConfig struct {
+	MaxSize       int64              `mapstructure:"maxSize"`     // 文件大小上限，单位: MB
+	ThumbWidth    int                `mapstructure:"thumbWidth"`  // 缩略图最大宽度，单位: 像素
+	ThumbPixels   int64              `mapstructure:"thumbPixels"` // 生成缩略图的原图宽×高上限，超过时不生成缩略图
+	AllowedTypes  []string           `mapstructure:"allowedTypes"`
+	OrphanGrace   int64              `mapstructure:"orphanGrace"`   // 上传超过该时间仍未被文章引用的文件会被清理，单位: 秒
+	CleanInterval int64              `mapstructure:"cleanInterval"` // 清理任务的执行间隔，单位: 秒
+	Storage       string             `mapstructure:"storage"`       // 存储后端: local/s3
+	Local         LocalStorageConfig `mapstructure:"local"`
+	S3            S3StorageConfig    `mapstructure:"s3"`
+}
+
+type LocalStorageConfig struct {
+	Dir     string `mapstructure:"dir"`     // 文件保存目录
+	BaseUrl string `mapstructure:"baseUrl"` // 访问地址前缀
+}
+
+type S3StorageConfig struct {
+	Endpoint  string `mapstructure:"endpoint"` // 如 https://s3.amazonaws.com，或本地MinIO http://127.0.0.1:9000
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"accessKey"`
+	SecretKey string `mapstructure:"secretKey"`
+	PathStyle bool   `mapstructure:"pathStyle"` // 使用路径形式访问bucket，MinIO需要开启
+	BaseUrl   string `mapstructure:"baseUrl"`   // 访问地址前缀，为空时使用endpoint拼接
+}
@@ -1,17 +1,34 @@
 package model
 
+import "time"
+
+// ALTER TABLE user ADD avatar varchar(255) NOT NULL DEFAULT '' AFTER github, ADD deleted_at bigint NOT NULL DEFAULT 0;
+// ALTER TABLE user ADD privacy int NOT NULL DEFAULT 0;
+// ALTER TABLE comment ADD KEY idx_user_id (user_id);
+// ALTER TABLE user ADD banned_at bigint NOT NULL DEFAULT 0, ADD ban_expire_at bigint NOT NULL DEFAULT 0, ADD ban_reason varchar(255) NOT NULL DEFAULT '', ADD KEY idx_created_at (created_at), ADD KEY idx_login_at (login_at);
+// ALTER TABLE user MODIFY password varchar(255) NOT NULL;
 type User struct {
 	Id        int64  `json:"id"`
 	Name      string `json:"name"`
-	Password  string `json:"password"`
-	Salt      string `json:"salt"`
+	Password  string `json:"password"` // 编码了算法和参数的密码哈希，见password包；旧的sha256密码为64位十六进制
+	Salt      string `json:"salt"`     // 旧的sha256密码使用的盐，新密码为空
 	Gender    int8   `json:"gender"`
 	Introduce string `json:"introduce"`
 	Github    string `json:"github"`
+	Avatar    string `json:"avatar"`
 	Email     string `json:"email"`
 	CreatedAt int64  `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt int64  `json:"updated_at" gorm:"autoUpdateTime"`
 	LoginAt   int64  `json:"login_at" gorm:"autoCreateTime"`
+	DeletedAt int64  `json:"deleted_at"` // 注销时间，注销后个人信息被匿名化，0为未注销
+	Privacy   int    `json:"privacy"`    // 隐私设置，UserPrivacy*的组合，对其他用户隐藏对应的信息
+
+	BannedAt    int64  `json:"banned_at"`     // 封禁时间，0为未封禁
+	BanExpireAt int64  `json:"ban_expire_at"` // 封禁到期时间，0为永久封禁
+	BanReason   string `json:"ban_reason"`    // 封禁原因
+
+	ArticleCount int64 `json:"article_count" gorm:"-"` // 已发布的文章数
+	CommentCount int64 `json:"comment_count" gorm:"-"` // 正常显示的评论数
 }
 
 func (*User) TableName() string {
@@ -32,8 +49,70 @@ const (
 	LoginTypeByName  = 1 // 使用用户名登录
 	LoginTypeByEmail = 2 // 使用邮箱登录
 
-	// 用户身份
-	UserRoleNormal = 1  // 普通成员
-	UserRoleAdmin  = 10 // 管理员
+	// 注销后的匿名信息，%d为用户id，保证唯一索引不冲突
+	UserDeletedNameFormat  = "已注销%d"
+	UserDeletedEmailFormat = "deleted%d@deleted.invalid"
+
+	// 管理员查询用户列表时的账号状态
+	UserStatusNormal  = 1 // 正常
+	UserStatusBanned  = 2 // 封禁中
+	UserStatusDeleted = 3 // 已注销
 )
 
+// 隐私设置，邮箱和登录时间始终只对本人和管理员可见
+const (
+	UserPrivacyHideGender    = 1 << 0 // 隐藏性别
+	UserPrivacyHideIntroduce = 1 << 1 // 隐藏个人介绍
+	UserPrivacyHideGithub    = 1 << 2 // 隐藏github地址
+	UserPrivacyHideCounts    = 1 << 3 // 隐藏文章数和评论数
+)
+
+// Hides 是否对其他用户隐藏信息
+// @param privacy UserPrivacy*
+func (u *User) Hides(privacy int) bool {
+	return u.Privacy&privacy != 0
+}
+
+// IsDeleted 用户是否已注销
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt > 0
+}
+
+// IsBanned 用户当前是否处于封禁中，封禁到期后自动解除
+func (u *User) IsBanned() bool {
+	return u.BannedAt > 0 && (u.BanExpireAt == 0 || u.BanExpireAt > time.Now().Unix())
+}
+
+// UserFilter 管理员查询用户列表的筛选条件，为零值的条件不生效
+type UserFilter struct {
+	Keyword     string // 用户名或邮箱，模糊匹配
+	Status      int8   // 账号状态，UserStatus*
+	CreatedFrom int64  // 注册时间范围，时间戳
+	CreatedTo   int64
+	LoginFrom   int64 // 最后登录时间范围，时间戳
+	LoginTo     int64
+}
+
+// LoginGuardConfig 登录失败保护配置
+type LoginGuardConfig struct {
+	Window        int // 登录失败次数的统计窗口，单位: 秒
+	MaxFailures   int // 窗口内同一账号失败次数达到后锁定账号
+	IpMaxFailures int // 窗口内同一ip失败次数达到后限制该ip登录
+	LockDuration  int // 锁定时长，单位: 秒
+	BackoffBase   int // 每次失败后需要等待 BackoffBase*2^(失败次数-1) 秒才能再次尝试
+	BackoffMax    int // 最长等待时间，单位: 秒
+}
+
+// 管理员账号合并到user表，管理员的权限由角色决定，admin表废弃，已有数据迁移:
+// 1. 管理员迁移为用户，name与已有用户冲突时需先修改，email为占位地址，迁移后修改为真实邮箱
+// INSERT INTO user (name, password, salt, gender, introduce, github, email, created_at, updated_at, login_at) SELECT name, password, salt, 1, '', '', CONCAT('admin', id, '@admin.invalid'), UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), 0 FROM admin;
+// 2. 原来分配给管理员账号的角色转移到对应的用户，并去掉account_type列
+// UPDATE user_role ur JOIN admin a ON ur.account_type = 10 AND ur.user_id = a.id JOIN user u ON u.name = a.name SET ur.user_id = u.id, ur.account_type = 1;
+// ALTER TABLE user_role DROP PRIMARY KEY, DROP account_type, ADD PRIMARY KEY (user_id, role_id);
+// 3. 原管理员全部分配超级管理员角色
+// INSERT IGNORE INTO role (name, description, created_at, updated_at) VALUES ('super_admin', '超级管理员，拥有所有权限', UNIX_TIMESTAMP(), UNIX_TIMESTAMP());
+// INSERT IGNORE INTO user_role (user_id, role_id) SELECT u.id, r.id FROM admin a JOIN user u ON u.name = a.name JOIN role r ON r.name = 'super_admin';
+// 4. DROP TABLE admin;
+// 新部署时为第一个管理员分配超级管理员角色:
+// INSERT INTO user_role (user_id, role_id) SELECT u.id, r.id FROM user u JOIN role r ON r.name = 'super_admin' WHERE u.name = '管理员用户名';
+
@@ -1,25 +1,42 @@
 package service
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/mittacy/blogBack/apierr"
 	"github.com/mittacy/blogBack/app/api"
 	"github.com/mittacy/blogBack/app/model"
 	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/utils"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"strconv"
+	"time"
 )
 
 type Article struct {
 	articleData  IArticleData
 	categoryData IArticleCategoryData
+	tagData      IArticleTagData
+	revisionData IArticleRevisionData
+	searcher     IArticleSearcher
+	viewTracker  IArticleViewTracker
 	logger       *logger.CustomLogger
 }
 
 // 编写实现api层中的各个service接口的构建方法
 
-func NewArticle(articleData IArticleData, categoryData IArticleCategoryData, logger *logger.CustomLogger) api.IArticleService {
+func NewArticle(articleData IArticleData, categoryData IArticleCategoryData, tagData IArticleTagData, revisionData IArticleRevisionData, searcher IArticleSearcher, viewTracker IArticleViewTracker, logger *logger.CustomLogger) api.IArticleService {
 	return &Article{
-		articleData: articleData,
+		articleData:  articleData,
 		categoryData: categoryData,
-		logger:      logger,
+		tagData:      tagData,
+		revisionData: revisionData,
+		searcher:     searcher,
+		viewTracker:  viewTracker,
+		logger:       logger,
 	}
 }
 
@@ -27,13 +44,41 @@ type IArticleData interface {
 	Insert(article *model.Article) error
 	Delete(id int64) error
 	UpdateById(article *model.Article, updateFields []string) error
+	UpdateInfo(article *model.Article, updateFields []string) error
 	Get(id int64) (*model.Article, error)
+	GetIgnoreStatus(id int64) (*model.Article, error)
+	UpdateStatus(article *model.Article) error
 	GetSum() (int64, error)
 	GetSumByCategory(categoryId int64) (int64, error)
 	List(selectFields []string, page, pageSize int) ([]model.Article, error)
 	ListByCategory(selectFields []string, categoryId int64, page, pageSize int) ([]model.Article, error)
+	ListByTag(selectFields []string, tagId int64, page, pageSize int) ([]model.Article, error)
+	GetSumByTag(tagId int64) (int64, error)
+	ListByStatus(selectFields []string, status int8, page, pageSize int) ([]model.Article, error)
+	GetSumByStatus(status int8) (int64, error)
 	ListByWeight(selectFields []string, count int) ([]model.Article, error)
 	IncrView(id int64) error
+	GetPendingViews(id int64) (int64, error)
+}
+
+type IArticleTagData interface {
+	ListByArticles(articleIds []int64) (map[int64][]model.Tag, error)
+}
+
+type IArticleRevisionData interface {
+	Get(articleId, id int64) (*model.ArticleRevision, error)
+	List(selectFields []string, articleId int64, page, pageSize int) ([]model.ArticleRevision, error)
+	GetSum(articleId int64) (int64, error)
+}
+
+// IArticleSearcher 文章全文搜索，生产环境使用Mysql FULLTEXT，测试可使用进程内倒排索引
+type IArticleSearcher interface {
+	// Search 按相关度从高到低分页搜索未删除的文章
+	Search(selectFields []string, q string, page, pageSize int) ([]model.Article, int64, error)
+	// Index 创建或更新文章的索引
+	Index(article model.Article) error
+	// Remove 删除文章的索引
+	Remove(id int64) error
 }
 
 type IArticleCategoryData interface {
@@ -42,6 +87,19 @@ type IArticleCategoryData interface {
 }
 
 func (ctl *Article) Create(article model.Article) (int64, error) {
+	// 未指定状态时直接发布
+	if article.Status == 0 {
+		article.Status = model.ArticleStatusPublished
+	}
+
+	if err := ctl.checkPublishAt(&article); err != nil {
+		return 0, err
+	}
+
+	if err := renderContent(&article); err != nil {
+		return 0, err
+	}
+
 	if err := ctl.articleData.Insert(&article); err != nil {
 		return 0, err
 	}
@@ -49,6 +107,8 @@ func (ctl *Article) Create(article model.Article) (int64, error) {
 	// 让全部分类缓存失效
 	ctl.categoryData.ExpireCategoryData()
 
+	ctl.syncSearchIndex(article)
+
 	return article.Id, nil
 }
 
@@ -60,14 +120,150 @@ func (ctl *Article) Delete(id int64) error {
 	// 让全部分类缓存失效
 	ctl.categoryData.ExpireCategoryData()
 
+	if err := ctl.searcher.Remove(id); err != nil {
+		ctl.logger.Sugar().Errorf("remove article index err: %s", err)
+	}
+
 	return nil
 }
 
 func (ctl *Article) UpdateInfo(article model.Article) error {
-	fields := []string{"category_id", "title", "preview_ctx", "content"}
-	if err := ctl.articleData.UpdateById(&article, fields); err != nil {
+	if err := renderContent(&article); err != nil {
+		return err
+	}
+
+	// 未传标签(TagIds为nil)时不修改文章的标签
+	fields := []string{"category_id", "title", "preview_ctx", "content", "content_html", "toc"}
+	if err := ctl.articleData.UpdateInfo(&article, fields); err != nil {
+		return err
+	}
+
+	// 修改分类时分类文章数发生变化，让全部分类缓存失效
+	ctl.categoryData.ExpireCategoryData()
+
+	// 重新查询完整的文章，更新搜索索引
+	if article, err := ctl.articleData.GetIgnoreStatus(article.Id); err != nil {
+		ctl.logger.Sugar().Errorf("get article err: %s", err)
+	} else {
+		ctl.syncSearchIndex(*article)
+	}
+
+	return nil
+}
+
+func (ctl *Article) ListRevisions(articleId int64, page, pageSize int) ([]model.ArticleRevision, int64, error) {
+	if _, err := ctl.articleData.GetIgnoreStatus(articleId); err != nil {
+		return nil, 0, err
+	}
+
+	fields := []string{"id", "article_id", "title", "created_at"}
+	revisions, err := ctl.revisionData.List(fields, articleId, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalSize, err := ctl.revisionData.GetSum(articleId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return revisions, totalSize, nil
+}
+
+// DiffRevisions 对比文章的两个版本，版本id为model.ArticleRevisionCurrent时表示文章的当前内容
+// @param articleId 文章id
+// @param fromId 旧版本id
+// @param toId 新版本id
+// @return map[string]string 字段名 => 统一格式的差异，字段没有变化时为空字符串
+// @return error 不同的行数过多时返回apierr.ErrArticleRevisionDiffTooLarge
+func (ctl *Article) DiffRevisions(articleId, fromId, toId int64) (map[string]string, error) {
+	from, err := ctl.getRevision(articleId, fromId)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := ctl.getRevision(articleId, toId)
+	if err != nil {
+		return nil, err
+	}
+
+	fromName, toName := revisionName(fromId), revisionName(toId)
+	fields := map[string][2]string{
+		"title":       {from.Title, to.Title},
+		"preview_ctx": {from.PreviewCtx, to.PreviewCtx},
+		"content":     {from.Content, to.Content},
+	}
+
+	diff := make(map[string]string, len(fields))
+	for name, v := range fields {
+		d, err := utils.UnifiedDiff(fromName, toName, v[0], v[1])
+		if err != nil {
+			if errors.Is(err, utils.ErrDiffTooLarge) {
+				return nil, apierr.ErrArticleRevisionDiffTooLarge
+			}
+			return nil, err
+		}
+		diff[name] = d
+	}
+
+	return diff, nil
+}
+
+// RestoreRevision 使用历史版本覆盖文章的当前内容，当前内容会作为新的历史版本保存
+// @param articleId 文章id
+// @param revisionId 历史版本id
+// @return error
+func (ctl *Article) RestoreRevision(articleId, revisionId int64) error {
+	revision, err := ctl.revisionData.Get(articleId, revisionId)
+	if err != nil {
+		return err
+	}
+
+	article := model.Article{
+		Id:         articleId,
+		Title:      revision.Title,
+		PreviewCtx: revision.PreviewCtx,
+		Content:    revision.Content,
+	}
+	if err := renderContent(&article); err != nil {
+		return err
+	}
+
+	fields := []string{"title", "preview_ctx", "content", "content_html", "toc"}
+	if err := ctl.articleData.UpdateInfo(&article, fields); err != nil {
+		return err
+	}
+
+	if article, err := ctl.articleData.GetIgnoreStatus(articleId); err != nil {
+		ctl.logger.Sugar().Errorf("get article err: %s", err)
+	} else {
+		ctl.syncSearchIndex(*article)
+	}
+
+	return nil
+}
+
+func (ctl *Article) UpdateStatus(id int64, status int8, publishAt int64) error {
+	article, err := ctl.articleData.GetIgnoreStatus(id)
+	if err != nil {
+		return err
+	}
+
+	article.Status = status
+	article.PublishAt = publishAt
+	if err := ctl.checkPublishAt(article); err != nil {
+		return err
+	}
+
+	if err := ctl.articleData.UpdateStatus(article); err != nil {
 		return err
 	}
+
+	// 进入或离开已发布状态时分类文章数发生变化，让全部分类缓存失效
+	ctl.categoryData.ExpireCategoryData()
+
+	ctl.syncSearchIndex(*article)
+
 	return nil
 }
 
@@ -82,7 +278,7 @@ func (ctl *Article) UpdateWeight(id, weight int64) error {
 	return nil
 }
 
-func (ctl *Article) Get(id int64) (*model.Article, error) {
+func (ctl *Article) Get(id int64, visitor model.Visitor) (*model.Article, error) {
 	/*
 	 * 1. 获取文章
 	 * 2. 查询文章所属分类的分类名字
@@ -93,6 +289,13 @@ func (ctl *Article) Get(id int64) (*model.Article, error) {
 		return nil, err
 	}
 
+	// 迁移前的文章没有html，实时渲染
+	if article.ContentHtml == "" && article.Content != "" {
+		if err := renderContent(article); err != nil {
+			return nil, err
+		}
+	}
+
 	// 填充文章的分类名
 	categories, err := ctl.categoryData.GetCategoriesMap()
 	if err != nil {
@@ -100,14 +303,88 @@ func (ctl *Article) Get(id int64) (*model.Article, error) {
 	}
 	article.CategoryName = categories[article.CategoryId].Name
 
-	// 文章阅读量+1
-	if err := ctl.articleData.IncrView(id); err != nil {
-		zap.S().Errorf("article incr view, err: %s", err)
+	// 填充文章的标签
+	tags, err := ctl.tagData.ListByArticles([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+	article.Tags = tags[id]
+	if article.Tags == nil {
+		article.Tags = []model.Tag{}
+	}
+
+	// 文章阅读量+1，过滤爬虫，窗口期内同一访问者的重复访问不计入
+	if !utils.IsBot(visitor.UserAgent) {
+		counted, err := ctl.viewTracker.RecordView(id, article.CategoryId, visitorKey(visitor), time.Now())
+		if err != nil {
+			zap.S().Errorf("article record view, err: %s", err)
+		}
+
+		if counted {
+			if err := ctl.articleData.IncrView(id); err != nil {
+				zap.S().Errorf("article incr view, err: %s", err)
+			}
+		}
+	}
+
+	// 加上还未写入数据库的阅读量
+	pendingViews, err := ctl.articleData.GetPendingViews(id)
+	if err != nil {
+		zap.S().Errorf("article pending views, err: %s", err)
+	}
+	article.Views += pendingViews
+
+	return article, nil
+}
+
+func (ctl *Article) AdminGet(id int64) (*model.Article, error) {
+	article, err := ctl.articleData.GetIgnoreStatus(id)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := ctl.categoryData.GetCategoriesMap()
+	if err != nil {
+		return nil, err
+	}
+	article.CategoryName = categories[article.CategoryId].Name
+
+	tags, err := ctl.tagData.ListByArticles([]int64{id})
+	if err != nil {
+		return nil, err
+	}
+	article.Tags = tags[id]
+	if article.Tags == nil {
+		article.Tags = []model.Tag{}
 	}
 
 	return article, nil
 }
 
+func (ctl *Article) AdminList(status int8, page, pageSize int) ([]model.Article, int64, error) {
+	fields := []string{"id", "category_id", "title", "views", "status", "publish_at", "created_at", "updated_at"}
+
+	articles, err := ctl.articleData.ListByStatus(fields, status, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := ctl.FillArticlesCategoryName(articles); err != nil {
+		return nil, 0, err
+	}
+
+	if err := ctl.FillArticlesTags(articles); err != nil {
+		return nil, 0, err
+	}
+
+	totalSize, err := ctl.articleData.GetSumByStatus(status)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return articles, totalSize, nil
+}
+
 func (ctl *Article) List(page, pageSize int) ([]model.Article, int64, error) {
 	/*
 	 * 1. 获取文章列表
@@ -125,6 +402,10 @@ func (ctl *Article) List(page, pageSize int) ([]model.Article, int64, error) {
 		return nil, 0, err
 	}
 
+	if err := ctl.FillArticlesTags(articles); err != nil {
+		return nil, 0, err
+	}
+
 	totalSize, err := ctl.articleData.GetSum()
 	if err != nil {
 		return nil, 0, err
@@ -150,6 +431,10 @@ func (ctl *Article) ListByCategory(categoryId int64, page, pageSize int) ([]mode
 		return nil, 0, err
 	}
 
+	if err := ctl.FillArticlesTags(articles); err != nil {
+		return nil, 0, err
+	}
+
 	totalSize, err := ctl.articleData.GetSumByCategory(categoryId)
 	if err != nil {
 		return nil, 0, err
@@ -158,6 +443,35 @@ func (ctl *Article) ListByCategory(categoryId int64, page, pageSize int) ([]mode
 	return articles, totalSize, nil
 }
 
+func (ctl *Article) ListByTag(tagId int64, page, pageSize int) ([]model.Article, int64, error) {
+	/*
+	 * 1. 获取文章列表
+	 * 2. 填充文章的分类和标签信息
+	 * 3. 查询文章总记录量
+	 */
+	fields := []string{"id", "category_id", "title", "views", "created_at", "updated_at"}
+
+	articles, err := ctl.articleData.ListByTag(fields, tagId, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := ctl.FillArticlesCategoryName(articles); err != nil {
+		return nil, 0, err
+	}
+
+	if err := ctl.FillArticlesTags(articles); err != nil {
+		return nil, 0, err
+	}
+
+	totalSize, err := ctl.articleData.GetSumByTag(tagId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return articles, totalSize, nil
+}
+
 func (ctl *Article) ListHome() ([]model.Article, error) {
 	/*
 	 * 1. 获取主页文章列表
@@ -178,6 +492,40 @@ func (ctl *Article) ListHome() ([]model.Article, error) {
 	return articles, nil
 }
 
+func (ctl *Article) Search(q string, page, pageSize int) ([]model.Article, int64, error) {
+	/*
+	 * 1. 按相关度搜索文章
+	 * 2. 生成标题和正文的高亮片段
+	 * 3. 填充文章的分类信息
+	 */
+	const snippetLen = 120 // 高亮片段长度，单位: 字符
+	fields := []string{"id", "category_id", "title", "views", "preview_ctx", "content", "created_at", "updated_at"}
+
+	articles, totalSize, err := ctl.searcher.Search(fields, q, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	keywords := utils.Keywords(q)
+	for i := 0; i < len(articles); i++ {
+		articles[i].TitleHighlight = utils.Highlight(articles[i].Title, keywords)
+
+		articles[i].Snippet = utils.Snippet(articles[i].Content, keywords, snippetLen)
+		if articles[i].Snippet == "" {
+			articles[i].Snippet = utils.Snippet(articles[i].PreviewCtx, keywords, snippetLen)
+		}
+		if articles[i].Snippet == "" {
+			articles[i].Snippet = utils.Highlight(articles[i].PreviewCtx, nil)
+		}
+	}
+
+	if err := ctl.FillArticlesCategoryName(articles); err != nil {
+		return nil, 0, err
+	}
+
+	return articles, totalSize, nil
+}
+
 // FillArticlesCategoryName 获取文章的分类名
 func (ctl *Article) FillArticlesCategoryName(articles []model.Article) error {
 	categories, err := ctl.categoryData.GetCategoriesMap()
@@ -191,3 +539,109 @@ func (ctl *Article) FillArticlesCategoryName(articles []model.Article) error {
 
 	return nil
 }
+
+// FillArticlesTags 获取文章的标签
+func (ctl *Article) FillArticlesTags(articles []model.Article) error {
+	ids := make([]int64, 0, len(articles))
+	for _, v := range articles {
+		ids = append(ids, v.Id)
+	}
+
+	tags, err := ctl.tagData.ListByArticles(ids)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(articles); i++ {
+		articles[i].Tags = tags[articles[i].Id]
+		if articles[i].Tags == nil {
+			articles[i].Tags = []model.Tag{}
+		}
+	}
+
+	return nil
+}
+
+// checkPublishAt 校验并填充文章的发布时间
+// - 定时发布的文章，发布时间必须晚于当前时间
+// - 直接发布的文章，未指定发布时间时使用当前时间
+func (ctl *Article) checkPublishAt(article *model.Article) error {
+	now := time.Now().Unix()
+
+	switch article.Status {
+	case model.ArticleStatusScheduled:
+		if article.PublishAt <= now {
+			return apierr.ErrArticlePublishAt
+		}
+	case model.ArticleStatusPublished:
+		if article.PublishAt == 0 || article.PublishAt > now {
+			article.PublishAt = now
+		}
+	}
+
+	return nil
+}
+
+// syncSearchIndex 只有已发布的文章可以被搜索到
+func (ctl *Article) syncSearchIndex(article model.Article) {
+	var err error
+	if article.Status == model.ArticleStatusPublished {
+		err = ctl.searcher.Index(article)
+	} else {
+		err = ctl.searcher.Remove(article.Id)
+	}
+
+	if err != nil {
+		ctl.logger.Sugar().Errorf("sync article search index err: %s", err)
+	}
+}
+
+// getRevision 查询文章的历史版本，id为model.ArticleRevisionCurrent时返回文章的当前内容
+func (ctl *Article) getRevision(articleId, id int64) (*model.ArticleRevision, error) {
+	if id != model.ArticleRevisionCurrent {
+		return ctl.revisionData.Get(articleId, id)
+	}
+
+	article, err := ctl.articleData.GetIgnoreStatus(articleId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ArticleRevision{
+		ArticleId:  article.Id,
+		Title:      article.Title,
+		PreviewCtx: article.PreviewCtx,
+		Content:    article.Content,
+	}, nil
+}
+
+func revisionName(id int64) string {
+	if id == model.ArticleRevisionCurrent {
+		return "current"
+	}
+	return fmt.Sprintf("revision#%d", id)
+}
+
+// visitorKey 访问者标识，登录用户使用用户id，游客使用ip和User-Agent的哈希
+func visitorKey(visitor model.Visitor) string {
+	if visitor.UserId > 0 {
+		return "u" + strconv.FormatInt(visitor.UserId, 10)
+	}
+
+	sum := sha1.Sum([]byte(visitor.Ip + "|" + visitor.UserAgent))
+	return "g" + hex.EncodeToString(sum[:])
+}
+
+// renderContent 将文章的Markdown正文渲染为过滤后的html，并生成标题目录
+func renderContent(article *model.Article) error {
+	contentHtml, toc := utils.RenderMarkdown(article.Content)
+
+	tocData, err := json.Marshal(toc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	article.ContentHtml = contentHtml
+	article.Toc = string(tocData)
+	return nil
+}
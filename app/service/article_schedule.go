@@ -0,0 +1,81 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"time"
+)
+
+// ArticleScheduler 定时发布文章的后台任务
+type ArticleScheduler struct {
+	articleData  IArticleScheduleData
+	categoryData IArticleCategoryData
+	searcher     IArticleSearcher
+	interval     time.Duration
+	logger       *logger.CustomLogger
+	stop         chan struct{}
+	done         chan struct{}
+}
+
+func NewArticleScheduler(articleData IArticleScheduleData, categoryData IArticleCategoryData, searcher IArticleSearcher, interval time.Duration, logger *logger.CustomLogger) *ArticleScheduler {
+	return &ArticleScheduler{
+		articleData:  articleData,
+		categoryData: categoryData,
+		searcher:     searcher,
+		interval:     interval,
+		logger:       logger,
+		stop:         make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
+type IArticleScheduleData interface {
+	PublishScheduled(now int64) ([]model.Article, error)
+}
+
+// Start 启动后台任务，每隔interval检查一次到期的定时文章
+func (ctl *ArticleScheduler) Start() {
+	go func() {
+		defer close(ctl.done)
+
+		ticker := time.NewTicker(ctl.interval)
+		defer ticker.Stop()
+
+		ctl.publish()
+		for {
+			select {
+			case <-ticker.C:
+				ctl.publish()
+			case <-ctl.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台任务，等待正在进行的发布完成
+func (ctl *ArticleScheduler) Stop() {
+	close(ctl.stop)
+	<-ctl.done
+}
+
+func (ctl *ArticleScheduler) publish() {
+	articles, err := ctl.articleData.PublishScheduled(time.Now().Unix())
+	if err != nil {
+		ctl.logger.Sugar().Errorf("publish scheduled articles err: %s", err)
+		return
+	}
+
+	// 发布的文章计入分类文章数，让全部分类缓存失效
+	if len(articles) > 0 {
+		ctl.categoryData.ExpireCategoryData()
+	}
+
+	for _, v := range articles {
+		ctl.logger.Sugar().Infof("scheduled article published, id: %d", v.Id)
+
+		if err := ctl.searcher.Index(v); err != nil {
+			ctl.logger.Sugar().Errorf("index article err: %s", err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package service_test
+
+import (
+	"github.com/mittacy/blogBack/app/data"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"reflect"
+	"testing"
+)
+
+type fakeCategoryData struct{}
+
+func (fakeCategoryData) ExpireCategoryData() {}
+
+func (fakeCategoryData) GetCategoriesMap() (map[int64]model.Category, error) {
+	return map[int64]model.Category{1: {Id: 1, Name: "后端"}}, nil
+}
+
+// newSearchService 使用进程内倒排索引创建文章服务，并写入测试文章
+func newSearchService(t *testing.T, articles ...model.Article) (service.IArticleSearcher, *service.Article) {
+	searcher := data.NewArticleMemorySearch()
+	for _, v := range articles {
+		if err := searcher.Index(v); err != nil {
+			t.Fatalf("Index(%d) error = %v", v.Id, err)
+		}
+	}
+
+	articleService := service.NewArticle(nil, fakeCategoryData{}, nil, nil, searcher, nil, nil).(*service.Article)
+	return searcher, articleService
+}
+
+var searchArticles = []model.Article{
+	{Id: 1, CategoryId: 1, Title: "Golang 入门", PreviewCtx: "基础", Content: "hello", CreatedAt: 100},
+	{Id: 2, CategoryId: 1, Title: "随笔", PreviewCtx: "golang 笔记", Content: "world", CreatedAt: 100},
+	{Id: 3, CategoryId: 1, Title: "杂谈", PreviewCtx: "其他", Content: "<b>golang</b>", CreatedAt: 100},
+	{Id: 4, CategoryId: 2, Title: "无关", PreviewCtx: "其他", Content: "rust", CreatedAt: 100},
+	{Id: 5, CategoryId: 1, Title: "杂谈", PreviewCtx: "其他", Content: "golang", CreatedAt: 200},
+}
+
+func searchIds(t *testing.T, articleService *service.Article, q string, page, pageSize int) ([]int64, int64) {
+	articles, totalSize, err := articleService.Search(q, page, pageSize)
+	if err != nil {
+		t.Fatalf("Search(%q) error = %v", q, err)
+	}
+
+	ids := make([]int64, 0, len(articles))
+	for _, v := range articles {
+		ids = append(ids, v.Id)
+	}
+	return ids, totalSize
+}
+
+func TestArticleSearchRanking(t *testing.T) {
+	_, articleService := newSearchService(t, searchArticles...)
+
+	tests := []struct {
+		name string
+		q    string
+		want []int64
+	}{
+		// 标题权重 > 摘要权重 > 正文权重，相关度相同时新文章在前
+		{"field weight", "golang", []int64{1, 2, 5, 3}},
+		{"case insensitive", "GoLang", []int64{1, 2, 5, 3}},
+		// rust只出现在一篇文章中，逆文档频率更高
+		{"idf", "golang rust", []int64{1, 4, 2, 5, 3}},
+		{"cjk", "笔记", []int64{2}},
+		{"no match", "python", []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, totalSize := searchIds(t, articleService, tt.q, 1, 10)
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("Search(%q) ids = %v, want %v", tt.q, ids, tt.want)
+			}
+			if totalSize != int64(len(tt.want)) {
+				t.Errorf("Search(%q) totalSize = %d, want %d", tt.q, totalSize, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestArticleSearchHighlight(t *testing.T) {
+	_, articleService := newSearchService(t, searchArticles...)
+
+	articles, _, err := articleService.Search("golang", 1, 10)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	want := map[int64]struct {
+		title, snippet string
+	}{
+		// 正文和摘要都没有关键词时使用摘要
+		1: {"<em>Golang</em> 入门", "基础"},
+		// 正文没有关键词时从摘要中截取
+		2: {"随笔", "<em>golang</em> 笔记"},
+		// 正文中的html被转义
+		3: {"杂谈", "&lt;b&gt;<em>golang</em>&lt;/b&gt;"},
+		5: {"杂谈", "<em>golang</em>"},
+	}
+	for _, v := range articles {
+		w := want[v.Id]
+		if v.TitleHighlight != w.title {
+			t.Errorf("article %d TitleHighlight = %q, want %q", v.Id, v.TitleHighlight, w.title)
+		}
+		if v.Snippet != w.snippet {
+			t.Errorf("article %d Snippet = %q, want %q", v.Id, v.Snippet, w.snippet)
+		}
+		if v.CategoryName != "后端" {
+			t.Errorf("article %d CategoryName = %q, want %q", v.Id, v.CategoryName, "后端")
+		}
+	}
+}
+
+func TestArticleSearchPagination(t *testing.T) {
+	_, articleService := newSearchService(t, searchArticles...)
+
+	tests := []struct {
+		page, pageSize int
+		want           []int64
+	}{
+		{1, 2, []int64{1, 2}},
+		{2, 2, []int64{5, 3}},
+		{3, 2, []int64{}},
+		{2, 3, []int64{3}},
+	}
+
+	for _, tt := range tests {
+		ids, totalSize := searchIds(t, articleService, "golang", tt.page, tt.pageSize)
+		if !reflect.DeepEqual(ids, tt.want) {
+			t.Errorf("Search(page=%d, pageSize=%d) ids = %v, want %v", tt.page, tt.pageSize, ids, tt.want)
+		}
+		if totalSize != 4 {
+			t.Errorf("Search(page=%d, pageSize=%d) totalSize = %d, want 4", tt.page, tt.pageSize, totalSize)
+		}
+	}
+}
+
+func TestArticleSearchReindex(t *testing.T) {
+	searcher, articleService := newSearchService(t, searchArticles...)
+
+	// 修改后旧内容的词不再命中
+	updated := searchArticles[0]
+	updated.Title = "Rust 入门"
+	if err := searcher.Index(updated); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if ids, _ := searchIds(t, articleService, "golang", 1, 10); !reflect.DeepEqual(ids, []int64{2, 5, 3}) {
+		t.Errorf("Search() after update ids = %v, want [2 5 3]", ids)
+	}
+
+	// 删除的文章不会被搜索到
+	deleted := searchArticles[1]
+	deleted.Deleted = model.ArticleDeletedYes
+	if err := searcher.Index(deleted); err != nil {
+		t.Fatalf("Index() error = %v", err)
+	}
+	if err := searcher.Remove(5); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if ids, totalSize := searchIds(t, articleService, "golang", 1, 10); !reflect.DeepEqual(ids, []int64{3}) || totalSize != 1 {
+		t.Errorf("Search() after remove = %v, %d, want [3], 1", ids, totalSize)
+	}
+}
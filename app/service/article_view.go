@@ -0,0 +1,68 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/pkg/logger"
+	"time"
+)
+
+// ArticleViewFlusher 将redis中累加的文章阅读量定期批量写入数据库的后台任务
+type ArticleViewFlusher struct {
+	articleData IArticleViewData
+	interval    time.Duration
+	logger      *logger.CustomLogger
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func NewArticleViewFlusher(articleData IArticleViewData, interval time.Duration, logger *logger.CustomLogger) *ArticleViewFlusher {
+	return &ArticleViewFlusher{
+		articleData: articleData,
+		interval:    interval,
+		logger:      logger,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+type IArticleViewData interface {
+	FlushViews() (int, error)
+}
+
+// Start 启动后台任务，每隔interval写入一次阅读量
+func (ctl *ArticleViewFlusher) Start() {
+	go func() {
+		defer close(ctl.done)
+
+		ticker := time.NewTicker(ctl.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctl.flush()
+			case <-ctl.stop:
+				// 退出前写入剩余的阅读量
+				ctl.flush()
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台任务，等待剩余的阅读量写入完成
+func (ctl *ArticleViewFlusher) Stop() {
+	close(ctl.stop)
+	<-ctl.done
+}
+
+func (ctl *ArticleViewFlusher) flush() {
+	count, err := ctl.articleData.FlushViews()
+	if err != nil {
+		ctl.logger.Sugar().Errorf("flush article views err: %s", err)
+		return
+	}
+
+	if count > 0 {
+		ctl.logger.Sugar().Infof("article views flushed, count: %d", count)
+	}
+}
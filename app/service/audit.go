@@ -0,0 +1,161 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"time"
+)
+
+type Audit struct {
+	auditData IAuditData
+	logger    *logger.CustomLogger
+}
+
+// 编写实现api层中的各个service接口的构建方法
+
+func NewAudit(auditData IAuditData, logger *logger.CustomLogger) api.IAuditService {
+	return &Audit{
+		auditData: auditData,
+		logger:    logger,
+	}
+}
+
+type IAuditData interface {
+	ListByFilter(filter model.AuditFilter, page, pageSize int) ([]model.AuditLog, error)
+	GetSumByFilter(filter model.AuditFilter) (int64, error)
+	EachByFilter(filter model.AuditFilter, fn func([]model.AuditLog) error) error
+}
+
+// List 按筛选条件分页查询审计日志
+// @param filter 筛选条件
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.AuditLog
+// @return int64 符合条件的日志总数
+// @return error
+func (ctl *Audit) List(filter model.AuditFilter, page, pageSize int) ([]model.AuditLog, int64, error) {
+	logs, err := ctl.auditData.ListByFilter(filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalSize, err := ctl.auditData.GetSumByFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return logs, totalSize, nil
+}
+
+// Export 分批导出符合筛选条件的全部审计日志
+// @param filter 筛选条件
+// @param fn 处理每一批日志，返回错误时停止导出
+// @return error
+func (ctl *Audit) Export(filter model.AuditFilter, fn func([]model.AuditLog) error) error {
+	return ctl.auditData.EachByFilter(filter, fn)
+}
+
+// AuditWriter 异步批量写入审计日志的后台任务，请求只把日志放入队列，不等待写入数据库
+type AuditWriter struct {
+	auditData IAuditWriteData
+	logs      chan model.AuditLog
+	batchSize int
+	interval  time.Duration
+	logger    *logger.CustomLogger
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewAuditWriter(auditData IAuditWriteData, conf model.AuditConfig, logger *logger.CustomLogger) *AuditWriter {
+	return &AuditWriter{
+		auditData: auditData,
+		logs:      make(chan model.AuditLog, conf.BufferSize),
+		batchSize: conf.BatchSize,
+		interval:  time.Duration(conf.FlushInterval) * time.Second,
+		logger:    logger,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+type IAuditWriteData interface {
+	Snapshot(resource string, id int64) (string, error)
+	Insert(logs []model.AuditLog) error
+}
+
+// Snapshot 查询记录操作前的快照，查询出错时记录日志并返回空
+// @param resource 资源名，model.AuditResources中的键
+// @param id 记录id
+// @return string 记录的json
+func (ctl *AuditWriter) Snapshot(resource string, id int64) string {
+	snapshot, err := ctl.auditData.Snapshot(resource, id)
+	if err != nil {
+		ctl.logger.Sugar().Errorf("audit snapshot err: %s", err)
+	}
+
+	return snapshot
+}
+
+// Record 将审计日志放入写入队列，队列满时丢弃日志并记录错误，不阻塞请求
+// @param log 审计日志
+func (ctl *AuditWriter) Record(log model.AuditLog) {
+	select {
+	case ctl.logs <- log:
+	default:
+		ctl.logger.Sugar().Errorf("audit log queue is full, dropped: %+v", log)
+	}
+}
+
+// Start 启动后台任务，日志达到batchSize条或每隔interval写入一次
+func (ctl *AuditWriter) Start() {
+	go func() {
+		defer close(ctl.done)
+
+		ticker := time.NewTicker(ctl.interval)
+		defer ticker.Stop()
+
+		batch := make([]model.AuditLog, 0, ctl.batchSize)
+		for {
+			select {
+			case log := <-ctl.logs:
+				batch = append(batch, log)
+				if len(batch) >= ctl.batchSize {
+					batch = ctl.flush(batch)
+				}
+			case <-ticker.C:
+				batch = ctl.flush(batch)
+			case <-ctl.stop:
+				// 退出前写入队列中剩余的日志
+				for {
+					select {
+					case log := <-ctl.logs:
+						batch = append(batch, log)
+					default:
+						ctl.flush(batch)
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// Stop 停止后台任务，等待剩余的日志写入完成，需要在服务停止接收请求后调用
+func (ctl *AuditWriter) Stop() {
+	close(ctl.stop)
+	<-ctl.done
+}
+
+// flush 写入一批日志，返回清空后的切片
+func (ctl *AuditWriter) flush(batch []model.AuditLog) []model.AuditLog {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := ctl.auditData.Insert(batch); err != nil {
+		ctl.logger.Sugar().Errorf("insert audit logs err: %s, count: %d", err, len(batch))
+	}
+
+	return batch[:0]
+}
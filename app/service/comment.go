@@ -0,0 +1,164 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/pkg/errors"
+)
+
+type Comment struct {
+	commentData ICommentData
+	articleData ICommentArticleData
+	userData    ICommentUserData
+	logger      *logger.CustomLogger
+}
+
+// 编写实现api层中的各个service接口的构建方法
+
+func NewComment(commentData ICommentData, articleData ICommentArticleData, userData ICommentUserData, logger *logger.CustomLogger) api.ICommentService {
+	return &Comment{
+		commentData: commentData,
+		articleData: articleData,
+		userData:    userData,
+		logger:      logger,
+	}
+}
+
+type ICommentData interface {
+	Insert(comment *model.Comment) error
+	Get(id int64) (*model.Comment, error)
+	UpdateById(comment *model.Comment, updateFields []string) error
+	Delete(id int64) error
+	GetSumByArticle(articleId int64) (int64, error)
+	GetRootSumByArticle(articleId int64, withHidden bool) (int64, error)
+	ListRootByArticle(articleId int64, withHidden bool, page, pageSize int) ([]model.Comment, error)
+	ListByRoots(articleId int64, rootIds []int64, withHidden bool) ([]model.Comment, error)
+}
+
+type ICommentArticleData interface {
+	Get(id int64) (*model.Article, error)
+}
+
+type ICommentUserData interface {
+	Get(id int64) (*model.User, error)
+}
+
+func (ctl *Comment) Create(comment model.Comment) (int64, error) {
+	/*
+	 * 1. 检查文章是否存在
+	 * 2. 回复评论时，检查被回复的评论属于该文章，并记录顶级评论id
+	 * 3. 创建评论
+	 */
+	if _, err := ctl.articleData.Get(comment.ArticleId); err != nil {
+		return 0, err
+	}
+
+	if comment.ParentId > 0 {
+		parent, err := ctl.commentData.Get(comment.ParentId)
+		if err != nil {
+			return 0, err
+		}
+		if parent.ArticleId != comment.ArticleId || parent.Status != model.CommentStatusNormal {
+			return 0, apierr.ErrCommentNoExist
+		}
+
+		comment.RootId = parent.RootId
+		if comment.RootId == 0 {
+			comment.RootId = parent.Id
+		}
+	}
+
+	comment.Status = model.CommentStatusNormal
+	comment.Deleted = model.CommentDeletedNo
+
+	if err := ctl.commentData.Insert(&comment); err != nil {
+		return 0, err
+	}
+
+	return comment.Id, nil
+}
+
+func (ctl *Comment) List(articleId int64, withHidden bool, page, pageSize int) ([]model.Comment, int64, error) {
+	/*
+	 * 1. 分页查询顶级评论
+	 * 2. 查询这些顶级评论下的全部回复，挂到对应的顶级评论下
+	 * 3. 填充评论者的用户名
+	 * 4. 查询顶级评论总数
+	 */
+	comments, err := ctl.commentData.ListRootByArticle(articleId, withHidden, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rootIds := make([]int64, 0, len(comments))
+	for _, v := range comments {
+		rootIds = append(rootIds, v.Id)
+	}
+
+	replies, err := ctl.commentData.ListByRoots(articleId, rootIds, withHidden)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ctl.fillUserName(comments)
+	ctl.fillUserName(replies)
+
+	repliesMap := make(map[int64][]model.Comment, len(comments))
+	for _, v := range replies {
+		repliesMap[v.RootId] = append(repliesMap[v.RootId], v)
+	}
+	for i := 0; i < len(comments); i++ {
+		comments[i].Replies = repliesMap[comments[i].Id]
+		if comments[i].Replies == nil {
+			comments[i].Replies = []model.Comment{}
+		}
+	}
+
+	totalSize, err := ctl.commentData.GetRootSumByArticle(articleId, withHidden)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return comments, totalSize, nil
+}
+
+func (ctl *Comment) GetSumByArticle(articleId int64) (int64, error) {
+	return ctl.commentData.GetSumByArticle(articleId)
+}
+
+func (ctl *Comment) UpdateStatus(id int64, status int8) error {
+	comment, err := ctl.commentData.Get(id)
+	if err != nil {
+		return err
+	}
+
+	comment.Status = status
+	return ctl.commentData.UpdateById(comment, []string{"status"})
+}
+
+func (ctl *Comment) Delete(id int64) error {
+	return ctl.commentData.Delete(id)
+}
+
+// fillUserName 填充评论者的用户名，查询失败只记录日志
+func (ctl *Comment) fillUserName(comments []model.Comment) {
+	names := make(map[int64]string, len(comments))
+
+	for i := 0; i < len(comments); i++ {
+		userId := comments[i].UserId
+		if _, ok := names[userId]; !ok {
+			user, err := ctl.userData.Get(userId)
+			if err != nil {
+				if !errors.Is(err, apierr.ErrUserNoExist) {
+					ctl.logger.Sugar().Errorf("get comment user err: %s", err)
+				}
+				names[userId] = ""
+				continue
+			}
+			names[userId] = user.Name
+		}
+		comments[i].UserName = names[userId]
+	}
+}
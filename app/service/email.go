@@ -7,6 +7,7 @@ import (
 	"github.com/mittacy/blogBack/utils"
 	"github.com/pkg/errors"
 	"strings"
+	"time"
 )
 
 type Email struct {
@@ -25,13 +26,17 @@ func NewEmail(emailData IEmailData, logger *logger.CustomLogger) api.IEmailServi
 
 type IEmailData interface {
 	GetEmailTpl(name string) (*model.EmailTpl, error)
-	SaveCode(email string, code string) error
-	GetCode(email string) (string, error)
-	InvalidCode(email string) error
+	CheckSendLimit(email, ip string) error
+	SaveCode(scene, email string, code string) error
+	VerifyCode(scene, email string, code string) error
+	InvalidCode(scene, email string) error
+	SaveResetToken(tokenId string, userId int64, expire time.Duration) error
+	TakeResetToken(tokenId string) (int64, error)
+	InvalidResetTokens(userId int64) error
 	SendEmail(mailTo []string, subject string, body string) error
 }
 
-func (ctl *Email) SendRegisterCode(email string) error {
+func (ctl *Email) SendRegisterCode(email, ip string) error {
 	// 1. 查询邮件模板
 	emailName := model.EmailRegisterTplName
 	tpl, err := ctl.emailData.GetEmailTpl(emailName)
@@ -39,19 +44,46 @@ func (ctl *Email) SendRegisterCode(email string) error {
 		return err
 	}
 
-	// 2. 生成验证码，存入redis
+	// 2. 检查发送频率和次数
+	if err := ctl.emailData.CheckSendLimit(email, ip); err != nil {
+		return err
+	}
+
+	// 3. 生成验证码，存入redis
 	code := utils.RandCode(6)
-	if err := ctl.emailData.SaveCode(email, code); err != nil {
+	if err := ctl.emailData.SaveCode(model.EmailCodeSceneRegister, email, code); err != nil {
 		return err
 	}
 
-	// 3. 处理模板，填充数据
+	// 4. 处理模板，填充数据
 	tpl.Content = strings.Replace(tpl.Content, "${{code}}", code, 1)
 
-	// 4. 发送邮件
+	// 5. 发送邮件
 	if err := ctl.emailData.SendEmail([]string{email}, "注册验证码", tpl.Content); err != nil {
 		return errors.WithStack(err)
 	}
 
 	return nil
 }
+
+// SendAccountLocked 发送账号锁定通知
+// @param user 被锁定的用户
+// @param ip 最近一次失败登录的ip
+// @param until 自动解锁时间
+// @return error
+func (ctl *Email) SendAccountLocked(user *model.User, ip string, until time.Time) error {
+	tpl, err := ctl.emailData.GetEmailTpl(model.EmailAccountLockedTplName)
+	if err != nil {
+		return err
+	}
+
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{name}}", user.Name)
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{ip}}", ip)
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{until}}", until.Format("2006-01-02 15:04:05"))
+
+	if err := ctl.emailData.SendEmail([]string{user.Email}, "账号锁定通知", tpl.Content); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
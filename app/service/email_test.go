@@ -0,0 +1,99 @@
+package service_test
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/data"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"testing"
+	"time"
+)
+
+// recordingEmailData 使用miniredis保存验证码和发送次数，记录发送的邮件而不真正发送
+type recordingEmailData struct {
+	service.IEmailData
+	sent []string // 收件人
+}
+
+func (d *recordingEmailData) GetEmailTpl(name string) (*model.EmailTpl, error) {
+	return &model.EmailTpl{Name: name, Content: "${{link}}"}, nil
+}
+
+func (d *recordingEmailData) SendEmail(mailTo []string, subject string, body string) error {
+	d.sent = append(d.sent, mailTo...)
+	return nil
+}
+
+var testEmailCodeConf = model.EmailCodeConfig{
+	Expire:      300,
+	Cooldown:    60,
+	MaxAttempts: 3,
+	Window:      3600,
+	EmailLimit:  3,
+	IpLimit:     10,
+}
+
+func newRecordingEmailData(t *testing.T) (*recordingEmailData, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr())
+	}}
+	t.Cleanup(func() { pool.Close() })
+
+	return &recordingEmailData{IEmailData: data.NewEmail(nil, pool, model.EmailConfig{}, testEmailCodeConf, nil)}, s
+}
+
+func TestUserForgotPasswordSendLimit(t *testing.T) {
+	initTestToken(t)
+	emailData, s := newRecordingEmailData(t)
+	userData := newFakeUserData(model.User{Id: 1, Name: "user", Email: "user@example.com"})
+	userService := service.NewUser(userData, emailData, nil, nil, model.PasswordResetConfig{Expire: 30}, model.LoginGuardConfig{}, nil)
+
+	if err := userService.ForgotPassword("user@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	if len(emailData.sent) != 1 {
+		t.Fatalf("ForgotPassword() sent %d emails, want 1", len(emailData.sent))
+	}
+
+	// 冷却时间内换ip、换大小写也不能再次发送
+	for _, addr := range []string{"user@example.com", "USER@example.com"} {
+		if err := userService.ForgotPassword(addr, "10.0.0.2"); err != apierr.ErrEmailCodeCooldown {
+			t.Errorf("ForgotPassword(%q) error = %v, want ErrEmailCodeCooldown", addr, err)
+		}
+	}
+
+	for i := 2; i <= testEmailCodeConf.EmailLimit; i++ {
+		s.FastForward(60 * time.Second)
+		if err := userService.ForgotPassword("user@example.com", "10.0.0.3"); err != nil {
+			t.Fatalf("ForgotPassword() #%d error = %v", i, err)
+		}
+	}
+
+	s.FastForward(60 * time.Second)
+	if err := userService.ForgotPassword("user@example.com", "10.0.0.4"); err != apierr.ErrEmailCodeEmailLimit {
+		t.Errorf("ForgotPassword() over email limit error = %v, want ErrEmailCodeEmailLimit", err)
+	}
+	if len(emailData.sent) != testEmailCodeConf.EmailLimit {
+		t.Errorf("ForgotPassword() sent %d emails, want %d", len(emailData.sent), testEmailCodeConf.EmailLimit)
+	}
+}
+
+func TestUserForgotPasswordUnregistered(t *testing.T) {
+	initTestToken(t)
+	emailData, _ := newRecordingEmailData(t)
+	userService := service.NewUser(newFakeUserData(), emailData, nil, nil, model.PasswordResetConfig{}, model.LoginGuardConfig{}, nil)
+
+	// 未注册的邮箱同样计入次数，返回结果与已注册的邮箱相同
+	if err := userService.ForgotPassword("nobody@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("ForgotPassword() error = %v", err)
+	}
+	if err := userService.ForgotPassword("nobody@example.com", "10.0.0.1"); err != apierr.ErrEmailCodeCooldown {
+		t.Errorf("ForgotPassword() again error = %v, want ErrEmailCodeCooldown", err)
+	}
+	if len(emailData.sent) != 0 {
+		t.Errorf("ForgotPassword() sent %d emails to an unregistered address", len(emailData.sent))
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"fmt"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/utils"
+	"strings"
+	"time"
+)
+
+type Feed struct {
+	articleData  IFeedArticleData
+	categoryData IArticleCategoryData
+	conf         model.FeedConfig
+	logger       *logger.CustomLogger
+}
+
+// 编写实现api层中的各个service接口的构建方法
+
+func NewFeed(articleData IFeedArticleData, categoryData IArticleCategoryData, conf model.FeedConfig, logger *logger.CustomLogger) api.IFeedService {
+	conf.Link = strings.TrimSuffix(conf.Link, "/")
+
+	return &Feed{
+		articleData:  articleData,
+		categoryData: categoryData,
+		conf:         conf,
+		logger:       logger,
+	}
+}
+
+type IFeedArticleData interface {
+	List(selectFields []string, page, pageSize int) ([]model.Article, error)
+	ListByCategory(selectFields []string, categoryId int64, page, pageSize int) ([]model.Article, error)
+	GetFeed(name string) ([]byte, error)
+	CacheFeed(name string, data []byte) error
+}
+
+var feedSelectFields = []string{"id", "category_id", "title", "preview_ctx", "created_at", "updated_at"}
+
+func (ctl *Feed) RSS() ([]byte, error) {
+	return ctl.cached(model.FeedNameRSS, func() ([]byte, error) {
+		feed, err := ctl.build(0, "/feed.xml")
+		if err != nil {
+			return nil, err
+		}
+		return utils.RSS(feed)
+	})
+}
+
+func (ctl *Feed) Atom() ([]byte, error) {
+	return ctl.cached(model.FeedNameAtom, func() ([]byte, error) {
+		feed, err := ctl.build(0, "/atom.xml")
+		if err != nil {
+			return nil, err
+		}
+		return utils.Atom(feed)
+	})
+}
+
+func (ctl *Feed) CategoryRSS(categoryId int64) ([]byte, error) {
+	name := fmt.Sprintf("%s:categoryId#%d", model.FeedNameRSS, categoryId)
+
+	return ctl.cached(name, func() ([]byte, error) {
+		feed, err := ctl.build(categoryId, fmt.Sprintf("/category/%d/feed.xml", categoryId))
+		if err != nil {
+			return nil, err
+		}
+		return utils.RSS(feed)
+	})
+}
+
+// cached 优先返回缓存的订阅源，不存在时生成并缓存
+func (ctl *Feed) cached(name string, generate func() ([]byte, error)) ([]byte, error) {
+	data, err := ctl.articleData.GetFeed(name)
+	if err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+	if data != nil {
+		return data, nil
+	}
+
+	if data, err = generate(); err != nil {
+		return nil, err
+	}
+
+	// 缓存失败不影响返回结果
+	if err := ctl.articleData.CacheFeed(name, data); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	return data, nil
+}
+
+// build 查询最新的文章生成订阅源
+// @param categoryId 分类id，为0时包括全部分类
+// @param feedPath 订阅源地址
+func (ctl *Feed) build(categoryId int64, feedPath string) (utils.Feed, error) {
+	categories, err := ctl.categoryData.GetCategoriesMap()
+	if err != nil {
+		return utils.Feed{}, err
+	}
+
+	feed := utils.Feed{
+		Title:       ctl.conf.Title,
+		Link:        ctl.conf.Link,
+		FeedLink:    ctl.conf.Link + feedPath,
+		Description: ctl.conf.Description,
+		UpdatedAt:   time.Now(),
+	}
+
+	var articles []model.Article
+	if categoryId > 0 {
+		category, ok := categories[categoryId]
+		if !ok {
+			return utils.Feed{}, apierr.ErrCategoryNoExist
+		}
+		feed.Title = fmt.Sprintf("%s - %s", ctl.conf.Title, category.Name)
+
+		articles, err = ctl.articleData.ListByCategory(feedSelectFields, categoryId, 1, ctl.conf.Size)
+	} else {
+		articles, err = ctl.articleData.List(feedSelectFields, 1, ctl.conf.Size)
+	}
+	if err != nil {
+		return utils.Feed{}, err
+	}
+
+	// 订阅源的更新时间为最近更新的文章时间
+	var lastUpdated int64
+	feed.Items = make([]utils.FeedItem, 0, len(articles))
+	for _, v := range articles {
+		updated := articleUpdatedAt(v)
+		feed.Items = append(feed.Items, utils.FeedItem{
+			Title:       v.Title,
+			Link:        fmt.Sprintf("%s/article/%d", ctl.conf.Link, v.Id),
+			Description: v.PreviewCtx,
+			Category:    categories[v.CategoryId].Name,
+			CreatedAt:   time.Unix(v.CreatedAt, 0),
+			UpdatedAt:   time.Unix(updated, 0),
+		})
+
+		if updated > lastUpdated {
+			lastUpdated = updated
+		}
+	}
+	if lastUpdated > 0 {
+		feed.UpdatedAt = time.Unix(lastUpdated, 0)
+	}
+
+	return feed, nil
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+)
+
+type Rbac struct {
+	rbacData IRbacData
+	logger   *logger.CustomLogger
+}
+
+// NewRbac 返回具体类型，同时作为api层的service和中间件的权限校验器
+func NewRbac(rbacData IRbacData, logger *logger.CustomLogger) *Rbac {
+	return &Rbac{
+		rbacData: rbacData,
+		logger:   logger,
+	}
+}
+
+var _ api.IRbacService = (*Rbac)(nil)
+
+type IRbacData interface {
+	SyncPermissions(permissions []model.Permission) error
+	HasPermission(userId int64, permission string) (bool, error)
+	ListRoles() ([]model.Role, error)
+	GetRole(id int64) (*model.Role, error)
+	ListPermissions() ([]model.Permission, error)
+	ListUserRoles(userId int64) ([]model.Role, error)
+	CountRoleUsers(roleId int64) (int, error)
+	CreateRole(role *model.Role) error
+	UpdateRole(role model.Role) error
+	DeleteRole(id int64) error
+	SetUserRoles(userId int64, roleIds []int64) error
+	ExistsUser(userId int64) (bool, error)
+	CountActiveRoleUsers(roleId int64) (int64, error)
+}
+
+// Init 写入代码中定义的权限，启动时调用
+func (ctl *Rbac) Init() error {
+	return ctl.rbacData.SyncPermissions(model.Permissions)
+}
+
+// HasPermission 校验用户是否拥有权限，查询出错时记录日志并视为没有权限
+// @param userId 用户id
+// @param permission 权限名
+func (ctl *Rbac) HasPermission(userId int64, permission string) bool {
+	ok, err := ctl.rbacData.HasPermission(userId, permission)
+	if err != nil {
+		ctl.logger.Sugar().Errorf("check permission err: %s", err)
+		return false
+	}
+
+	return ok
+}
+
+func (ctl *Rbac) ListRoles() ([]model.Role, error) {
+	return ctl.rbacData.ListRoles()
+}
+
+func (ctl *Rbac) ListPermissions() ([]model.Permission, error) {
+	return ctl.rbacData.ListPermissions()
+}
+
+// CreateRole 创建角色，只能授予操作人自己拥有的权限
+// @param operatorId 操作人id
+// @param role 角色信息，包括权限名
+func (ctl *Rbac) CreateRole(operatorId int64, role model.Role) (int64, error) {
+	if err := ctl.checkGrantPermissions(operatorId, role.Permissions); err != nil {
+		return 0, err
+	}
+
+	if err := ctl.rbacData.CreateRole(&role); err != nil {
+		return 0, err
+	}
+
+	return role.Id, nil
+}
+
+// UpdateRole 修改角色，修改前后的权限都必须是操作人拥有的权限
+// @param operatorId 操作人id
+// @param role 角色信息，包括权限名
+func (ctl *Rbac) UpdateRole(operatorId int64, role model.Role) error {
+	old, err := ctl.rbacData.GetRole(role.Id)
+	if err != nil {
+		return err
+	}
+
+	// 超级管理员角色不能改名，也不需要设置权限
+	if old.Name == model.RoleSuperAdmin && role.Name != model.RoleSuperAdmin {
+		return apierr.ErrRoleBuiltin
+	}
+	if role.Name == model.RoleSuperAdmin && old.Name != model.RoleSuperAdmin {
+		return apierr.ErrRoleNameExist
+	}
+
+	if err := ctl.checkGrantRole(operatorId, *old); err != nil {
+		return err
+	}
+	if err := ctl.checkGrantPermissions(operatorId, role.Permissions); err != nil {
+		return err
+	}
+
+	return ctl.rbacData.UpdateRole(role)
+}
+
+// DeleteRole 删除角色，角色的权限必须是操作人拥有的权限
+// @param operatorId 操作人id
+// @param id 角色id
+func (ctl *Rbac) DeleteRole(operatorId, id int64) error {
+	role, err := ctl.rbacData.GetRole(id)
+	if err != nil {
+		return err
+	}
+
+	if role.Name == model.RoleSuperAdmin {
+		return apierr.ErrRoleBuiltin
+	}
+
+	if err := ctl.checkGrantRole(operatorId, *role); err != nil {
+		return err
+	}
+
+	return ctl.rbacData.DeleteRole(id)
+}
+
+func (ctl *Rbac) ListUserRoles(userId int64) ([]model.Role, error) {
+	return ctl.rbacData.ListUserRoles(userId)
+}
+
+// SetUserRoles 设置用户的角色，用户必须存在且未注销，不能移除最后一个超级管理员
+// 新增和移除的角色都必须是操作人可以授予的角色，见checkGrantRole
+// @param operatorId 操作人id
+// @param userId 用户id
+// @param roleIds 角色id
+func (ctl *Rbac) SetUserRoles(operatorId, userId int64, roleIds []int64) error {
+	exists, err := ctl.rbacData.ExistsUser(userId)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apierr.ErrUserNoExist
+	}
+
+	oldRoles, err := ctl.rbacData.ListUserRoles(userId)
+	if err != nil {
+		return err
+	}
+
+	oldRoleMap := make(map[int64]model.Role, len(oldRoles))
+	for _, v := range oldRoles {
+		oldRoleMap[v.Id] = v
+	}
+
+	keepSuperAdmin := false
+	seen := make(map[int64]bool, len(roleIds))
+	ids := make([]int64, 0, len(roleIds))
+
+	for _, id := range roleIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+
+		role, err := ctl.rbacData.GetRole(id)
+		if err != nil {
+			return err
+		}
+		if role.Name == model.RoleSuperAdmin {
+			keepSuperAdmin = true
+		}
+
+		if _, ok := oldRoleMap[id]; !ok {
+			if err := ctl.checkGrantRole(operatorId, *role); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, v := range oldRoles {
+		if seen[v.Id] {
+			continue
+		}
+
+		if err := ctl.checkGrantRole(operatorId, v); err != nil {
+			return err
+		}
+	}
+
+	if !keepSuperAdmin {
+		for _, v := range oldRoles {
+			if v.Name != model.RoleSuperAdmin {
+				continue
+			}
+
+			count, err := ctl.rbacData.CountRoleUsers(v.Id)
+			if err != nil {
+				return err
+			}
+			if count <= 1 {
+				return apierr.ErrRoleLastSuperAdmin
+			}
+		}
+	}
+
+	return ctl.rbacData.SetUserRoles(userId, ids)
+}
+
+// CheckManageUser 校验操作人能否管理用户(强制重置密码等)，用户拥有的每个角色都必须是操作人可以授予的角色
+// @param operatorId 操作人id
+// @param userId 被管理的用户id
+// @return error 不能管理时返回apierr.ErrRoleGrantDenied
+func (ctl *Rbac) CheckManageUser(operatorId, userId int64) error {
+	_, err := ctl.checkManageUser(operatorId, userId)
+	return err
+}
+
+// CheckBanUser 校验操作人能否封禁用户，在CheckManageUser的基础上不能封禁最后一个可用的超级管理员
+// @param operatorId 操作人id
+// @param userId 被封禁的用户id
+// @return error
+func (ctl *Rbac) CheckBanUser(operatorId, userId int64) error {
+	roles, err := ctl.checkManageUser(operatorId, userId)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range roles {
+		if v.Name != model.RoleSuperAdmin {
+			continue
+		}
+
+		count, err := ctl.rbacData.CountActiveRoleUsers(v.Id)
+		if err != nil {
+			return err
+		}
+		if count <= 1 {
+			return apierr.ErrRoleLastSuperAdmin
+		}
+	}
+
+	return nil
+}
+
+// checkManageUser 校验用户拥有的每个角色都是操作人可以授予的角色，返回用户的角色
+func (ctl *Rbac) checkManageUser(operatorId, userId int64) ([]model.Role, error) {
+	roles, err := ctl.rbacData.ListUserRoles(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range roles {
+		if err := ctl.checkGrantRole(operatorId, v); err != nil {
+			return nil, err
+		}
+	}
+
+	return roles, nil
+}
+
+// checkGrantRole 校验操作人能否授予、收回或修改角色
+// 超级管理员角色只有超级管理员可以操作，其他角色的权限必须都是操作人拥有的权限
+// @param operatorId 操作人id
+// @param role 角色信息，包括权限名
+func (ctl *Rbac) checkGrantRole(operatorId int64, role model.Role) error {
+	if role.Name != model.RoleSuperAdmin {
+		return ctl.checkGrantPermissions(operatorId, role.Permissions)
+	}
+
+	roles, err := ctl.rbacData.ListUserRoles(operatorId)
+	if err != nil {
+		return err
+	}
+	for _, v := range roles {
+		if v.Name == model.RoleSuperAdmin {
+			return nil
+		}
+	}
+
+	return apierr.ErrRoleGrantDenied
+}
+
+// checkGrantPermissions 校验权限是否都是操作人拥有的权限，超级管理员拥有所有权限
+// @param operatorId 操作人id
+// @param permissions 权限名
+func (ctl *Rbac) checkGrantPermissions(operatorId int64, permissions []string) error {
+	for _, v := range permissions {
+		ok, err := ctl.rbacData.HasPermission(operatorId, v)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return apierr.ErrRoleGrantDenied
+		}
+	}
+
+	return nil
+}
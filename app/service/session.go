@@ -0,0 +1,51 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/jwt"
+	"github.com/mittacy/blogBack/pkg/logger"
+)
+
+type Session struct {
+	userData ISessionUserData
+	logger   *logger.CustomLogger
+}
+
+func NewSession(userData ISessionUserData, logger *logger.CustomLogger) api.ISessionService {
+	return &Session{
+		userData: userData,
+		logger:   logger,
+	}
+}
+
+type ISessionUserData interface {
+	Get(id int64) (*model.User, error)
+}
+
+func (ctl *Session) Refresh(refreshToken, ip string) (*jwt.TokenPair, error) {
+	return jwt.Token.Refresh(refreshToken, ip)
+}
+
+func (ctl *Session) List(userId int64) ([]jwt.Session, error) {
+	return jwt.Token.ListSessions(userId)
+}
+
+func (ctl *Session) Revoke(userId int64, sessionId string) error {
+	return jwt.Token.RevokeSession(userId, sessionId)
+}
+
+func (ctl *Session) Logout(accessToken string) error {
+	return jwt.Token.Logout(accessToken)
+}
+
+// RevokeUser 注销用户所有的会话
+// @param userId 用户id
+// @return error 用户不存在时返回apierr.ErrUserNoExist
+func (ctl *Session) RevokeUser(userId int64) error {
+	if _, err := ctl.userData.Get(userId); err != nil {
+		return err
+	}
+
+	return jwt.Token.RevokeAll(userId)
+}
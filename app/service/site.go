@@ -0,0 +1,209 @@
+package service
+
+import (
+	"fmt"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Site struct {
+	articleData  ISiteArticleData
+	categoryData IArticleCategoryData
+	link         string
+	robots       model.RobotsConfig
+	logger       *logger.CustomLogger
+}
+
+// 编写实现api层中的各个service接口的构建方法
+
+func NewSite(articleData ISiteArticleData, categoryData IArticleCategoryData, link string, robots model.RobotsConfig, logger *logger.CustomLogger) api.ISiteService {
+	return &Site{
+		articleData:  articleData,
+		categoryData: categoryData,
+		link:         strings.TrimSuffix(link, "/"),
+		robots:       robots,
+		logger:       logger,
+	}
+}
+
+type ISiteArticleData interface {
+	ListAll(selectFields []string) ([]model.Article, error)
+	GetSitemap(name string) ([]byte, error)
+	CacheSitemap(name string, data []byte) error
+}
+
+// Sitemap 查询/sitemap.xml，地址数超过上限时返回sitemap索引
+func (ctl *Site) Sitemap() ([]byte, error) {
+	return ctl.sitemap(model.SitemapNameIndex)
+}
+
+// SitemapPage 查询sitemap索引中的第page个sitemap
+func (ctl *Site) SitemapPage(page int) ([]byte, error) {
+	return ctl.sitemap(fmt.Sprintf(model.SitemapNamePage, page))
+}
+
+// Robots 根据配置生成robots.txt
+func (ctl *Site) Robots() string {
+	var b strings.Builder
+
+	for _, rule := range ctl.robots.Rules {
+		fmt.Fprintf(&b, "User-agent: %s\n", rule.UserAgent)
+		for _, v := range rule.Allow {
+			fmt.Fprintf(&b, "Allow: %s\n", v)
+		}
+		for _, v := range rule.Disallow {
+			fmt.Fprintf(&b, "Disallow: %s\n", v)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Sitemap: %s/sitemap.xml\n", ctl.link)
+
+	return b.String()
+}
+
+// sitemap 优先返回缓存，缓存不存在时重新生成全部sitemap
+func (ctl *Site) sitemap(name string) ([]byte, error) {
+	data, err := ctl.articleData.GetSitemap(name)
+	if err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+	if data != nil {
+		return data, nil
+	}
+
+	sitemaps, err := ctl.generate()
+	if err != nil {
+		return nil, err
+	}
+
+	// 缓存失败不影响返回结果
+	for k, v := range sitemaps {
+		if err := ctl.articleData.CacheSitemap(k, v); err != nil {
+			ctl.logger.CacheErrLog(err)
+		}
+	}
+
+	data, ok := sitemaps[name]
+	if !ok {
+		return nil, apierr.ErrSitemapNoExist
+	}
+
+	return data, nil
+}
+
+// generate 生成首页、分类和文章的sitemap
+// @return map[string][]byte sitemap名 => sitemap文档
+// @return error
+func (ctl *Site) generate() (map[string][]byte, error) {
+	articles, err := ctl.articleData.ListAll([]string{"id", "category_id", "created_at", "updated_at"})
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := ctl.categoryData.GetCategoriesMap()
+	if err != nil {
+		return nil, err
+	}
+
+	// 首页和分类的最后修改时间取其中最近更新的文章
+	var siteUpdated int64
+	categoryUpdated := make(map[int64]int64, len(categories))
+	articleURLs := make([]utils.SitemapURL, 0, len(articles))
+	for _, v := range articles {
+		updated := articleUpdatedAt(v)
+		articleURLs = append(articleURLs, utils.SitemapURL{
+			Loc:     fmt.Sprintf("%s/article/%d", ctl.link, v.Id),
+			LastMod: unixOrZero(updated),
+		})
+
+		if updated > siteUpdated {
+			siteUpdated = updated
+		}
+		if updated > categoryUpdated[v.CategoryId] {
+			categoryUpdated[v.CategoryId] = updated
+		}
+	}
+
+	urls := make([]utils.SitemapURL, 0, len(articleURLs)+len(categories)+1)
+	urls = append(urls, utils.SitemapURL{Loc: ctl.link + "/", LastMod: unixOrZero(siteUpdated)})
+	categoryIds := make([]int64, 0, len(categories))
+	for id := range categories {
+		categoryIds = append(categoryIds, id)
+	}
+	sort.Slice(categoryIds, func(i, j int) bool { return categoryIds[i] < categoryIds[j] })
+	for _, id := range categoryIds {
+		urls = append(urls, utils.SitemapURL{
+			Loc:     fmt.Sprintf("%s/category/%d", ctl.link, id),
+			LastMod: unixOrZero(categoryUpdated[id]),
+		})
+	}
+	urls = append(urls, articleURLs...)
+
+	sitemaps := make(map[string][]byte, 1)
+
+	// 未超过上限时/sitemap.xml直接列出所有地址
+	if len(urls) <= utils.SitemapMaxURLs {
+		data, err := utils.Sitemap(urls)
+		if err != nil {
+			return nil, err
+		}
+		sitemaps[model.SitemapNameIndex] = data
+		return sitemaps, nil
+	}
+
+	// 超过上限时按上限拆分，/sitemap.xml为索引
+	var index []utils.SitemapURL
+	for page := 1; (page-1)*utils.SitemapMaxURLs < len(urls); page++ {
+		start := (page - 1) * utils.SitemapMaxURLs
+		end := start + utils.SitemapMaxURLs
+		if end > len(urls) {
+			end = len(urls)
+		}
+
+		data, err := utils.Sitemap(urls[start:end])
+		if err != nil {
+			return nil, err
+		}
+		sitemaps[fmt.Sprintf(model.SitemapNamePage, page)] = data
+
+		var lastMod time.Time
+		for _, v := range urls[start:end] {
+			if v.LastMod.After(lastMod) {
+				lastMod = v.LastMod
+			}
+		}
+		index = append(index, utils.SitemapURL{
+			Loc:     fmt.Sprintf("%s/sitemap/%d.xml", ctl.link, page),
+			LastMod: lastMod,
+		})
+	}
+
+	data, err := utils.SitemapIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	sitemaps[model.SitemapNameIndex] = data
+
+	return sitemaps, nil
+}
+
+// articleUpdatedAt 文章的最后修改时间，没有更新时间的旧文章使用创建时间
+func articleUpdatedAt(article model.Article) int64 {
+	if article.UpdatedAt == 0 {
+		return article.CreatedAt
+	}
+	return article.UpdatedAt
+}
+
+func unixOrZero(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(timestamp, 0)
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"time"
+)
+
+type Stat struct {
+	statData IStatData
+	logger   *logger.CustomLogger
+}
+
+// 编写实现api层中的各个service接口的构建方法
+
+func NewStat(statData IStatData, logger *logger.CustomLogger) api.IStatService {
+	return &Stat{
+		statData: statData,
+		logger:   logger,
+	}
+}
+
+type IStatData interface {
+	ListDaily(target string, id int64, start, end time.Time) ([]model.DailyStat, error)
+}
+
+// IArticleViewTracker 记录文章访问，用于去重阅读量和每日统计
+type IArticleViewTracker interface {
+	RecordView(articleId, categoryId int64, visitor string, now time.Time) (bool, error)
+}
+
+func (ctl *Stat) ListDaily(target string, id int64, start, end time.Time) ([]model.DailyStat, error) {
+	return ctl.statData.ListDaily(target, id, start, end)
+}
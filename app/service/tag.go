@@ -0,0 +1,85 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+)
+
+type Tag struct {
+	tagData     ITagData
+	articleData ITagArticleData
+	logger      *logger.CustomLogger
+}
+
+// 编写实现api层中的各个service接口的构建方法
+
+func NewTag(tagData ITagData, articleData ITagArticleData, logger *logger.CustomLogger) api.ITagService {
+	return &Tag{
+		tagData:     tagData,
+		articleData: articleData,
+		logger:      logger,
+	}
+}
+
+type ITagData interface {
+	Create(tag *model.Tag) error
+	Delete(id int64) error
+	UpdateNameById(tag model.Tag) error
+	List(page, pageSize int) ([]model.Tag, error)
+	GetSum() (int64, error)
+}
+
+type ITagArticleData interface {
+	ExpireSumByTag(tagId int64)
+}
+
+func (ctl *Tag) Create(tag model.Tag) (int64, error) {
+	if err := ctl.tagData.Create(&tag); err != nil {
+		return 0, err
+	}
+
+	return tag.Id, nil
+}
+
+func (ctl *Tag) Delete(id int64) error {
+	if err := ctl.tagData.Delete(id); err != nil {
+		return err
+	}
+
+	// 标签下的文章关联已被删除，让标签文章数缓存失效
+	ctl.articleData.ExpireSumByTag(id)
+
+	return nil
+}
+
+func (ctl *Tag) UpdateName(tag model.Tag) error {
+	return ctl.tagData.UpdateNameById(tag)
+}
+
+// tagListPageSize 未指定分页大小时的默认值，标签数量没有上限，不提供获取全部的方式
+const tagListPageSize = 50
+
+// List 按文章数从多到少分页查询标签
+// @param page 页码，为0时查询第一页
+// @param pageSize 分页大小，为0时使用tagListPageSize
+func (ctl *Tag) List(page, pageSize int) (tags []model.Tag, totalSize int64, err error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = tagListPageSize
+	}
+
+	// 查询列表
+	if tags, err = ctl.tagData.List(page, pageSize); err != nil {
+		return
+	}
+
+	// 查询总记录数
+	if totalSize, err = ctl.tagData.GetSum(); err != nil {
+		return
+	}
+
+	return tags, totalSize, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/api"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/utils"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type Upload struct {
+	uploadData IUploadData
+	storage    IStorage
+	conf       model.UploadConfig
+	logger     *logger.CustomLogger
+}
+
+func NewUpload(uploadData IUploadData, storage IStorage, conf model.UploadConfig, logger *logger.CustomLogger) api.IUploadService {
+	return &Upload{
+		uploadData: uploadData,
+		storage:    storage,
+		conf:       conf,
+		logger:     logger,
+	}
+}
+
+type IUploadData interface {
+	Create(upload *model.Upload) error
+	GetByHash(hash string) (*model.Upload, error)
+	Touch(id, usedAt int64) (bool, error)
+}
+
+// IStorage 文件存储，key为存储中的路径，使用"/"分隔
+type IStorage interface {
+	Put(key string, data []byte, contentType string) error
+	Delete(key string) error
+	URL(key string) string
+}
+
+// uploadExtensions 允许上传的类型对应的文件扩展名
+var uploadExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// thumbnailTypes 可以生成缩略图的类型
+var thumbnailTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/gif": true}
+
+func (ctl *Upload) MaxSize() int64 {
+	return ctl.conf.MaxSize << 20
+}
+
+// Upload 上传文件，内容相同的文件直接返回已上传的记录
+// @param userId 上传者id
+// @param name 原文件名
+// @param data 文件内容
+func (ctl *Upload) Upload(userId int64, name string, data []byte) (*model.Upload, error) {
+	if int64(len(data)) > ctl.MaxSize() {
+		return nil, apierr.ErrUploadTooLarge
+	}
+
+	// 根据文件内容判断类型，不信任客户端提交的Content-Type
+	mimeType := http.DetectContentType(data)
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+	if utils.IndexOf(ctl.conf.AllowedTypes, mimeType) == -1 {
+		return nil, apierr.ErrUploadType
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	now := time.Now().Unix()
+
+	exist, err := ctl.uploadData.GetByHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	if exist != nil {
+		// 刷新最后使用时间，避免刚返回的文件被清理任务按上传时间清理
+		// 记录在查询后被清理时按新文件重新上传
+		touched, err := ctl.uploadData.Touch(exist.Id, now)
+		if err != nil {
+			return nil, err
+		}
+		if touched {
+			exist.LastUsedAt = now
+			ctl.fillUrl(exist)
+			return exist, nil
+		}
+	}
+
+	upload := model.Upload{
+		UserId:     userId,
+		Hash:       hash,
+		Name:       name,
+		MimeType:   mimeType,
+		Size:       int64(len(data)),
+		Path:       "files/" + hash[:2] + "/" + hash + uploadExtensions[mimeType],
+		LastUsedAt: now,
+	}
+
+	if err := ctl.storage.Put(upload.Path, data, mimeType); err != nil {
+		return nil, err
+	}
+
+	if thumbnailTypes[mimeType] {
+		thumb, thumbType, err := utils.Thumbnail(data, ctl.conf.ThumbWidth, ctl.conf.ThumbPixels)
+		if err != nil {
+			// 文件头合法但内容损坏或尺寸过大的图片，不生成缩略图
+			ctl.logger.Sugar().Warnf("generate thumbnail err, hash: %s, err: %s", hash, err)
+		} else {
+			thumbPath := "thumbs/" + hash[:2] + "/" + hash + uploadExtensions[thumbType]
+			if err := ctl.storage.Put(thumbPath, thumb, thumbType); err != nil {
+				return nil, err
+			}
+			upload.ThumbPath = thumbPath
+		}
+	}
+
+	if err := ctl.uploadData.Create(&upload); err != nil {
+		// 同时上传了相同的文件，文件路径由hash决定，直接使用先写入的记录
+		if exist, _ := ctl.uploadData.GetByHash(hash); exist != nil {
+			ctl.fillUrl(exist)
+			return exist, nil
+		}
+		return nil, err
+	}
+
+	ctl.fillUrl(&upload)
+	return &upload, nil
+}
+
+func (ctl *Upload) fillUrl(upload *model.Upload) {
+	upload.Url = ctl.storage.URL(upload.Path)
+	if upload.ThumbPath != "" {
+		upload.ThumbUrl = ctl.storage.URL(upload.ThumbPath)
+	}
+}
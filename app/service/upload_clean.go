@@ -0,0 +1,149 @@
+package service
+
+import (
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"time"
+)
+
+// UploadCleaner 定期清理没有被任何文章引用的上传文件的后台任务
+type UploadCleaner struct {
+	uploadData IUploadCleanData
+	storage    IStorage
+	grace      time.Duration
+	interval   time.Duration
+	logger     *logger.CustomLogger
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// NewUploadCleaner
+// @param grace 最后使用后的保留时间，避免清理掉刚上传或刚重复上传、文章还未保存的文件
+// @param interval 清理间隔
+func NewUploadCleaner(uploadData IUploadCleanData, storage IStorage, grace, interval time.Duration, logger *logger.CustomLogger) *UploadCleaner {
+	return &UploadCleaner{
+		uploadData: uploadData,
+		storage:    storage,
+		grace:      grace,
+		interval:   interval,
+		logger:     logger,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+type IUploadCleanData interface {
+	ListUsedBefore(usedBefore, afterId int64, limit int) ([]model.Upload, error)
+	IsReferenced(path string) (bool, error)
+	DeleteUsedBefore(id, usedBefore int64) (bool, error)
+}
+
+const uploadCleanBatch = 100
+
+// Start 启动后台任务，每隔interval清理一次
+func (ctl *UploadCleaner) Start() {
+	go func() {
+		defer close(ctl.done)
+
+		ticker := time.NewTicker(ctl.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ctl.clean()
+			case <-ctl.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止后台任务，等待正在进行的清理完成
+func (ctl *UploadCleaner) Stop() {
+	close(ctl.stop)
+	<-ctl.done
+}
+
+func (ctl *UploadCleaner) clean() {
+	usedBefore := time.Now().Add(-ctl.grace).Unix()
+	count := 0
+
+	for afterId := int64(0); ; {
+		uploads, err := ctl.uploadData.ListUsedBefore(usedBefore, afterId, uploadCleanBatch)
+		if err != nil {
+			ctl.logger.Sugar().Errorf("list uploads err: %s", err)
+			return
+		}
+
+		for _, v := range uploads {
+			afterId = v.Id
+
+			orphan, err := ctl.isOrphan(v)
+			if err != nil {
+				ctl.logger.Sugar().Errorf("check upload reference err, id: %d, err: %s", v.Id, err)
+				continue
+			}
+			if !orphan {
+				continue
+			}
+
+			removed, err := ctl.remove(v, usedBefore)
+			if err != nil {
+				ctl.logger.Sugar().Errorf("remove orphan upload err, id: %d, err: %s", v.Id, err)
+				continue
+			}
+			if removed {
+				count++
+			}
+		}
+
+		if len(uploads) < uploadCleanBatch {
+			break
+		}
+	}
+
+	if count > 0 {
+		ctl.logger.Sugar().Infof("orphan uploads removed, count: %d", count)
+	}
+}
+
+// isOrphan 文件和缩略图都没有被引用
+func (ctl *UploadCleaner) isOrphan(upload model.Upload) (bool, error) {
+	for _, path := range []string{upload.Path, upload.ThumbPath} {
+		if path == "" {
+			continue
+		}
+
+		referenced, err := ctl.uploadData.IsReferenced(path)
+		if err != nil {
+			return false, err
+		}
+		if referenced {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// remove 先按最后使用时间删除记录再删除文件，查询后被重复上传命中的文件保留
+// 删除文件失败时文件残留在存储中，相同内容再次上传时会覆盖
+// @return bool 是否删除了记录
+func (ctl *UploadCleaner) remove(upload model.Upload, usedBefore int64) (bool, error) {
+	deleted, err := ctl.uploadData.DeleteUsedBefore(upload.Id, usedBefore)
+	if err != nil || !deleted {
+		return false, err
+	}
+
+	for _, path := range []string{upload.Path, upload.ThumbPath} {
+		if path == "" {
+			continue
+		}
+		if err := ctl.storage.Delete(path); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
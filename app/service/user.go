@@ -1,62 +1,99 @@
 package service
 
 import (
+	"fmt"
 	"github.com/mittacy/blogBack/apierr"
 	"github.com/mittacy/blogBack/app/api"
 	"github.com/mittacy/blogBack/app/model"
 	"github.com/mittacy/blogBack/pkg/jwt"
 	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/password"
 	"github.com/mittacy/blogBack/utils"
 	"github.com/pkg/errors"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const passwordResetSignPrefix = "password_reset:" // 重置密码凭证的签名前缀，防止与其他签名数据混用
+
 type User struct {
 	userData  IUserData
 	emailData IEmailData
+	guardData ILoginGuardData
+	roleGuard IUserRoleGuard
+	email     *Email
+	resetConf model.PasswordResetConfig
+	guardConf model.LoginGuardConfig
 	logger    *logger.CustomLogger
 }
 
 // 编写实现api层中的各个service接口的构建方法
 
-func NewUser(userData IUserData, emailData IEmailData, logger *logger.CustomLogger) api.IUserService {
+func NewUser(userData IUserData, emailData IEmailData, guardData ILoginGuardData, roleGuard IUserRoleGuard,
+	resetConf model.PasswordResetConfig, guardConf model.LoginGuardConfig, logger *logger.CustomLogger) *User {
 	return &User{
 		userData:  userData,
 		emailData: emailData,
+		guardData: guardData,
+		roleGuard: roleGuard,
+		email:     &Email{emailData: emailData, logger: logger},
+		resetConf: resetConf,
+		guardConf: guardConf,
 		logger:    logger,
 	}
 }
 
+var _ api.IUserService = (*User)(nil)
+
 type IUserData interface {
 	Create(*model.User) error
 	Get(id int64) (*model.User, error)
 	GetByName(name string) (*model.User, error)
 	GetByEmail(email string) (*model.User, error)
 	UpdatesById(user model.User, updateFields []string, isCleanCache bool) error
+	CleanCache(user *model.User) error
+	GetIdByEmail(email string) (int64, error)
+	UpdateEmail(user *model.User, email string) error
+	CountArticles(userId int64) (int64, error)
+	CountComments(userId int64) (int64, error)
+	ListByFilter(selectFields []string, filter model.UserFilter, page, pageSize int) ([]model.User, error)
+	GetSumByFilter(filter model.UserFilter) (int64, error)
+}
+
+type ILoginGuardData interface {
+	CheckIp(ip string) error
+	CheckAccount(userId int64) error
+	RecordFailure(userId int64, ip string) (locked bool, err error)
+	Reset(userId int64) error
+	Unlock(userId int64) error
+}
+
+// IUserRoleGuard 校验管理员能否管理拥有角色的用户，由Rbac实现
+type IUserRoleGuard interface {
+	CheckManageUser(operatorId, userId int64) error
+	CheckBanUser(operatorId, userId int64) error
 }
 
 func (ctl *User) Register(user model.User, code string) (userId int64, err error) {
 	// 1. 检查验证码
-	rCode, err := ctl.emailData.GetCode(user.Email)
-	if err != nil {
+	if err = ctl.emailData.VerifyCode(model.EmailCodeSceneRegister, user.Email, code); err != nil {
 		return
 	}
 
-	if rCode != code {
-		err = apierr.ErrRegisterCode
+	// 2. 加密密码
+	if user.Password, err = password.Hash(user.Password); err != nil {
 		return
 	}
 
-	// 2. 加密密码
-	user.Password, user.Salt = utils.Encryption(user.Password)
-
 	// 3. 创建用户
 	if err = ctl.userData.Create(&user); err != nil {
 		return
 	}
 
 	// 4. 让验证码失效
-	if err := ctl.emailData.InvalidCode(user.Email); err != nil {
+	if err := ctl.emailData.InvalidCode(model.EmailCodeSceneRegister, user.Email); err != nil {
 		ctl.logger.Sugar().Errorf("置位注册验证码失效错误: %s", err)
 	}
 
@@ -64,21 +101,278 @@ func (ctl *User) Register(user model.User, code string) (userId int64, err error
 }
 
 func (ctl *User) GetUserInfo(id int64) (*model.User, error) {
-	return ctl.userData.Get(id)
+	user, err := ctl.userData.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsDeleted() {
+		return nil, apierr.ErrUserNoExist
+	}
+
+	if user.ArticleCount, err = ctl.userData.CountArticles(id); err != nil {
+		return nil, err
+	}
+	if user.CommentCount, err = ctl.userData.CountComments(id); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// UpdatePrivacy 修改隐私设置
+// @param userId 用户id
+// @param privacy UserPrivacy*的组合
+// @return error
+func (ctl *User) UpdatePrivacy(userId int64, privacy int) error {
+	return ctl.userData.UpdatesById(model.User{Id: userId, Privacy: privacy}, []string{"privacy"}, true)
+}
+
+// UpdateProfile 修改个人资料
+// @param user 用户id和修改后的资料
+// @return error
+func (ctl *User) UpdateProfile(user model.User) error {
+	return ctl.userData.UpdatesById(user, []string{"gender", "introduce", "github", "avatar"}, true)
+}
+
+// UpdatePassword 使用当前密码修改密码，成功后注销其他登录设备
+// @param userId 用户id
+// @param sessionId 当前会话id，不会被注销
+// @param oldPassword 当前密码
+// @param newPassword 新密码
+// @return error 当前密码错误时返回apierr.ErrOldPassword
+func (ctl *User) UpdatePassword(userId int64, sessionId, oldPassword, newPassword string) error {
+	// 1. 校验当前密码
+	if _, err := ctl.checkPassword(userId, oldPassword); err != nil {
+		return err
+	}
+
+	// 2. 保存新密码
+	encoded, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	u := model.User{Id: userId, Password: encoded, Salt: ""}
+	if err := ctl.userData.UpdatesById(u, []string{"password", "salt"}, true); err != nil {
+		return err
+	}
+
+	// 3. 注销其他登录设备
+	sessions, err := jwt.Token.ListSessions(userId)
+	if err != nil {
+		return err
+	}
+	for _, v := range sessions {
+		if v.Id == sessionId {
+			continue
+		}
+		if err := jwt.Token.RevokeSession(userId, v.Id); err != nil && !errors.Is(err, apierr.ErrSessionNoExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Delete 注销账号，个人信息被匿名化，name和email释放给其他用户注册，所有会话失效
+// 评论等内容保留，显示为匿名化后的用户名
+// @param userId 用户id
+// @param pw 当前密码
+// @return error 密码错误时返回apierr.ErrOldPassword
+func (ctl *User) Delete(userId int64, pw string) error {
+	// 1. 校验密码
+	user, err := ctl.checkPassword(userId, pw)
+	if err != nil {
+		return err
+	}
+
+	// 2. 匿名化个人信息，清空密码使账号无法再登录
+	anonymous := model.User{
+		Id:        userId,
+		Name:      fmt.Sprintf(model.UserDeletedNameFormat, userId),
+		Email:     fmt.Sprintf(model.UserDeletedEmailFormat, userId),
+		Gender:    model.UserGenderSecret,
+		DeletedAt: time.Now().Unix(),
+	}
+	fields := []string{"name", "email", "password", "salt", "gender", "introduce", "github", "avatar", "deleted_at"}
+	if err := ctl.userData.UpdatesById(anonymous, fields, false); err != nil {
+		return err
+	}
+
+	// 3. 清除缓存，原name和email不能再查询到该用户
+	if err := ctl.userData.CleanCache(user); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	// 4. 使未使用的重置密码凭证失效，注销所有会话
+	if err := ctl.emailData.InvalidResetTokens(userId); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+	if err := ctl.guardData.Unlock(userId); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+	return jwt.Token.RevokeAll(userId)
+}
+
+// SendChangeEmailCode 向新邮箱发送修改邮箱的验证码
+// @param userId 用户id
+// @param email 新邮箱
+// @param ip 请求ip
+// @return error 新邮箱已被注册时返回apierr.ErrUserEmailExist
+func (ctl *User) SendChangeEmailCode(userId int64, email, ip string) error {
+	// 1. 检查新邮箱是否已被注册
+	if err := ctl.checkEmailUnused(email); err != nil {
+		return err
+	}
+
+	// 2. 查询邮件模板
+	tpl, err := ctl.emailData.GetEmailTpl(model.EmailChangeCodeTplName)
+	if err != nil {
+		return err
+	}
+
+	// 3. 检查发送频率和次数
+	if err := ctl.emailData.CheckSendLimit(email, ip); err != nil {
+		return err
+	}
+
+	// 4. 生成验证码，存入redis，验证码与用户绑定
+	code := utils.RandCode(6)
+	if err := ctl.emailData.SaveCode(changeEmailScene(userId), email, code); err != nil {
+		return err
+	}
+
+	// 5. 发送邮件
+	tpl.Content = strings.Replace(tpl.Content, "${{code}}", code, 1)
+	if err := ctl.emailData.SendEmail([]string{email}, "修改邮箱验证码", tpl.Content); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// ChangeEmail 使用新邮箱收到的验证码修改邮箱，修改后向旧邮箱发送通知
+// @param userId 用户id
+// @param email 新邮箱
+// @param code 验证码
+// @return error
+func (ctl *User) ChangeEmail(userId int64, email, code string) error {
+	// 1. 校验验证码
+	scene := changeEmailScene(userId)
+	if err := ctl.emailData.VerifyCode(scene, email, code); err != nil {
+		return err
+	}
+
+	// 2. 修改邮箱
+	user, err := ctl.userData.Get(userId)
+	if err != nil {
+		return err
+	}
+	if user.Email == email {
+		return nil
+	}
+
+	if err := ctl.userData.UpdateEmail(user, email); err != nil {
+		return err
+	}
+
+	// 3. 让验证码失效
+	if err := ctl.emailData.InvalidCode(scene, email); err != nil {
+		ctl.logger.Sugar().Errorf("置位修改邮箱验证码失效错误: %s", err)
+	}
+
+	// 4. 通知旧邮箱
+	go func() {
+		if err := ctl.sendEmailChanged(user, email); err != nil {
+			ctl.logger.Sugar().Errorf("send email changed notice err: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// checkEmailUnused 检查邮箱是否未被注册
+// @param email 邮箱
+// @return error 已被注册时返回apierr.ErrUserEmailExist
+func (ctl *User) checkEmailUnused(email string) error {
+	_, err := ctl.userData.GetIdByEmail(email)
+	if err == nil {
+		return apierr.ErrUserEmailExist
+	}
+	if errors.Is(err, apierr.ErrUserNoExist) {
+		return nil
+	}
+
+	return err
+}
+
+// sendEmailChanged 向旧邮箱发送邮箱已修改的通知
+// @param user 用户修改前的信息
+// @param email 新邮箱
+// @return error
+func (ctl *User) sendEmailChanged(user *model.User, email string) error {
+	tpl, err := ctl.emailData.GetEmailTpl(model.EmailChangedTplName)
+	if err != nil {
+		return err
+	}
+
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{name}}", user.Name)
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{email}}", email)
+
+	if err := ctl.emailData.SendEmail([]string{user.Email}, "邮箱已修改", tpl.Content); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// changeEmailScene 修改邮箱验证码的用途，绑定用户id
+func changeEmailScene(userId int64) string {
+	return model.EmailCodeSceneChangeEmail + ":" + strconv.FormatInt(userId, 10)
 }
 
-func (ctl *User) LoginByName(name, password string) (string, error) {
+// checkPassword 校验用户的当前密码
+// @param userId 用户id
+// @param pw 当前密码
+// @return *model.User 用户信息
+// @return error 密码错误时返回apierr.ErrOldPassword
+func (ctl *User) checkPassword(userId int64, pw string) (*model.User, error) {
+	user, err := ctl.userData.Get(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, _, err := password.Verify(pw, user.Password, user.Salt)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, apierr.ErrOldPassword
+	}
+
+	return user, nil
+}
+
+func (ctl *User) LoginByName(name, password string, device model.Device) (*jwt.TokenPair, error) {
 	user := model.User{Name: name, Password: password}
-	return ctl.login(model.LoginTypeByName, user)
+	return ctl.login(model.LoginTypeByName, user, device)
 }
 
-func (ctl *User) LoginByEmail(email, password string) (string, error) {
+func (ctl *User) LoginByEmail(email, password string, device model.Device) (*jwt.TokenPair, error) {
 	user := model.User{Email: email, Password: password}
-	return ctl.login(model.LoginTypeByEmail, user)
+	return ctl.login(model.LoginTypeByEmail, user, device)
 }
 
-func (ctl *User) login(loginType int, user model.User) (token string, err error) {
-	// 1. 查询数据库中用户的信息
+func (ctl *User) login(loginType int, user model.User, device model.Device) (token *jwt.TokenPair, err error) {
+	// 1. 检查ip是否被限制登录，限流数据查询出错时不影响登录
+	if err = ctl.guardData.CheckIp(device.Ip); err != nil {
+		if errors.Is(err, apierr.ErrLoginIpLocked) {
+			return
+		}
+		ctl.logger.CacheErrLog(err)
+	}
+
+	// 2. 查询数据库中用户的信息
 	realUser := &model.User{}
 
 	switch loginType {
@@ -88,19 +382,57 @@ func (ctl *User) login(loginType int, user model.User) (token string, err error)
 		realUser, err = ctl.userData.GetByEmail(user.Email)
 	}
 
+	if err == nil && realUser.IsDeleted() {
+		// 已注销的账号按不存在处理
+		err = apierr.ErrUserNoExist
+	}
 	if err != nil {
 		if errors.Is(err, apierr.ErrUserNoExist) { // 隐藏错误信息，不让用户知道是账号不存在
+			password.VerifyDummy(user.Password) // 同样计算一次哈希，避免通过响应时间判断账号是否存在
+			ctl.recordLoginFailure(nil, device.Ip)
 			err = apierr.ErrUserOrPassword
 		}
 		return
 	}
 
-	// 2. 校验密码
-	if utils.EncryptionBySalt(user.Password, realUser.Salt) != realUser.Password {
+	// 3. 检查账号是否被锁定
+	if err = ctl.guardData.CheckAccount(realUser.Id); err != nil {
+		if errors.Is(err, apierr.ErrAccountLocked) || errors.Is(err, apierr.ErrLoginTooFrequent) {
+			return
+		}
+		ctl.logger.CacheErrLog(err)
+	}
+
+	// 4. 校验密码
+	ok, needsRehash, err := password.Verify(user.Password, realUser.Password, realUser.Salt)
+	if err != nil {
+		return
+	}
+	if !ok {
+		if ctl.recordLoginFailure(realUser, device.Ip) {
+			err = apierr.ErrAccountLocked
+			return
+		}
+		err = apierr.ErrUserOrPassword
 		return
 	}
 
-	// 3. 更新登录时间
+	if err := ctl.guardData.Reset(realUser.Id); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	// 密码正确后再检查封禁，避免泄露账号的封禁状态
+	if realUser.IsBanned() {
+		err = apierr.ErrUserBanned
+		return
+	}
+
+	// 旧算法或旧参数的密码使用当前算法重新哈希，失败不影响登录
+	if needsRehash {
+		ctl.rehashPassword(realUser.Id, user.Password)
+	}
+
+	// 5. 更新登录时间
 	u := model.User{Id: realUser.Id, LoginAt: time.Now().Unix()}
 	go func() {
 		if err := ctl.userData.UpdatesById(u, []string{"login_at"}, false); err != nil {
@@ -108,11 +440,310 @@ func (ctl *User) login(loginType int, user model.User) (token string, err error)
 		}
 	}()
 
-	// 4. 生成 token
-	token, err = jwt.Token.Create(user.Id, model.UserRoleNormal)
+	// 6. 创建会话，生成 token
+	return jwt.Token.CreateSession(realUser.Id, device.UserAgent, device.Ip)
+}
+
+// recordLoginFailure 记录登录失败，账号因此被锁定时发送邮件通知
+// @param user 登录的用户，账号不存在时为nil
+// @param ip 登录ip
+// @return bool 账号是否被锁定
+func (ctl *User) recordLoginFailure(user *model.User, ip string) bool {
+	var userId int64
+	if user != nil {
+		userId = user.Id
+	}
+
+	locked, err := ctl.guardData.RecordFailure(userId, ip)
+	if err != nil {
+		ctl.logger.CacheErrLog(err)
+		return false
+	}
+	if !locked {
+		return false
+	}
+
+	until := time.Now().Add(time.Duration(ctl.guardConf.LockDuration) * time.Second)
+	go func() {
+		if err := ctl.email.SendAccountLocked(user, ip, until); err != nil {
+			ctl.logger.Sugar().Errorf("send account locked email err: %s", err)
+		}
+	}()
+
+	return true
+}
+
+// Unlock 解除因登录失败被锁定的账号
+// @param userId 用户id
+// @return error 用户不存在时返回apierr.ErrUserNoExist
+func (ctl *User) Unlock(userId int64) error {
+	if _, err := ctl.userData.Get(userId); err != nil {
+		return err
+	}
+
+	return ctl.guardData.Unlock(userId)
+}
+
+// AdminList 管理员按筛选条件分页查询用户
+// @param filter 筛选条件
+// @param page 页码
+// @param pageSize 分页大小
+// @return []model.User
+// @return int64 符合条件的用户总数
+// @return error
+func (ctl *User) AdminList(filter model.UserFilter, page, pageSize int) ([]model.User, int64, error) {
+	fields := []string{"id", "name", "email", "avatar", "created_at", "login_at", "deleted_at", "banned_at", "ban_expire_at"}
+
+	users, err := ctl.userData.ListByFilter(fields, filter, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	totalSize, err := ctl.userData.GetSumByFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return users, totalSize, nil
+}
+
+// AdminGet 管理员查询用户详情，包括已注销的用户和封禁信息
+// @param id 用户id
+// @return *model.User
+// @return error
+func (ctl *User) AdminGet(id int64) (*model.User, error) {
+	user, err := ctl.userData.Get(id)
 	if err != nil {
-		return "", errors.WithStack(err)
+		return nil, err
+	}
+
+	if user.ArticleCount, err = ctl.userData.CountArticles(id); err != nil {
+		return nil, err
+	}
+	if user.CommentCount, err = ctl.userData.CountComments(id); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Ban 封禁用户，封禁期间不能登录，已登录的会话全部失效
+// 拥有角色的用户只能由可以授予其所有角色的管理员封禁，不能封禁最后一个可用的超级管理员
+// @param operatorId 操作的管理员id，不能封禁自己
+// @param userId 用户id
+// @param reason 封禁原因
+// @param expireAt 封禁到期时间戳，0为永久封禁
+// @return error
+func (ctl *User) Ban(operatorId, userId int64, reason string, expireAt int64) error {
+	if operatorId == userId {
+		return apierr.ErrUserBanSelf
 	}
 
-	return token, nil
+	now := time.Now().Unix()
+	if expireAt != 0 && expireAt <= now {
+		return apierr.ErrUserBanExpire
+	}
+
+	user, err := ctl.userData.Get(userId)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted() {
+		return apierr.ErrUserNoExist
+	}
+
+	if err := ctl.roleGuard.CheckBanUser(operatorId, userId); err != nil {
+		return err
+	}
+
+	u := model.User{Id: userId, BannedAt: now, BanExpireAt: expireAt, BanReason: reason}
+	if err := ctl.userData.UpdatesById(u, []string{"banned_at", "ban_expire_at", "ban_reason"}, true); err != nil {
+		return err
+	}
+
+	return jwt.Token.RevokeAll(userId)
+}
+
+// Unban 解除用户封禁
+// @param userId 用户id
+// @return error
+func (ctl *User) Unban(userId int64) error {
+	if _, err := ctl.userData.Get(userId); err != nil {
+		return err
+	}
+
+	u := model.User{Id: userId}
+	return ctl.userData.UpdatesById(u, []string{"banned_at", "ban_expire_at", "ban_reason"}, true)
+}
+
+// IsBanned 查询用户当前是否处于封禁中
+// @param userId 用户id
+// @return bool
+// @return error
+func (ctl *User) IsBanned(userId int64) (bool, error) {
+	user, err := ctl.userData.Get(userId)
+	if err != nil {
+		return false, err
+	}
+
+	return user.IsBanned(), nil
+}
+
+// ForceResetPassword 强制用户重置密码，清空当前密码并注销所有会话，向用户邮箱发送重置密码邮件
+// @param operatorId 操作的管理员id，必须可以授予用户拥有的所有角色
+// @param userId 用户id
+// @return error
+func (ctl *User) ForceResetPassword(operatorId, userId int64) error {
+	// 1. 查询用户
+	user, err := ctl.userData.Get(userId)
+	if err != nil {
+		return err
+	}
+	if user.IsDeleted() {
+		return apierr.ErrUserNoExist
+	}
+	if err := ctl.roleGuard.CheckManageUser(operatorId, userId); err != nil {
+		return err
+	}
+
+	// 2. 清空密码，原密码不能再登录
+	u := model.User{Id: userId, Password: "", Salt: ""}
+	if err := ctl.userData.UpdatesById(u, []string{"password", "salt"}, true); err != nil {
+		return err
+	}
+
+	// 3. 注销所有会话
+	if err := jwt.Token.RevokeAll(userId); err != nil {
+		return err
+	}
+
+	// 4. 发送重置密码邮件
+	return ctl.sendPasswordReset(user)
+}
+
+// ForgotPassword 发送重置密码邮件，邮箱未注册时同样返回成功，避免泄露邮箱是否注册
+// 发送频率和次数限制与验证码相同，不论邮箱是否注册都计入次数
+// @param email 邮箱
+// @param ip 请求ip
+// @return error 超过限制时返回对应的apierr错误
+func (ctl *User) ForgotPassword(email, ip string) error {
+	// 1. 检查发送频率和次数
+	if err := ctl.emailData.CheckSendLimit(email, ip); err != nil {
+		return err
+	}
+
+	// 2. 查询用户
+	user, err := ctl.userData.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, apierr.ErrUserNoExist) {
+			return nil
+		}
+		return err
+	}
+	if user.IsDeleted() {
+		return nil
+	}
+
+	// 3. 发送重置密码邮件
+	return ctl.sendPasswordReset(user)
+}
+
+// sendPasswordReset 生成重置密码凭证并发送重置密码邮件
+// @param user 用户信息
+// @return error
+func (ctl *User) sendPasswordReset(user *model.User) error {
+	// 1. 查询邮件模板
+	tpl, err := ctl.emailData.GetEmailTpl(model.EmailPasswordResetTplName)
+	if err != nil {
+		return err
+	}
+
+	// 2. 生成凭证，存入redis，格式: 随机串.签名
+	tokenId, err := utils.RandToken(32)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	expire := time.Duration(ctl.resetConf.Expire) * time.Minute
+	if err := ctl.emailData.SaveResetToken(tokenId, user.Id, expire); err != nil {
+		return err
+	}
+	token := tokenId + "." + jwt.Sign(passwordResetSignPrefix+tokenId)
+
+	// 3. 处理模板，填充数据
+	link := ctl.resetConf.Link + "?token=" + url.QueryEscape(token)
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{link}}", link)
+	tpl.Content = strings.ReplaceAll(tpl.Content, "${{expire}}", strconv.Itoa(ctl.resetConf.Expire))
+
+	// 4. 发送邮件
+	if err := ctl.emailData.SendEmail([]string{user.Email}, "重置密码", tpl.Content); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// ResetPassword 使用重置密码凭证修改密码，凭证只能使用一次，成功后注销用户所有的会话
+// @param token 邮件中的重置密码凭证
+// @param newPassword 新密码
+// @return error 凭证无效时返回apierr.ErrPasswordResetToken
+func (ctl *User) ResetPassword(token, newPassword string) error {
+	// 1. 校验签名，签名错误的凭证不需要查询redis
+	i := strings.IndexByte(token, '.')
+	if i <= 0 || !jwt.VerifySign(passwordResetSignPrefix+token[:i], token[i+1:]) {
+		return apierr.ErrPasswordResetToken
+	}
+
+	// 2. 取出凭证，凭证同时失效
+	userId, err := ctl.emailData.TakeResetToken(token[:i])
+	if err != nil {
+		return err
+	}
+
+	// 3. 已注销的账号不能通过重置密码恢复
+	user, err := ctl.userData.Get(userId)
+	if err != nil {
+		if errors.Is(err, apierr.ErrUserNoExist) {
+			return apierr.ErrPasswordResetToken
+		}
+		return err
+	}
+	if user.IsDeleted() {
+		return apierr.ErrPasswordResetToken
+	}
+
+	// 4. 保存新密码
+	encoded, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	u := model.User{Id: userId, Password: encoded, Salt: ""}
+	if err := ctl.userData.UpdatesById(u, []string{"password", "salt"}, true); err != nil {
+		return err
+	}
+
+	// 5. 解除登录失败导致的锁定
+	if err := ctl.guardData.Unlock(userId); err != nil {
+		ctl.logger.CacheErrLog(err)
+	}
+
+	// 6. 注销所有会话，已登录的设备需要使用新密码重新登录
+	return jwt.Token.RevokeAll(userId)
+}
+
+// rehashPassword 使用当前算法重新哈希密码并保存，清除旧密码的盐
+// @param userId 用户id
+// @param pw 校验通过的原密码
+func (ctl *User) rehashPassword(userId int64, pw string) {
+	encoded, err := password.Hash(pw)
+	if err != nil {
+		ctl.logger.Sugar().Errorf("rehash password err: %s", err)
+		return
+	}
+
+	u := model.User{Id: userId, Password: encoded, Salt: ""}
+	if err := ctl.userData.UpdatesById(u, []string{"password", "salt"}, true); err != nil {
+		ctl.logger.Sugar().Errorf("rehash password err: %s", err)
+	}
 }
@@ -0,0 +1,254 @@
+package service_test
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/service"
+	"github.com/mittacy/blogBack/pkg/jwt"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/spf13/viper"
+	"testing"
+)
+
+// fakeUserData 内存中的用户数据，记录修改过的字段
+type fakeUserData struct {
+	users   map[int64]*model.User
+	updates map[int64][]string // 用户id => 修改的字段
+}
+
+func newFakeUserData(users ...model.User) *fakeUserData {
+	d := &fakeUserData{users: map[int64]*model.User{}, updates: map[int64][]string{}}
+	for i := range users {
+		d.users[users[i].Id] = &users[i]
+	}
+	return d
+}
+
+func (d *fakeUserData) Create(user *model.User) error {
+	user.Id = int64(len(d.users) + 1)
+	d.users[user.Id] = user
+	return nil
+}
+
+func (d *fakeUserData) Get(id int64) (*model.User, error) {
+	user, ok := d.users[id]
+	if !ok {
+		return nil, apierr.ErrUserNoExist
+	}
+	u := *user
+	return &u, nil
+}
+
+func (d *fakeUserData) GetByName(name string) (*model.User, error) {
+	for _, v := range d.users {
+		if v.Name == name {
+			return d.Get(v.Id)
+		}
+	}
+	return nil, apierr.ErrUserNoExist
+}
+
+func (d *fakeUserData) GetByEmail(email string) (*model.User, error) {
+	for _, v := range d.users {
+		if v.Email == email {
+			return d.Get(v.Id)
+		}
+	}
+	return nil, apierr.ErrUserNoExist
+}
+
+func (d *fakeUserData) UpdatesById(user model.User, updateFields []string, isCleanCache bool) error {
+	d.updates[user.Id] = append(d.updates[user.Id], updateFields...)
+	return nil
+}
+
+func (d *fakeUserData) CleanCache(user *model.User) error                { return nil }
+func (d *fakeUserData) GetIdByEmail(email string) (int64, error)         { return 0, nil }
+func (d *fakeUserData) UpdateEmail(user *model.User, email string) error { return nil }
+func (d *fakeUserData) CountArticles(userId int64) (int64, error)        { return 0, nil }
+func (d *fakeUserData) CountComments(userId int64) (int64, error)        { return 0, nil }
+
+func (d *fakeUserData) ListByFilter(selectFields []string, filter model.UserFilter, page, pageSize int) ([]model.User, error) {
+	return nil, nil
+}
+
+func (d *fakeUserData) GetSumByFilter(filter model.UserFilter) (int64, error) { return 0, nil }
+
+// fakeRbacData 内存中的角色分配
+type fakeRbacData struct {
+	roles             map[int64]model.Role
+	userRoles         map[int64][]int64
+	activeSuperAdmins int64
+}
+
+func (d *fakeRbacData) SyncPermissions(permissions []model.Permission) error { return nil }
+
+func (d *fakeRbacData) HasPermission(userId int64, permission string) (bool, error) {
+	for _, id := range d.userRoles[userId] {
+		role := d.roles[id]
+		if role.Name == model.RoleSuperAdmin {
+			return true, nil
+		}
+		for _, v := range role.Permissions {
+			if v == permission {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (d *fakeRbacData) ListRoles() ([]model.Role, error) { return nil, nil }
+
+func (d *fakeRbacData) GetRole(id int64) (*model.Role, error) {
+	role, ok := d.roles[id]
+	if !ok {
+		return nil, apierr.ErrRoleNoExist
+	}
+	return &role, nil
+}
+
+func (d *fakeRbacData) ListPermissions() ([]model.Permission, error) { return nil, nil }
+
+func (d *fakeRbacData) ListUserRoles(userId int64) ([]model.Role, error) {
+	roles := make([]model.Role, 0, len(d.userRoles[userId]))
+	for _, id := range d.userRoles[userId] {
+		roles = append(roles, d.roles[id])
+	}
+	return roles, nil
+}
+
+func (d *fakeRbacData) CountRoleUsers(roleId int64) (int, error)         { return 0, nil }
+func (d *fakeRbacData) CreateRole(role *model.Role) error                { return nil }
+func (d *fakeRbacData) UpdateRole(role model.Role) error                 { return nil }
+func (d *fakeRbacData) DeleteRole(id int64) error                        { return nil }
+func (d *fakeRbacData) SetUserRoles(userId int64, roleIds []int64) error { return nil }
+func (d *fakeRbacData) ExistsUser(userId int64) (bool, error)            { return true, nil }
+func (d *fakeRbacData) CountActiveRoleUsers(roleId int64) (int64, error) {
+	return d.activeSuperAdmins, nil
+}
+
+// initTestToken 使用miniredis初始化jwt.Token
+func initTestToken(t *testing.T) {
+	s := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr())
+	}}
+	t.Cleanup(func() { pool.Close() })
+
+	viper.Set("jwt.accessExpire", 30)
+	viper.Set("jwt.refreshExpire", 24)
+	viper.Set("jwt.secret", "test-secret")
+	jwt.InitToken(cache.ConnRedisByPool(pool, "token"))
+}
+
+const (
+	testSuperAdminRoleId = 1
+	testModeratorRoleId  = 2
+	testEditorRoleId     = 3
+
+	testSuperAdminId = 1 // 超级管理员
+	testModeratorId  = 2 // 只有封禁和重置密码权限
+	testEditorId     = 3 // 编辑
+	testNormalUserId = 4 // 没有角色
+)
+
+func newManageUserService(t *testing.T, activeSuperAdmins int64) (*service.User, *fakeUserData, *fakeRbacData) {
+	initTestToken(t)
+
+	rbacData := &fakeRbacData{
+		roles: map[int64]model.Role{
+			testSuperAdminRoleId: {Id: testSuperAdminRoleId, Name: model.RoleSuperAdmin},
+			testModeratorRoleId:  {Id: testModeratorRoleId, Name: "moderator", Permissions: []string{model.PermUserBan, model.PermUserResetPassword}},
+			testEditorRoleId:     {Id: testEditorRoleId, Name: "editor", Permissions: []string{model.PermArticleCreate}},
+		},
+		userRoles: map[int64][]int64{
+			testSuperAdminId: {testSuperAdminRoleId},
+			testModeratorId:  {testModeratorRoleId},
+			testEditorId:     {testEditorRoleId},
+		},
+		activeSuperAdmins: activeSuperAdmins,
+	}
+
+	userData := newFakeUserData(
+		model.User{Id: testSuperAdminId, Name: "admin", Email: "admin@example.com"},
+		model.User{Id: testModeratorId, Name: "moderator", Email: "moderator@example.com"},
+		model.User{Id: testEditorId, Name: "editor", Email: "editor@example.com"},
+		model.User{Id: testNormalUserId, Name: "user", Email: "user@example.com"},
+	)
+
+	userService := service.NewUser(userData, nil, nil, service.NewRbac(rbacData, nil),
+		model.PasswordResetConfig{}, model.LoginGuardConfig{}, nil)
+	return userService, userData, rbacData
+}
+
+func TestUserBanRoleHierarchy(t *testing.T) {
+	tests := []struct {
+		name       string
+		operatorId int64
+		userId     int64
+		want       error
+	}{
+		{"ban user without roles", testModeratorId, testNormalUserId, nil},
+		{"ban super admin", testModeratorId, testSuperAdminId, apierr.ErrRoleGrantDenied},
+		{"ban role with permissions the operator lacks", testModeratorId, testEditorId, apierr.ErrRoleGrantDenied},
+		{"super admin bans editor", testSuperAdminId, testEditorId, nil},
+		{"ban self", testModeratorId, testModeratorId, apierr.ErrUserBanSelf},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService, userData, _ := newManageUserService(t, 2)
+
+			if err := userService.Ban(tt.operatorId, tt.userId, "spam", 0); err != tt.want {
+				t.Fatalf("Ban() error = %v, want %v", err, tt.want)
+			}
+			if banned := len(userData.updates[tt.userId]) > 0; banned != (tt.want == nil) {
+				t.Errorf("Ban() updated user = %v, want %v", banned, tt.want == nil)
+			}
+		})
+	}
+}
+
+func TestUserBanLastSuperAdmin(t *testing.T) {
+	// 另一个超级管理员已被封禁或注销，被封禁的是最后一个可用的超级管理员
+	userService, userData, rbacData := newManageUserService(t, 1)
+
+	target := model.User{Id: 5, Name: "admin2", Email: "admin2@example.com"}
+	userData.users[target.Id] = &target
+	rbacData.userRoles[target.Id] = []int64{testSuperAdminRoleId}
+
+	if err := userService.Ban(testSuperAdminId, target.Id, "spam", 0); err != apierr.ErrRoleLastSuperAdmin {
+		t.Fatalf("Ban() error = %v, want ErrRoleLastSuperAdmin", err)
+	}
+	if len(userData.updates[target.Id]) > 0 {
+		t.Error("Ban() updated the last super admin")
+	}
+}
+
+func TestUserForceResetPasswordRoleHierarchy(t *testing.T) {
+	tests := []struct {
+		name       string
+		operatorId int64
+		userId     int64
+		want       error
+	}{
+		{"reset super admin", testModeratorId, testSuperAdminId, apierr.ErrRoleGrantDenied},
+		{"reset role with permissions the operator lacks", testModeratorId, testEditorId, apierr.ErrRoleGrantDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userService, userData, _ := newManageUserService(t, 2)
+
+			if err := userService.ForceResetPassword(tt.operatorId, tt.userId); err != tt.want {
+				t.Fatalf("ForceResetPassword() error = %v, want %v", err, tt.want)
+			}
+			if len(userData.updates[tt.userId]) > 0 {
+				t.Error("ForceResetPassword() cleared the password")
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package transform
 
 import (
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
 	"github.com/mittacy/blogBack/app/model"
@@ -28,6 +29,14 @@ func (ctl *Article) ArticlePack(data *model.Article) (*articleValidator.GetReply
 		return nil, err
 	}
 
+	// 目录以json保存
+	reply.Toc = []articleValidator.TocReply{}
+	if data.Toc != "" {
+		if err := json.Unmarshal([]byte(data.Toc), &reply.Toc); err != nil {
+			return nil, err
+		}
+	}
+
 	return &reply, nil
 }
 
@@ -40,6 +49,18 @@ func (ctl *Article) ArticlesPack(data []model.Article) (reply []articleValidator
 	return
 }
 
+func (ctl *Article) SearchArticlesPack(data []model.Article) (reply []articleValidator.SearchReply, err error) {
+	reply = []articleValidator.SearchReply{}
+	err = copier.Copy(&reply, &data)
+	return
+}
+
+func (ctl *Article) AdminArticlesPack(data []model.Article) (reply []articleValidator.AdminListReply, err error) {
+	reply = []articleValidator.AdminListReply{}
+	err = copier.Copy(&reply, &data)
+	return
+}
+
 func (ctl *Article) HomeArticlesPack(data []model.Article) (reply []articleValidator.ListHomeReply, err error) {
 	err = copier.Copy(&reply, &data)
 	return
@@ -95,3 +116,69 @@ func (ctl *Article) HomeListReply(c *gin.Context, articles []model.Article) {
 	return
 }
 
+
+// SearchReply 搜索结果响应包装
+// @param data 数据库列表数据
+// @param totalSize 命中的记录总数
+func (ctl *Article) SearchReply(c *gin.Context, data []model.Article, totalSize int64) {
+	list, err := ctl.SearchArticlesPack(data)
+	if err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	res := map[string]interface{}{
+		"list":       list,
+		"total_size": totalSize,
+	}
+
+	response.Success(c, res)
+}
+
+// AdminGetReply 管理员查看文章详情响应包装
+// @param data 数据库数据
+func (ctl *Article) AdminGetReply(c *gin.Context, data *model.Article) {
+	reply := articleValidator.AdminGetReply{}
+	if err := copier.Copy(&reply, data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	response.Success(c, map[string]interface{}{"article": reply})
+}
+
+// AdminListReply 管理员文章列表响应包装
+// @param data 数据库列表数据
+// @param totalSize 记录总数
+func (ctl *Article) AdminListReply(c *gin.Context, data []model.Article, totalSize int64) {
+	list, err := ctl.AdminArticlesPack(data)
+	if err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	res := map[string]interface{}{
+		"list":       list,
+		"total_size": totalSize,
+	}
+
+	response.Success(c, res)
+}
+
+// RevisionListReply 文章历史版本列表响应包装
+// @param data 数据库列表数据
+// @param totalSize 记录总数
+func (ctl *Article) RevisionListReply(c *gin.Context, data []model.ArticleRevision, totalSize int64) {
+	list := []articleValidator.RevisionListReply{}
+	if err := copier.Copy(&list, &data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	res := map[string]interface{}{
+		"list":       list,
+		"total_size": totalSize,
+	}
+
+	response.Success(c, res)
+}
@@ -0,0 +1,100 @@
+package transform
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/auditValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Audit struct {
+	logger *logger.CustomLogger
+}
+
+func NewAudit(customLogger *logger.CustomLogger) Audit {
+	return Audit{logger: customLogger}
+}
+
+// auditCsvHeader 导出的csv表头
+var auditCsvHeader = []string{"id", "时间", "操作人id", "操作", "资源", "请求方法", "请求路径", "操作对象id", "ip", "操作前", "操作后"}
+
+// ListReply 审计日志列表响应包装
+// @param data 数据库列表数据
+// @param totalSize 记录总数
+func (ctl *Audit) ListReply(c *gin.Context, data []model.AuditLog, totalSize int64) {
+	list := []auditValidator.ListReply{}
+	if err := copier.Copy(&list, &data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+	for i := range list {
+		list[i].Before = snapshotJson(data[i].Before)
+		list[i].After = snapshotJson(data[i].After)
+	}
+
+	res := map[string]interface{}{
+		"list":       list,
+		"total_size": totalSize,
+	}
+
+	response.Success(c, res)
+}
+
+// CsvWriter 写入csv下载的响应头和表头，返回用于写入数据行的csv.Writer
+// @param filename 下载的文件名
+// @return *csv.Writer
+func (ctl *Audit) CsvWriter(c *gin.Context, filename string) *csv.Writer {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// 写入BOM，Excel打开时才能正确识别utf-8编码
+	_, _ = c.Writer.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(auditCsvHeader)
+	return w
+}
+
+// CsvRows 将一批审计日志写入csv
+// @param w CsvWriter返回的csv.Writer
+// @param data 数据库列表数据
+// @return error
+func (ctl *Audit) CsvRows(w *csv.Writer, data []model.AuditLog) error {
+	for _, v := range data {
+		row := []string{
+			strconv.FormatInt(v.Id, 10),
+			time.Unix(v.CreatedAt, 0).Format("2006-01-02 15:04:05"),
+			strconv.FormatInt(v.ActorId, 10),
+			v.Action,
+			v.Resource,
+			v.Method,
+			v.Path,
+			strconv.FormatInt(v.TargetId, 10),
+			v.Ip,
+			v.Before,
+			v.After,
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+// snapshotJson 快照为空时返回null
+func snapshotJson(snapshot string) json.RawMessage {
+	if snapshot == "" {
+		return nil
+	}
+	return json.RawMessage(snapshot)
+}
@@ -0,0 +1,48 @@
+package transform
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/commentValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+)
+
+type Comment struct {
+	logger *logger.CustomLogger
+}
+
+func NewComment(customLogger *logger.CustomLogger) Comment {
+	return Comment{logger: customLogger}
+}
+
+// CommentsPack 数据库数据转化为响应数据
+// @param data 数据库数据
+// @return reply 响应体数据
+// @return err
+func (ctl *Comment) CommentsPack(data []model.Comment) (reply []commentValidator.ListReply, err error) {
+	reply = []commentValidator.ListReply{}
+	err = copier.Copy(&reply, &data)
+	return
+}
+
+// ListReply 列表响应包装
+// @param data 数据库列表数据
+// @param totalSize 顶级评论总数
+// @param commentCount 文章的评论总数
+func (ctl *Comment) ListReply(c *gin.Context, data []model.Comment, totalSize, commentCount int64) {
+	list, err := ctl.CommentsPack(data)
+	if err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	res := map[string]interface{}{
+		"list":          list,
+		"total_size":    totalSize,
+		"comment_count": commentCount,
+	}
+
+	response.Success(c, res)
+}
@@ -0,0 +1,42 @@
+package transform
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/rbacValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+)
+
+type Rbac struct {
+	logger *logger.CustomLogger
+}
+
+func NewRbac(customLogger *logger.CustomLogger) Rbac {
+	return Rbac{logger: customLogger}
+}
+
+// RolesReply 角色列表响应包装
+// @param data 角色列表
+func (ctl *Rbac) RolesReply(c *gin.Context, data []model.Role) {
+	list := []rbacValidator.RoleReply{}
+	if err := copier.Copy(&list, &data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	response.Success(c, map[string]interface{}{"list": list})
+}
+
+// PermissionsReply 权限列表响应包装
+// @param data 权限列表
+func (ctl *Rbac) PermissionsReply(c *gin.Context, data []model.Permission) {
+	list := []rbacValidator.PermissionReply{}
+	if err := copier.Copy(&list, &data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	response.Success(c, map[string]interface{}{"list": list})
+}
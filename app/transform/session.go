@@ -0,0 +1,52 @@
+package transform
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/validator/sessionValidator"
+	"github.com/mittacy/blogBack/pkg/jwt"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+	"time"
+)
+
+type Session struct {
+	logger *logger.CustomLogger
+}
+
+func NewSession(customLogger *logger.CustomLogger) Session {
+	return Session{logger: customLogger}
+}
+
+// TokenReply 登录、刷新token响应包装，同时将访问token写入cookie
+// @param data 访问token和刷新token
+func (ctl *Session) TokenReply(c *gin.Context, data *jwt.TokenPair) {
+	reply := sessionValidator.TokenReply{}
+	if err := copier.Copy(&reply, data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	maxAge := int(time.Until(time.Unix(data.AccessExpireAt, 0)) / time.Second)
+	c.SetCookie("access_token", data.AccessToken, maxAge, "/", "", false, true)
+
+	response.Success(c, reply)
+}
+
+// SessionsReply 会话列表响应包装
+// @param data 会话列表
+// @param currentId 当前请求所属的会话id
+func (ctl *Session) SessionsReply(c *gin.Context, data []jwt.Session, currentId string) {
+	list := make([]sessionValidator.SessionReply, 0, len(data))
+	for _, v := range data {
+		reply := sessionValidator.SessionReply{}
+		if err := copier.Copy(&reply, &v); err != nil {
+			response.CopierErrAndLog(c, ctl.logger, err)
+			return
+		}
+		reply.Current = v.Id == currentId
+		list = append(list, reply)
+	}
+
+	response.Success(c, map[string]interface{}{"list": list})
+}
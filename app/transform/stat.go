@@ -0,0 +1,30 @@
+package transform
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/statValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+)
+
+type Stat struct {
+	logger *logger.CustomLogger
+}
+
+func NewStat(customLogger *logger.CustomLogger) Stat {
+	return Stat{logger: customLogger}
+}
+
+// DailyReply 每日统计响应包装
+// @param data 每日统计数据
+func (ctl *Stat) DailyReply(c *gin.Context, data []model.DailyStat) {
+	list := []statValidator.DailyReply{}
+	if err := copier.Copy(&list, &data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	response.Success(c, map[string]interface{}{"list": list})
+}
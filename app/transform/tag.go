@@ -0,0 +1,46 @@
+package transform
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/tagValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+)
+
+type Tag struct {
+	logger *logger.CustomLogger
+}
+
+func NewTag(customLogger *logger.CustomLogger) Tag {
+	return Tag{logger: customLogger}
+}
+
+// TagsPack 数据库数据转化为响应数据
+// @param data 数据库数据
+// @return reply 响应体数据
+// @return err
+func (ctl *Tag) TagsPack(data []model.Tag) (reply []tagValidator.ListReply, err error) {
+	reply = []tagValidator.ListReply{}
+	err = copier.Copy(&reply, &data)
+	return
+}
+
+// ListReply 列表响应包装
+// @param data 数据库列表数据
+// @param totalSize 记录总数
+func (ctl *Tag) ListReply(c *gin.Context, data []model.Tag, totalSize int64) {
+	list, err := ctl.TagsPack(data)
+	if err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	res := map[string]interface{}{
+		"list":       list,
+		"total_size": totalSize,
+	}
+
+	response.Success(c, res)
+}
@@ -0,0 +1,30 @@
+package transform
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/copier"
+	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/uploadValidator"
+	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/response"
+)
+
+type Upload struct {
+	logger *logger.CustomLogger
+}
+
+func NewUpload(customLogger *logger.CustomLogger) Upload {
+	return Upload{logger: customLogger}
+}
+
+// UploadReply 上传文件响应包装
+// @param data 上传的文件
+func (ctl *Upload) UploadReply(c *gin.Context, data *model.Upload) {
+	reply := uploadValidator.UploadReply{}
+	if err := copier.Copy(&reply, data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	response.Success(c, reply)
+}
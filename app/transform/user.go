@@ -4,20 +4,27 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
 	"github.com/mittacy/blogBack/app/model"
+	"github.com/mittacy/blogBack/app/validator/rbacValidator"
 	"github.com/mittacy/blogBack/app/validator/userValidator"
 	"github.com/mittacy/blogBack/pkg/logger"
 	"github.com/mittacy/blogBack/pkg/response"
 )
 
+// IAuthorizer 权限校验，用于判断调用者能否查看用户的隐私信息
+type IAuthorizer interface {
+	HasPermission(userId int64, permission string) bool
+}
+
 type User struct {
-	logger *logger.CustomLogger
+	authorizer IAuthorizer
+	logger     *logger.CustomLogger
 }
 
-func NewUser(customLogger *logger.CustomLogger) User {
-	return User{logger: customLogger}
+func NewUser(authorizer IAuthorizer, customLogger *logger.CustomLogger) User {
+	return User{authorizer: authorizer, logger: customLogger}
 }
 
-// UserPack 数据库数据转化为响应数据
+// UserPack 数据库数据转化为本人和管理员查看的响应数据
 // @param data 数据库数据
 // @return reply 响应体数据
 // @return err
@@ -28,12 +35,55 @@ func (ctl *User) UserPack(data *model.User) (*userValidator.GetReply, error) {
 		return nil, err
 	}
 
+	reply.Privacy = userValidator.PrivacyReply{
+		HideGender:    data.Hides(model.UserPrivacyHideGender),
+		HideIntroduce: data.Hides(model.UserPrivacyHideIntroduce),
+		HideGithub:    data.Hides(model.UserPrivacyHideGithub),
+		HideCounts:    data.Hides(model.UserPrivacyHideCounts),
+	}
+
 	return &reply, nil
 }
 
-// GetReply 详情响应包装
+// PublicPack 数据库数据转化为其他用户查看的公开数据，按用户的隐私设置隐藏字段
+// @param data 数据库数据
+// @return *userValidator.PublicReply
+func (ctl *User) PublicPack(data *model.User) *userValidator.PublicReply {
+	reply := userValidator.PublicReply{
+		Id:        data.Id,
+		Name:      data.Name,
+		Avatar:    data.Avatar,
+		CreatedAt: data.CreatedAt,
+	}
+
+	if !data.Hides(model.UserPrivacyHideGender) {
+		reply.Gender = data.Gender
+	}
+	if !data.Hides(model.UserPrivacyHideIntroduce) {
+		reply.Introduce = data.Introduce
+	}
+	if !data.Hides(model.UserPrivacyHideGithub) {
+		reply.Github = data.Github
+	}
+	if !data.Hides(model.UserPrivacyHideCounts) {
+		reply.ArticleCount = &data.ArticleCount
+		reply.CommentCount = &data.CommentCount
+	}
+
+	return &reply
+}
+
+// GetReply 详情响应包装，根据调用者的token选择返回的信息:
+// 本人和拥有model.PermUserViewPrivate权限的管理员返回完整信息，其他调用者只返回公开信息
 // @param data 数据库数据
 func (ctl *User) GetReply(c *gin.Context, data *model.User) {
+	if !ctl.canViewPrivate(c.GetInt64("userId"), data.Id) {
+		response.Success(c, map[string]interface{}{
+			"user": ctl.PublicPack(data),
+		})
+		return
+	}
+
 	reply, err := ctl.UserPack(data)
 	if err != nil {
 		response.CopierErrAndLog(c, ctl.logger, err)
@@ -46,3 +96,63 @@ func (ctl *User) GetReply(c *gin.Context, data *model.User) {
 
 	response.Success(c, res)
 }
+
+// AdminGetReply 管理员查看用户详情响应包装
+// @param data 数据库数据
+// @param roles 用户的角色
+func (ctl *User) AdminGetReply(c *gin.Context, data *model.User, roles []model.Role) {
+	reply, err := ctl.UserPack(data)
+	if err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	adminReply := userValidator.AdminGetReply{
+		GetReply:    *reply,
+		Banned:      data.IsBanned(),
+		BannedAt:    data.BannedAt,
+		BanExpireAt: data.BanExpireAt,
+		BanReason:   data.BanReason,
+		DeletedAt:   data.DeletedAt,
+		Roles:       []rbacValidator.RoleReply{},
+	}
+	if err := copier.Copy(&adminReply.Roles, &roles); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+
+	response.Success(c, map[string]interface{}{"user": adminReply})
+}
+
+// AdminListReply 管理员用户列表响应包装
+// @param data 数据库列表数据
+// @param totalSize 记录总数
+func (ctl *User) AdminListReply(c *gin.Context, data []model.User, totalSize int64) {
+	list := []userValidator.AdminListReply{}
+	if err := copier.Copy(&list, &data); err != nil {
+		response.CopierErrAndLog(c, ctl.logger, err)
+		return
+	}
+	for i := range list {
+		list[i].Banned = data[i].IsBanned()
+	}
+
+	res := map[string]interface{}{
+		"list":       list,
+		"total_size": totalSize,
+	}
+
+	response.Success(c, res)
+}
+
+// canViewPrivate 调用者能否查看用户的隐私信息
+// @param viewerId 调用者的用户id，未登录为0
+// @param userId 查看的用户id
+// @return bool
+func (ctl *User) canViewPrivate(viewerId, userId int64) bool {
+	if viewerId <= 0 {
+		return false
+	}
+
+	return viewerId == userId || ctl.authorizer.HasPermission(viewerId, model.PermUserViewPrivate)
+}
@@ -1,22 +1,26 @@
 package articleValidator
 
 type CreateReq struct {
-	CategoryId int64  `json:"category_id" binding:"required,min=1"`
-	Title      string `json:"title" binding:"required,min=1,max=64"`
-	PreviewCtx string `json:"preview_ctx" binding:"required,min=1,max=1024"`
-	Content    string `json:"content" binding:"required,min=1"`
+	CategoryId int64   `json:"category_id" binding:"required,min=1"`
+	Title      string  `json:"title" binding:"required,min=1,max=64"`
+	PreviewCtx string  `json:"preview_ctx" binding:"required,min=1,max=1024"`
+	Content    string  `json:"content" binding:"required,min=1"`
+	TagIds     []int64 `json:"tag_ids" binding:"omitempty,max=10,dive,min=1"`
+	Status     int8    `json:"status" binding:"omitempty,oneof=1 2 3"`
+	PublishAt  int64   `json:"publish_at" binding:"omitempty,min=0"`
 }
 
 type UpdateReq struct {
-	UpdateType int `json:"update_type" binding:"required,oneof=1 2"`
+	UpdateType int `json:"update_type" binding:"required,oneof=1 2 3"`
 }
 
 type UpdateInfoReq struct {
-	Id         int64  `json:"id" binding:"required,min=1"`
-	CategoryId int64  `json:"category_id" binding:"required,min=1"`
-	Title      string `json:"title" binding:"required,min=1,max=64"`
-	PreviewCtx string `json:"preview_ctx" binding:"required,min=1,max=1024"`
-	Content    string `json:"content" binding:"required,min=1"`
+	Id         int64   `json:"id" binding:"required,min=1"`
+	CategoryId int64   `json:"category_id" binding:"required,min=1"`
+	Title      string  `json:"title" binding:"required,min=1,max=64"`
+	PreviewCtx string  `json:"preview_ctx" binding:"required,min=1,max=1024"`
+	Content    string  `json:"content" binding:"required,min=1"`
+	TagIds     []int64 `json:"tag_ids" binding:"omitempty,max=10,dive,min=1"`
 }
 
 type UpdateWeightReq struct {
@@ -24,33 +28,55 @@ type UpdateWeightReq struct {
 	Weight int64 `json:"weight" binding:"omitempty,min=0"`
 }
 
+type TagReply struct {
+	Id   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type UpdateStatusReq struct {
+	Id        int64 `json:"id" binding:"required,min=1"`
+	Status    int8  `json:"status" binding:"required,oneof=1 2 3 4"`
+	PublishAt int64 `json:"publish_at" binding:"omitempty,min=0"`
+}
+
 type GetReply struct {
-	Id           int64  `json:"id"`
-	CategoryId   int64  `json:"category_id"`
-	CategoryName string `json:"category_name"`
-	Title        string `json:"title"`
-	Views        int64  `json:"views"`
-	CreatedAt    int64  `json:"created_at"`
-	UpdatedAt    int64  `json:"updated_at"`
-	Content      string `json:"content"`
-	Picture      string `json:"picture"`
-	Sentence     string `json:"sentence"`
+	Id           int64      `json:"id"`
+	CategoryId   int64      `json:"category_id"`
+	CategoryName string     `json:"category_name"`
+	Tags         []TagReply `json:"tags"`
+	Title        string     `json:"title"`
+	Views        int64      `json:"views"`
+	CreatedAt    int64      `json:"created_at"`
+	UpdatedAt    int64      `json:"updated_at"`
+	Content      string     `json:"content"`
+	ContentHtml  string     `json:"content_html"`
+	Toc          []TocReply `json:"toc" copier:"-"`
+	Picture      string     `json:"picture"`
+	Sentence     string     `json:"sentence"`
+}
+
+type TocReply struct {
+	Level int    `json:"level"`
+	Id    string `json:"id"`
+	Title string `json:"title"`
 }
 
 type ListReq struct {
 	Page       int   `form:"page" json:"page" binding:"omitempty,min=1"`
 	PageSize   int   `form:"page_size" json:"page_size" binding:"omitempty,min=1,max=50"`
 	CategoryId int64 `form:"category_id" json:"category_id" binding:"omitempty,min=1"`
+	Tag        int64 `form:"tag" json:"tag" binding:"omitempty,min=1"`
 }
 
 type ListReply struct {
-	Id           int64  `json:"id"`
-	CategoryId   int64  `json:"category_id"`
-	CategoryName string `json:"category_name"`
-	Title        string `json:"title"`
-	Views        int64  `json:"views"`
-	CreatedAt    int64  `json:"created_at"`
-	UpdatedAt    int64  `json:"updated_at"`
+	Id           int64      `json:"id"`
+	CategoryId   int64      `json:"category_id"`
+	CategoryName string     `json:"category_name"`
+	Tags         []TagReply `json:"tags"`
+	Title        string     `json:"title"`
+	Views        int64      `json:"views"`
+	CreatedAt    int64      `json:"created_at"`
+	UpdatedAt    int64      `json:"updated_at"`
 }
 
 type ListHomeReply struct {
@@ -63,3 +89,79 @@ type ListHomeReply struct {
 	CreatedAt    int64  `json:"created_at"`
 	UpdatedAt    int64  `json:"updated_at"`
 }
+
+type SearchReq struct {
+	Q        string `form:"q" json:"q" binding:"required,min=1,max=64"`
+	Page     int    `form:"page" json:"page" binding:"omitempty,min=1"`
+	PageSize int    `form:"page_size" json:"page_size" binding:"omitempty,min=1,max=50"`
+}
+
+type SearchReply struct {
+	Id             int64  `json:"id"`
+	CategoryId     int64  `json:"category_id"`
+	CategoryName   string `json:"category_name"`
+	Title          string `json:"title"`
+	TitleHighlight string `json:"title_highlight"`
+	Snippet        string `json:"snippet"`
+	Views          int64  `json:"views"`
+	CreatedAt      int64  `json:"created_at"`
+	UpdatedAt      int64  `json:"updated_at"`
+}
+
+type AdminListReq struct {
+	Page     int  `form:"page" json:"page" binding:"required,min=1"`
+	PageSize int  `form:"page_size" json:"page_size" binding:"required,min=1,max=50"`
+	Status   int8 `form:"status" json:"status" binding:"omitempty,oneof=1 2 3 4"`
+}
+
+type AdminGetReply struct {
+	Id           int64      `json:"id"`
+	Weight       int64      `json:"weight"`
+	CategoryId   int64      `json:"category_id"`
+	CategoryName string     `json:"category_name"`
+	Tags         []TagReply `json:"tags"`
+	Title        string     `json:"title"`
+	Views        int64      `json:"views"`
+	PreviewCtx   string     `json:"preview_ctx"`
+	Status       int8       `json:"status"`
+	PublishAt    int64      `json:"publish_at"`
+	CreatedAt    int64      `json:"created_at"`
+	UpdatedAt    int64      `json:"updated_at"`
+	Content      string     `json:"content"`
+	Picture      string     `json:"picture"`
+	Sentence     string     `json:"sentence"`
+}
+
+type AdminListReply struct {
+	Id           int64      `json:"id"`
+	CategoryId   int64      `json:"category_id"`
+	CategoryName string     `json:"category_name"`
+	Tags         []TagReply `json:"tags"`
+	Title        string     `json:"title"`
+	Views        int64      `json:"views"`
+	Status       int8       `json:"status"`
+	PublishAt    int64      `json:"publish_at"`
+	CreatedAt    int64      `json:"created_at"`
+	UpdatedAt    int64      `json:"updated_at"`
+}
+
+type RevisionListReq struct {
+	Page     int `form:"page" json:"page" binding:"required,min=1"`
+	PageSize int `form:"page_size" json:"page_size" binding:"required,min=1,max=50"`
+}
+
+type RevisionListReply struct {
+	Id        int64  `json:"id"`
+	ArticleId int64  `json:"article_id"`
+	Title     string `json:"title"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type RevisionDiffReq struct {
+	From int64 `form:"from" json:"from" binding:"omitempty,min=0"`
+	To   int64 `form:"to" json:"to" binding:"omitempty,min=0"`
+}
+
+type RevisionRestoreReq struct {
+	RevisionId int64 `json:"revision_id" binding:"required,min=1"`
+}
@@ -0,0 +1,31 @@
+package auditValidator
+
+import "encoding/json"
+
+type ListReq struct {
+	Page     int `form:"page" json:"page" binding:"required,min=1"`
+	PageSize int `form:"page_size" json:"page_size" binding:"required,min=1,max=50"`
+}
+
+type FilterReq struct {
+	ActorId     int64  `form:"actor_id" json:"actor_id" binding:"omitempty,min=1"`
+	Action      string `form:"action" json:"action" binding:"omitempty,max=64"`
+	Resource    string `form:"resource" json:"resource" binding:"omitempty,max=32"`
+	TargetId    int64  `form:"target_id" json:"target_id" binding:"omitempty,min=1"`
+	CreatedFrom int64  `form:"created_from" json:"created_from" binding:"omitempty,min=1"`
+	CreatedTo   int64  `form:"created_to" json:"created_to" binding:"omitempty,min=1"`
+}
+
+type ListReply struct {
+	Id        int64           `json:"id"`
+	ActorId   int64           `json:"actor_id"`
+	Action    string          `json:"action"`
+	Resource  string          `json:"resource"`
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	TargetId  int64           `json:"target_id"`
+	Before    json.RawMessage `json:"before" copier:"-"`
+	After     json.RawMessage `json:"after" copier:"-"`
+	Ip        string          `json:"ip"`
+	CreatedAt int64           `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package commentValidator
+
+type CreateReq struct {
+	ParentId int64  `json:"parent_id" binding:"omitempty,min=1"`
+	Content  string `json:"content" binding:"required,min=1,max=1024"`
+}
+
+type UpdateReq struct {
+	UpdateType int `json:"update_type" binding:"required,oneof=1"`
+}
+
+type UpdateStatusReq struct {
+	Id     int64 `json:"id" binding:"required,min=1"`
+	Status int8  `json:"status" binding:"required,oneof=1 2"`
+}
+
+type ListReq struct {
+	Page     int `form:"page" json:"page" binding:"required,min=1"`
+	PageSize int `form:"page_size" json:"page_size" binding:"required,min=1,max=50"`
+}
+
+type ReplyReply struct {
+	Id        int64  `json:"id"`
+	UserId    int64  `json:"user_id"`
+	UserName  string `json:"user_name"`
+	RootId    int64  `json:"root_id"`
+	ParentId  int64  `json:"parent_id"`
+	Content   string `json:"content"`
+	Status    int8   `json:"status"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type ListReply struct {
+	Id        int64        `json:"id"`
+	UserId    int64        `json:"user_id"`
+	UserName  string       `json:"user_name"`
+	Content   string       `json:"content"`
+	Status    int8         `json:"status"`
+	CreatedAt int64        `json:"created_at"`
+	Replies   []ReplyReply `json:"replies"`
+}
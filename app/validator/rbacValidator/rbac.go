@@ -0,0 +1,38 @@
+package rbacValidator
+
+type CreateRoleReq struct {
+	Name        string   `json:"name" binding:"required,min=1,max=32"`
+	Description string   `json:"description" binding:"omitempty,max=255"`
+	Permissions []string `json:"permissions" binding:"omitempty,dive,min=1,max=64"`
+}
+
+type UpdateRoleReq struct {
+	Id          int64    `json:"id" binding:"required,min=1"`
+	Name        string   `json:"name" binding:"required,min=1,max=32"`
+	Description string   `json:"description" binding:"omitempty,max=255"`
+	Permissions []string `json:"permissions" binding:"omitempty,dive,min=1,max=64"`
+}
+
+type UserRolesReq struct {
+	UserId int64 `form:"user_id" json:"user_id" binding:"required,min=1"`
+}
+
+type SetUserRolesReq struct {
+	UserId  int64   `json:"user_id" binding:"required,min=1"`
+	RoleIds []int64 `json:"role_ids" binding:"omitempty,dive,min=1"`
+}
+
+type RoleReply struct {
+	Id          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at"`
+}
+
+type PermissionReply struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,21 @@
+package sessionValidator
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required,max=128"`
+}
+
+type TokenReply struct {
+	AccessToken     string `json:"token"`
+	AccessExpireAt  int64  `json:"expire_at"`
+	RefreshToken    string `json:"refresh_token"`
+	RefreshExpireAt int64  `json:"refresh_expire_at"`
+}
+
+type SessionReply struct {
+	Id         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	Ip         string `json:"ip"`
+	CreatedAt  int64  `json:"created_at"`
+	LastUsedAt int64  `json:"last_used_at"`
+	Current    bool   `json:"current" copier:"-"`
+}
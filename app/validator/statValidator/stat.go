@@ -0,0 +1,12 @@
+package statValidator
+
+type DailyReq struct {
+	StartDate string `form:"start_date" json:"start_date" binding:"omitempty,datetime=2006-01-02"`
+	EndDate   string `form:"end_date" json:"end_date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+type DailyReply struct {
+	Date string `json:"date"`
+	PV   int64  `json:"pv"`
+	UV   int64  `json:"uv"`
+}
@@ -0,0 +1,11 @@
+package uploadValidator
+
+type UploadReply struct {
+	Id       int64  `json:"id"`
+	Hash     string `json:"hash"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+	Size     int64  `json:"size"`
+	Url      string `json:"url"`
+	ThumbUrl string `json:"thumb_url"`
+}
@@ -1,5 +1,7 @@
 package userValidator
 
+import "github.com/mittacy/blogBack/app/validator/rbacValidator"
+
 type RegisterReq struct {
 	Name      string `json:"name" binding:"required,min=1,max=10"`
 	Password  string `json:"password" binding:"required,min=8,max=20"`
@@ -17,15 +19,121 @@ type LoginReq struct {
 	Password  string `json:"password" binding:"required,min=8,max=20"`
 }
 
+type ForgotPasswordReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordReq struct {
+	Token    string `json:"token" binding:"required,max=128"`
+	Password string `json:"password" binding:"required,min=8,max=20"`
+}
+
+type UpdateProfileReq struct {
+	Gender    int8   `json:"gender" binding:"required,oneof=1 5 10"`
+	Introduce string `json:"introduce" binding:"omitempty,max=255"`
+	Github    string `json:"github" binding:"required,url"`
+	Avatar    string `json:"avatar" binding:"omitempty,url,max=255"`
+}
+
+type UpdatePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required,max=20"`
+	Password    string `json:"password" binding:"required,min=8,max=20"`
+}
+
+type DeleteReq struct {
+	Password string `json:"password" binding:"required,max=20"`
+}
+
+type ChangeEmailCodeReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ChangeEmailReq struct {
+	Email string `json:"email" binding:"required,email"`
+	Code  string `json:"code" binding:"required,len=6"`
+}
+
+type PrivacyReq struct {
+	HideGender    bool `json:"hide_gender"`
+	HideIntroduce bool `json:"hide_introduce"`
+	HideGithub    bool `json:"hide_github"`
+	HideCounts    bool `json:"hide_counts"`
+}
+
+type PrivacyReply struct {
+	HideGender    bool `json:"hide_gender"`
+	HideIntroduce bool `json:"hide_introduce"`
+	HideGithub    bool `json:"hide_github"`
+	HideCounts    bool `json:"hide_counts"`
+}
+
+// GetReply 本人和管理员查看的用户信息
 type GetReply struct {
-	Id        int64  `json:"id"`
-	Name      string `json:"name"`
-	Gender    int8   `json:"gender"`
-	Introduce string `json:"introduce"`
-	Github    string `json:"github"`
-	Email     string `json:"email"`
-	Views     int64  `json:"views"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
-	LoginAt   int64  `json:"login_at"`
+	Id           int64        `json:"id"`
+	Name         string       `json:"name"`
+	Gender       int8         `json:"gender"`
+	Introduce    string       `json:"introduce"`
+	Github       string       `json:"github"`
+	Avatar       string       `json:"avatar"`
+	Email        string       `json:"email"`
+	Views        int64        `json:"views"`
+	ArticleCount int64        `json:"article_count"`
+	CommentCount int64        `json:"comment_count"`
+	CreatedAt    int64        `json:"created_at"`
+	UpdatedAt    int64        `json:"updated_at"`
+	LoginAt      int64        `json:"login_at"`
+	Privacy      PrivacyReply `json:"privacy" copier:"-"`
+}
+
+// PublicReply 其他用户查看的公开信息，被隐私设置隐藏的字段不返回
+type PublicReply struct {
+	Id           int64  `json:"id"`
+	Name         string `json:"name"`
+	Gender       int8   `json:"gender,omitempty"`
+	Introduce    string `json:"introduce,omitempty"`
+	Github       string `json:"github,omitempty"`
+	Avatar       string `json:"avatar"`
+	ArticleCount *int64 `json:"article_count,omitempty"`
+	CommentCount *int64 `json:"comment_count,omitempty"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+type AdminListReq struct {
+	Page        int    `form:"page" json:"page" binding:"required,min=1"`
+	PageSize    int    `form:"page_size" json:"page_size" binding:"required,min=1,max=50"`
+	Keyword     string `form:"keyword" json:"keyword" binding:"omitempty,max=64"`
+	Status      int8   `form:"status" json:"status" binding:"omitempty,oneof=1 2 3"`
+	CreatedFrom int64  `form:"created_from" json:"created_from" binding:"omitempty,min=1"`
+	CreatedTo   int64  `form:"created_to" json:"created_to" binding:"omitempty,min=1"`
+	LoginFrom   int64  `form:"login_from" json:"login_from" binding:"omitempty,min=1"`
+	LoginTo     int64  `form:"login_to" json:"login_to" binding:"omitempty,min=1"`
+}
+
+type BanReq struct {
+	Reason   string `json:"reason" binding:"required,min=1,max=255"`
+	ExpireAt int64  `json:"expire_at" binding:"omitempty,min=1"`
+}
+
+// AdminListReply 管理员查看的用户列表信息
+type AdminListReply struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Avatar      string `json:"avatar"`
+	Banned      bool   `json:"banned" copier:"-"`
+	BanExpireAt int64  `json:"ban_expire_at"`
+	CreatedAt   int64  `json:"created_at"`
+	LoginAt     int64  `json:"login_at"`
+	DeletedAt   int64  `json:"deleted_at"`
+}
+
+// AdminGetReply 管理员查看的用户详情，包括封禁信息和角色
+type AdminGetReply struct {
+	GetReply
+	Banned      bool                      `json:"banned"`
+	BannedAt    int64                     `json:"banned_at"`
+	BanExpireAt int64                     `json:"ban_expire_at"`
+	BanReason   string                    `json:"ban_reason"`
+	DeletedAt   int64                     `json:"deleted_at"`
+	Roles       []rbacValidator.RoleReply `json:"roles"`
 }
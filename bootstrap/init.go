@@ -6,6 +6,7 @@ import (
 	"github.com/mittacy/blogBack/pkg/config"
 	"github.com/mittacy/blogBack/pkg/jwt"
 	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/password"
 	"github.com/mittacy/blogBack/pkg/store/cache"
 	"go.uber.org/zap"
 )
@@ -31,4 +32,7 @@ func Init() {
 	// 5. 初始化token
 	tokenCache := cache.ConnCustomRedis("blog", "token")
 	jwt.InitToken(tokenCache)
+
+	// 6. 初始化密码哈希算法
+	password.Init()
 }
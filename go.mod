@@ -4,32 +4,36 @@ go 1.16
 
 require (
 	github.com/AlecAivazis/survey/v2 v2.2.16 // indirect
-	github.com/gin-contrib/zap v0.0.1 // indirect
-	github.com/gin-gonic/gin v1.7.2 // indirect
-	github.com/go-playground/locales v0.13.0 // indirect
-	github.com/go-playground/universal-translator v0.17.0 // indirect
+	github.com/alicebob/miniredis/v2 v2.30.4
+	github.com/gin-contrib/zap v0.0.1
+	github.com/gin-gonic/gin v1.7.2
+	github.com/go-playground/locales v0.13.0
+	github.com/go-playground/universal-translator v0.17.0
 	github.com/go-playground/validator v9.31.0+incompatible // indirect
-	github.com/go-playground/validator/v10 v10.8.0 // indirect
-	github.com/golang-jwt/jwt v3.2.2+incompatible // indirect
-	github.com/gomodule/redigo v1.8.5 // indirect
+	github.com/go-playground/validator/v10 v10.8.0
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/gomodule/redigo v1.8.5
 	github.com/google/wire v0.5.0 // indirect
-	github.com/jinzhu/copier v0.3.2 // indirect
-	github.com/lestrrat-go/file-rotatelogs v2.4.0+incompatible // indirect
+	github.com/jinzhu/copier v0.3.2
+	github.com/lestrrat-go/file-rotatelogs v2.4.0+incompatible
 	github.com/lestrrat-go/strftime v1.0.5 // indirect
 	github.com/mattn/go-isatty v0.0.13 // indirect
+	github.com/mattn/go-sqlite3 v1.14.6 // indirect
 	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d // indirect
 	github.com/mittacy/ego v0.0.4 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180306012644-bacd9c7ef1dd // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	github.com/spf13/viper v1.8.1 // indirect
+	github.com/pkg/errors v0.9.1
+	github.com/spf13/viper v1.8.1
 	github.com/ugorji/go v1.2.6 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.7.0 // indirect
-	go.uber.org/zap v1.18.1 // indirect
+	go.uber.org/zap v1.18.1
+	golang.org/x/crypto v0.0.0-20210711020723-a769d52b0f97
 	golang.org/x/sys v0.0.0-20210806184541-e5e7981a1069 // indirect
 	golang.org/x/term v0.0.0-20210615171337-6886f2dfbf5b // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
-	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df // indirect
-	gorm.io/driver/mysql v1.0.6 // indirect
-	gorm.io/gorm v1.21.9 // indirect
+	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
+	gorm.io/driver/mysql v1.0.6
+	gorm.io/driver/sqlite v1.1.4
+	gorm.io/gorm v1.21.9
 )
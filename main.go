@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/mittacy/blogBack/bootstrap"
+	"github.com/mittacy/blogBack/middleware"
 	"github.com/mittacy/blogBack/pkg/config"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/mittacy/blogBack/pkg/store/db"
 	"github.com/mittacy/blogBack/router"
 	"go.uber.org/zap"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 )
 
@@ -18,9 +26,30 @@ func init() {
 func main() {
 	r := gin.New()
 
+	// 启动异步写入审计日志的后台任务，退出时写入队列中剩余的日志
+	auditWriter := router.InitAuditWriter(db.ConnectGorm("blog"))
+	middleware.InitAuditor(auditWriter)
+	auditWriter.Start()
+	defer auditWriter.Stop()
+
 	// 初始化路由
 	router.InitRouter(r)
 
+	// 启动定时发布文章的后台任务
+	articleScheduler := router.InitArticleScheduler(db.ConnectGorm("blog"), cache.ConnRedis("blog"))
+	articleScheduler.Start()
+	defer articleScheduler.Stop()
+
+	// 启动阅读量写入数据库的后台任务，退出时写入剩余的阅读量
+	articleViewFlusher := router.InitArticleViewFlusher(db.ConnectGorm("blog"), cache.ConnRedis("blog"))
+	articleViewFlusher.Start()
+	defer articleViewFlusher.Stop()
+
+	// 启动清理未被文章引用的上传文件的后台任务
+	uploadCleaner := router.InitUploadCleaner(db.ConnectGorm("blog"))
+	uploadCleaner.Start()
+	defer uploadCleaner.Stop()
+
 	serverConfig := config.ServerConfig
 	s := &http.Server{
 		Addr: ":" + strconv.Itoa(serverConfig.Port),
@@ -32,5 +61,21 @@ func main() {
 
 	zap.S().Infof("监听端口:%d", serverConfig.Port)
 
-	s.ListenAndServe()
+	go func() {
+		if err := s.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zap.S().Fatalf("服务启动失败:%s", err)
+		}
+	}()
+
+	// 收到退出信号后停止接收新请求，等待处理中的请求完成，再停止后台任务
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	zap.S().Info("服务关闭中...")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		zap.S().Errorf("服务关闭失败:%s", err)
+	}
 }
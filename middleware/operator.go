@@ -1,38 +1,165 @@
 package middleware
 
 import (
+	"bytes"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
 	"github.com/mittacy/blogBack/app/model"
 	"github.com/mittacy/blogBack/pkg/response"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-const (
-	ActionAddCategory = iota
-	ActionPutCategory
-	ActionDeleteCategory
+// IAuthorizer 权限校验
+type IAuthorizer interface {
+	HasPermission(userId int64, permission string) bool
+}
+
+// IAuditor 记录管理员的写操作
+type IAuditor interface {
+	Snapshot(resource string, id int64) string
+	Record(log model.AuditLog)
+}
 
-	ActionAddArticle
-	ActionPutArticle
-	ActionDeleteArticle
+var (
+	authorizer IAuthorizer
+	auditor    IAuditor
 )
 
-func Operate(action int) gin.HandlerFunc {
+// InitAuthorizer 设置权限校验器，需要在注册路由前调用
+func InitAuthorizer(a IAuthorizer) {
+	authorizer = a
+}
+
+// InitAuditor 设置审计日志记录器，设置后Operate记录所有成功的写操作，需要在处理请求前调用
+func InitAuditor(a IAuditor) {
+	auditor = a
+}
+
+// Operate 校验当前登录账号是否拥有权限，需要在ParseToken之后使用
+// 非GET请求视为写操作，成功后记录审计日志
+// @param permission 权限名，如 model.PermArticleDelete
+func Operate(permission string) gin.HandlerFunc {
+	if authorizer == nil {
+		panic("middleware.InitAuthorizer must be called before middleware.Operate")
+	}
+
 	return func(c *gin.Context) {
-		userRole := c.GetInt("role")
-
-		switch action {
-		case ActionAddCategory, ActionPutCategory, ActionDeleteCategory, ActionAddArticle, ActionPutArticle, ActionDeleteArticle:
-			if userRole < model.UserRoleAdmin {
-				response.FailMsg(c, "权限不足")
-				c.Abort()
-				return
-			}
-		default:
-			response.FailMsg(c, "操作参数有误")
+		if !authorizer.HasPermission(c.GetInt64("userId"), permission) {
+			response.FailMsg(c, "权限不足")
 			c.Abort()
 			return
 		}
 
-		c.Next()
+		if auditor == nil || c.Request.Method == http.MethodGet {
+			c.Next()
+			return
+		}
+
+		audit(c, permission)
+	}
+}
+
+// audit 执行写操作并记录审计日志，操作失败时不记录
+// 操作对象id依次从路径参数id、json请求体的id和user_id、响应数据的id中获取
+func audit(c *gin.Context, permission string) {
+	log := model.AuditLog{
+		ActorId:   c.GetInt64("userId"),
+		Action:    permission,
+		Resource:  auditResource(c.FullPath()),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		TargetId:  auditTargetId(c),
+		Ip:        c.ClientIP(),
+		CreatedAt: time.Now().Unix(),
+	}
+	if log.TargetId > 0 {
+		log.Before = auditor.Snapshot(log.Resource, log.TargetId)
+	}
+
+	w := &auditResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+
+	reply := struct {
+		Code int `json:"code"`
+		Data struct {
+			Id int64 `json:"id"`
+		} `json:"data"`
+	}{}
+	if c.Writer.Status() != http.StatusOK || json.Unmarshal(w.body.Bytes(), &reply) != nil || reply.Code != 0 {
+		return
+	}
+
+	if log.TargetId == 0 {
+		log.TargetId = reply.Data.Id
+	}
+	// 在请求内查询操作后的快照，异步写入时记录可能已被后续请求修改
+	if log.TargetId > 0 {
+		log.After = auditor.Snapshot(log.Resource, log.TargetId)
+	}
+	auditor.Record(log)
+}
+
+// auditResource 从路由中取出资源名，如 /api/v1/article/:id 为 article
+func auditResource(fullPath string) string {
+	segments := strings.Split(strings.Trim(fullPath, "/"), "/")
+	// 跳过统一前缀 /api/版本号
+	if len(segments) < 3 {
+		return ""
+	}
+
+	if _, ok := model.AuditResources[segments[2]]; !ok {
+		return ""
+	}
+	return segments[2]
+}
+
+// auditTargetId 操作前能确定的操作对象id，读取请求体后重新放回，不影响后续处理
+func auditTargetId(c *gin.Context) int64 {
+	if id, err := strconv.ParseInt(c.Param("id"), 10, 64); err == nil {
+		return id
+	}
+
+	if c.ContentType() != gin.MIMEJSON || c.Request.Body == nil {
+		return 0
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return 0
+	}
+
+	req := struct {
+		Id     int64 `json:"id"`
+		UserId int64 `json:"user_id"`
+	}{}
+	if json.Unmarshal(body, &req) != nil {
+		return 0
 	}
+
+	if req.Id > 0 {
+		return req.Id
+	}
+	return req.UserId
+}
+
+// auditResponseWriter 保存响应内容，用于判断操作是否成功和取出新建记录的id
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
 }
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/mittacy/blogBack/pkg/ratelimit"
+	"github.com/mittacy/blogBack/pkg/response"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	rateLimiter     ratelimit.Limiter
+	rateLimitConfig ratelimit.Config
+)
+
+// InitRateLimiter 设置限流器和限流策略，需要在注册路由前调用
+func InitRateLimiter(limiter ratelimit.Limiter, conf ratelimit.Config) {
+	for name, policy := range conf.Policies {
+		if err := policy.Validate(); err != nil {
+			panic(fmt.Sprintf("checkout the rateLimit policy %s: %s", name, err))
+		}
+	}
+
+	rateLimiter = limiter
+	rateLimitConfig = conf
+}
+
+// RateLimit 按策略限流，策略按user限流时需要在ParseToken之后使用
+// 限流关闭或配置中没有该策略时不做限制
+// @param name 策略名，与配置文件rateLimit.policies中的路由组名对应
+func RateLimit(name string) gin.HandlerFunc {
+	policy, ok := rateLimitConfig.Policies[name]
+	if !ok || !rateLimitConfig.Enable || rateLimiter == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		res, err := rateLimiter.Allow(rateLimitKey(c, name, policy), policy)
+		if err != nil {
+			// 限流器不可用时放行，不影响正常请求
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(res.ResetAfter).Unix(), 10))
+
+		if !res.Allowed {
+			c.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(res.RetryAfter.Seconds())), 10))
+			response.TooManyRequests(c)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitKey 生成限流键，不同策略的额度互不影响
+func rateLimitKey(c *gin.Context, name string, policy ratelimit.Policy) string {
+	switch policy.Key {
+	case ratelimit.KeyByUser:
+		if userId := c.GetInt64("userId"); userId > 0 {
+			return name + ":user#" + strconv.FormatInt(userId, 10)
+		}
+	case ratelimit.KeyByRoute:
+		return name + ":route#" + c.Request.Method + c.FullPath()
+	}
+
+	return name + ":ip#" + c.ClientIP()
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net"
+	"strings"
+)
+
+// RealIP 根据可信代理解析客户端ip并写回Request.RemoteAddr，之后c.ClientIP()返回解析后的ip，
+// 需要在所有中间件之前使用，并关闭gin自身对转发头的解析(ForwardedByClientIP)。
+// 只有直连地址属于可信代理时才读取X-Forwarded-For，从右向左取第一个不属于可信代理的地址，
+// 客户端自己填写的X-Forwarded-For位于最左侧，不会被采用
+// @param trustedProxies 可信代理的ip或CIDR，为空时直接使用直连地址
+func RealIP(trustedProxies []string) gin.HandlerFunc {
+	trusted := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, v := range trustedProxies {
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil && ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+
+		_, cidr, err := net.ParseCIDR(v)
+		if err != nil {
+			panic(fmt.Sprintf("checkout the server.trustedProxies config: %s", err))
+		}
+		trusted = append(trusted, cidr)
+	}
+
+	isTrusted := func(ip net.IP) bool {
+		for _, cidr := range trusted {
+			if cidr.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *gin.Context) {
+		host, port, err := net.SplitHostPort(strings.TrimSpace(c.Request.RemoteAddr))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		remoteIP := net.ParseIP(host)
+		if remoteIP == nil || !isTrusted(remoteIP) {
+			c.Next()
+			return
+		}
+
+		forwarded := strings.Split(c.GetHeader("X-Forwarded-For"), ",")
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+			if ip == nil {
+				break
+			}
+			if !isTrusted(ip) {
+				c.Request.RemoteAddr = net.JoinHostPort(ip.String(), port)
+				break
+			}
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name       string
+		trusted    []string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"no trusted proxies ignores header", nil, "203.0.113.7:5000", "1.1.1.1", "203.0.113.7"},
+		{"untrusted remote ignores header", []string{"10.0.0.0/8"}, "203.0.113.7:5000", "1.1.1.1", "203.0.113.7"},
+		{"trusted proxy uses forwarded ip", []string{"10.0.0.0/8"}, "10.0.0.2:5000", "198.51.100.4", "198.51.100.4"},
+		{"spoofed leftmost entry is skipped", []string{"10.0.0.0/8"}, "10.0.0.2:5000", "1.1.1.1, 198.51.100.4", "198.51.100.4"},
+		{"chained trusted proxies are skipped", []string{"10.0.0.0/8", "127.0.0.1"}, "127.0.0.1:5000", "198.51.100.4, 10.0.0.3", "198.51.100.4"},
+		{"invalid entry stops parsing", []string{"10.0.0.0/8"}, "10.0.0.2:5000", "198.51.100.4, bad", "10.0.0.2"},
+		{"ipv6 proxy", []string{"::1"}, "[::1]:5000", "2001:db8::1", "2001:db8::1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.ForwardedByClientIP = false
+			r.Use(RealIP(tc.trusted))
+
+			var got string
+			r.GET("/", func(c *gin.Context) {
+				got = c.ClientIP()
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+			req.Header.Set("X-Real-IP", "1.1.1.1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tc.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRealIPInvalidConfig(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("RealIP should panic on invalid trusted proxy")
+		}
+	}()
+
+	RealIP([]string{"not-an-ip"})
+}
@@ -5,9 +5,22 @@ import (
 	"github.com/mittacy/blogBack/apierr"
 	"github.com/mittacy/blogBack/pkg/jwt"
 	"github.com/mittacy/blogBack/pkg/response"
+	"go.uber.org/zap"
 )
 
-// ParseToken 解析token到gin.Context
+// IBanChecker 查询用户是否被封禁
+type IBanChecker interface {
+	IsBanned(userId int64) (bool, error)
+}
+
+var banChecker IBanChecker
+
+// InitBanChecker 设置封禁查询，设置后ParseToken和TryParseToken不再接受被封禁用户已持有的token，需要在处理请求前调用
+func InitBanChecker(c IBanChecker) {
+	banChecker = c
+}
+
+// ParseToken 解析token到gin.Context，被封禁用户的token视为无效，查询封禁状态出错时拒绝请求
 func ParseToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		accessToken, err := c.Cookie("access_token")
@@ -24,9 +37,50 @@ func ParseToken() gin.HandlerFunc {
 			return
 		}
 
+		if banned, err := isBanned(token.UserId); err != nil {
+			response.Unknown(c)
+			c.Abort()
+			return
+		} else if banned {
+			response.FailErr(c, apierr.ErrUserBanned)
+			c.Abort()
+			return
+		}
+
 		c.Set("userId", token.UserId)
-		c.Set("role", token.Role)
+		c.Set("sessionId", token.SessionId)
 
 		c.Next()
 	}
 }
+
+// TryParseToken 尝试解析token到gin.Context，未登录、token失效、用户被封禁或查询封禁状态出错时按游客继续处理
+func TryParseToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken, err := c.Cookie("access_token")
+		if err == nil {
+			if token, err := jwt.Token.Parse(accessToken); token != nil && err == nil {
+				if banned, err := isBanned(token.UserId); err == nil && !banned {
+					c.Set("userId", token.UserId)
+				}
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// isBanned 查询用户是否被封禁，未设置封禁查询时视为未封禁，查询出错时记录日志并返回错误
+func isBanned(userId int64) (bool, error) {
+	if banChecker == nil {
+		return false, nil
+	}
+
+	banned, err := banChecker.IsBanned(userId)
+	if err != nil {
+		zap.S().Errorf("查询用户封禁状态出错: %s", err)
+		return false, err
+	}
+
+	return banned, nil
+}
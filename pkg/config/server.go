@@ -9,4 +9,6 @@ type Server struct {
 	Port         int
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+
+	TrustedProxies []string // 可信的反向代理ip或CIDR，只有来自这些地址的X-Forwarded-For才会被采用
 }
@@ -0,0 +1,419 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/golang-jwt/jwt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+// Session 登录会话，每次登录生成一个会话，对应一台设备
+// 会话保存当前刷新token的hash，刷新时轮换刷新token
+type Session struct {
+	Id          string `redis:"id"`
+	Subject     string `redis:"subject"` // 账号标识，账号合并前创建的会话没有该字段，视为无效
+	UserId      int64  `redis:"user_id"`
+	UserAgent   string `redis:"user_agent"`
+	Ip          string `redis:"ip"`
+	CreatedAt   int64  `redis:"created_at"`
+	LastUsedAt  int64  `redis:"last_used_at"`
+	RefreshHash string `redis:"refresh_hash"`
+}
+
+// TokenPair 访问token和刷新token
+type TokenPair struct {
+	AccessToken     string
+	AccessExpireAt  int64
+	RefreshToken    string
+	RefreshExpireAt int64
+	SessionId       string
+}
+
+const (
+	sessionIdLen    = 32 // 会话id为16字节随机数的hex
+	maxUserAgentLen = 255
+)
+
+// rotateScript 校验并轮换刷新token
+// 提交的刷新token与当前的不一致，说明提交的是已经轮换过的旧token，token可能已泄露，删除整个会话
+// KEYS[1] 会话键，KEYS[2] 用户的会话集合键
+// ARGV[1] 提交的刷新token hash，ARGV[2] 新的刷新token hash，ARGV[3] 当前时间，ARGV[4] 当前ip，ARGV[5] 会话id，ARGV[6] 有效期
+// 返回 1: 成功，0: 会话不存在，-1: 刷新token被重复使用
+var rotateScript = redis.NewScript(2, `
+local current = redis.call('hget', KEYS[1], 'refresh_hash')
+if not current then
+	return 0
+end
+if current ~= ARGV[1] then
+	redis.call('del', KEYS[1])
+	redis.call('zrem', KEYS[2], ARGV[5])
+	return -1
+end
+redis.call('hmset', KEYS[1], 'refresh_hash', ARGV[2], 'last_used_at', ARGV[3], 'ip', ARGV[4])
+redis.call('expire', KEYS[1], ARGV[6])
+redis.call('expire', KEYS[2], ARGV[6])
+return 1
+`)
+
+// CreateSession 登录成功后创建会话，生成访问token和刷新token
+// @param userId 用户id
+// @param userAgent 登录设备的User-Agent
+// @param ip 登录ip
+// @return *TokenPair
+// @return error
+func (ctl *token) CreateSession(userId int64, userAgent, ip string) (*TokenPair, error) {
+	sessionId, err := randomHex(sessionIdLen / 2)
+	if err != nil {
+		return nil, err
+	}
+	secret, refreshToken, err := newRefreshToken(sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+
+	now := time.Now()
+	session := Session{
+		Id:          sessionId,
+		Subject:     Subject(userId),
+		UserId:      userId,
+		UserAgent:   userAgent,
+		Ip:          ip,
+		CreatedAt:   now.Unix(),
+		LastUsedAt:  now.Unix(),
+		RefreshHash: hashSecret(secret),
+	}
+
+	conn, err := ctl.Cache.GetConn()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	sessionKey := ctl.sessionKey(sessionId)
+	userKey := ctl.userSessionsKey(userId)
+	expire := int64(ctl.RefreshExpire / time.Second)
+
+	if err := conn.Send("multi"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	conn.Send("hmset", redis.Args{}.Add(sessionKey).AddFlat(&session)...)
+	conn.Send("expire", sessionKey, expire)
+	conn.Send("zadd", userKey, session.CreatedAt, sessionId)
+	conn.Send("expire", userKey, expire)
+	if _, err := conn.Do("exec"); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return ctl.tokenPair(userId, sessionId, refreshToken, now)
+}
+
+// Refresh 使用刷新token换取新的访问token，同时轮换刷新token，旧的刷新token失效
+// @param refreshToken 刷新token
+// @param ip 当前ip
+// @return *TokenPair
+// @return error 刷新token无效时返回apierr.ErrRefreshTokenInvalid，重复使用时返回apierr.ErrRefreshTokenReused
+func (ctl *token) Refresh(refreshToken, ip string) (*TokenPair, error) {
+	sessionId, secret, ok := parseRefreshToken(refreshToken)
+	if !ok {
+		return nil, apierr.ErrRefreshTokenInvalid
+	}
+
+	session, err := ctl.GetSession(sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, apierr.ErrRefreshTokenInvalid
+	}
+
+	newSecret, newRefreshToken, err := newRefreshToken(sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ctl.Cache.GetConn()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	now := time.Now()
+	res, err := redis.Int(rotateScript.Do(conn,
+		ctl.sessionKey(sessionId), ctl.userSessionsKey(session.UserId),
+		hashSecret(secret), hashSecret(newSecret), now.Unix(), ip, sessionId, int64(ctl.RefreshExpire/time.Second)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	switch res {
+	case 0:
+		return nil, apierr.ErrRefreshTokenInvalid
+	case -1:
+		return nil, apierr.ErrRefreshTokenReused
+	}
+
+	return ctl.tokenPair(session.UserId, sessionId, newRefreshToken, now)
+}
+
+// GetSession 查询会话
+// @param sessionId 会话id
+// @return *Session 不存在时返回nil
+// @return error
+func (ctl *token) GetSession(sessionId string) (*Session, error) {
+	values, err := redis.Values(ctl.Cache.Do("hgetall", ctl.sessionKey(sessionId)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	session := Session{}
+	if err := redis.ScanStruct(values, &session); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if session.Subject != Subject(session.UserId) {
+		return nil, nil
+	}
+
+	return &session, nil
+}
+
+// ListSessions 查询用户所有有效的会话，按登录时间倒序
+// @param userId 用户id
+// @return []Session
+// @return error
+func (ctl *token) ListSessions(userId int64) ([]Session, error) {
+	userKey := ctl.userSessionsKey(userId)
+
+	ids, err := redis.Strings(ctl.Cache.Do("zrevrange", userKey, 0, -1))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	sessions := make([]Session, 0, len(ids))
+	var expired []interface{}
+
+	for _, id := range ids {
+		session, err := ctl.GetSession(id)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			expired = append(expired, id)
+			continue
+		}
+		sessions = append(sessions, *session)
+	}
+
+	// 清理已过期的会话id
+	if len(expired) > 0 {
+		if _, err := ctl.Cache.Do("zrem", redis.Args{}.Add(userKey).Add(expired...)...); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession 注销用户的某个会话，该会话的刷新token和访问token立即失效
+// @param userId 用户id
+// @param sessionId 会话id
+// @return error 会话不存在或不属于该用户时返回apierr.ErrSessionNoExist
+func (ctl *token) RevokeSession(userId int64, sessionId string) error {
+	userKey := ctl.userSessionsKey(userId)
+
+	removed, err := redis.Int(ctl.Cache.Do("zrem", userKey, sessionId))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if removed == 0 {
+		return apierr.ErrSessionNoExist
+	}
+
+	deleted, err := redis.Int(ctl.Cache.Do("del", ctl.sessionKey(sessionId)))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if deleted == 0 {
+		return apierr.ErrSessionNoExist
+	}
+
+	return nil
+}
+
+// revokeAllScript 代数加一并删除用户所有的会话，读取会话集合和删除在同一脚本中执行，不会遗漏并发创建的会话
+// KEYS[1] 用户的会话集合键，KEYS[2] 用户的代数键
+// ARGV[1] 会话键的前缀
+// 返回删除的会话数
+var revokeAllScript = redis.NewScript(2, `
+redis.call('incr', KEYS[2])
+local ids = redis.call('zrange', KEYS[1], 0, -1)
+for _, id in ipairs(ids) do
+	redis.call('del', ARGV[1] .. id)
+end
+redis.call('del', KEYS[1])
+return #ids
+`)
+
+// RevokeAll 注销用户所有的会话，用户已签发的访问token和刷新token全部失效
+// @param userId 用户id
+// @return error
+func (ctl *token) RevokeAll(userId int64) error {
+	conn, err := ctl.Cache.GetConn()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	// 代数加一使不属于任何会话的旧token也失效
+	_, err = revokeAllScript.Do(conn, ctl.userSessionsKey(userId), ctl.generationKey(userId), ctl.sessionKey(""))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+// Logout 退出登录，访问token加入黑名单，所属的会话被注销
+// 访问token已过期时依然可以注销会话，使刷新token失效
+// @param tokenString 访问token
+// @return error
+func (ctl *token) Logout(tokenString string) error {
+	claims := TokenData{}
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	})
+	if err != nil {
+		// 只忽略过期错误，签名错误等非法token不做处理
+		var ve *jwt.ValidationError
+		if !errors.As(err, &ve) || ve.Errors != jwt.ValidationErrorExpired {
+			return nil
+		}
+	}
+
+	userId, ok := ParseSubject(claims.Subject)
+	if ok && claims.SessionId != "" {
+		if err := ctl.RevokeSession(userId, claims.SessionId); err != nil && !errors.Is(err, apierr.ErrSessionNoExist) {
+			return err
+		}
+	}
+
+	return ctl.JoinBlackList(tokenString)
+}
+
+// isActive 访问token所属的会话存在且签发时的代数为用户当前的代数
+func (ctl *token) isActive(claims *TokenData) bool {
+	conn, err := ctl.Cache.GetConn()
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+
+	conn.Send("get", ctl.generationKey(claims.UserId))
+	if claims.SessionId != "" {
+		conn.Send("exists", ctl.sessionKey(claims.SessionId))
+	}
+	if err := conn.Flush(); err != nil {
+		return false
+	}
+
+	generation, err := redis.Int64(conn.Receive())
+	if err != nil && err != redis.ErrNil {
+		return false
+	}
+	if generation != claims.Generation {
+		return false
+	}
+
+	if claims.SessionId != "" {
+		exists, err := redis.Bool(conn.Receive())
+		if err != nil || !exists {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generation 用户当前的token代数，从未注销过所有会话时为0
+func (ctl *token) generation(userId int64) (int64, error) {
+	generation, err := redis.Int64(ctl.Cache.Do("get", ctl.generationKey(userId)))
+	if err != nil && err != redis.ErrNil {
+		return 0, errors.WithStack(err)
+	}
+
+	return generation, nil
+}
+
+func (ctl *token) tokenPair(userId int64, sessionId, refreshToken string, now time.Time) (*TokenPair, error) {
+	accessToken, err := ctl.Create(userId, sessionId)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &TokenPair{
+		AccessToken:     accessToken,
+		AccessExpireAt:  now.Add(ctl.Expire).Unix(),
+		RefreshToken:    refreshToken,
+		RefreshExpireAt: now.Add(ctl.RefreshExpire).Unix(),
+		SessionId:       sessionId,
+	}, nil
+}
+
+func (ctl *token) sessionKey(sessionId string) string {
+	return fmt.Sprintf("%s:session:%s", ctl.Cache.CachePrefixKey(), sessionId)
+}
+
+func (ctl *token) generationKey(userId int64) string {
+	return fmt.Sprintf("%s:generation:userId#%d", ctl.Cache.CachePrefixKey(), userId)
+}
+
+func (ctl *token) userSessionsKey(userId int64) string {
+	return fmt.Sprintf("%s:sessions:userId#%d", ctl.Cache.CachePrefixKey(), userId)
+}
+
+// newRefreshToken 生成刷新token，格式: 会话id.随机串，只保存随机串的hash
+func newRefreshToken(sessionId string) (secret, refreshToken string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", errors.WithStack(err)
+	}
+
+	secret = base64.RawURLEncoding.EncodeToString(b)
+	return secret, sessionId + "." + secret, nil
+}
+
+func parseRefreshToken(refreshToken string) (sessionId, secret string, ok bool) {
+	i := strings.IndexByte(refreshToken, '.')
+	if i != sessionIdLen || i == len(refreshToken)-1 {
+		return "", "", false
+	}
+
+	return refreshToken[:i], refreshToken[i+1:], true
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,198 @@
+package jwt
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/apierr"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestToken 使用miniredis创建token句柄
+func newTestToken(t *testing.T) (*token, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr())
+	}}
+	t.Cleanup(func() { pool.Close() })
+
+	secret = "test-secret"
+	return &token{
+		Expire:        time.Minute,
+		RefreshExpire: time.Hour,
+		Cache:         cache.ConnRedisByPool(pool, "jwt"),
+		BlackName:     "test:token:blacklist",
+	}, s
+}
+
+func TestRefreshRotation(t *testing.T) {
+	tk, s := newTestToken(t)
+
+	pair, err := tk.CreateSession(1, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	oldHash := s.HGet(tk.sessionKey(pair.SessionId), "refresh_hash")
+
+	// 会话有效期按最后一次刷新重新计算
+	s.FastForward(30 * time.Minute)
+
+	refreshed, err := tk.Refresh(pair.RefreshToken, "10.0.0.2")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if refreshed.SessionId != pair.SessionId {
+		t.Errorf("Refresh() session id = %s, want %s", refreshed.SessionId, pair.SessionId)
+	}
+	if refreshed.RefreshToken == pair.RefreshToken {
+		t.Error("Refresh() did not rotate the refresh token")
+	}
+	if claims, err := tk.Parse(refreshed.AccessToken); err != nil || claims == nil || claims.UserId != 1 {
+		t.Errorf("Parse() refreshed access token = %+v, %v, want claims of user 1", claims, err)
+	}
+
+	sessionKey := tk.sessionKey(pair.SessionId)
+	if hash := s.HGet(sessionKey, "refresh_hash"); hash == oldHash || hash != hashSecret(strings.SplitN(refreshed.RefreshToken, ".", 2)[1]) {
+		t.Errorf("refresh_hash = %s, want hash of the new refresh token", hash)
+	}
+	if ip := s.HGet(sessionKey, "ip"); ip != "10.0.0.2" {
+		t.Errorf("session ip = %s, want 10.0.0.2", ip)
+	}
+	if ttl := s.TTL(sessionKey); ttl != time.Hour {
+		t.Errorf("session ttl = %s, want %s", ttl, time.Hour)
+	}
+	if ttl := s.TTL(tk.userSessionsKey(1)); ttl != time.Hour {
+		t.Errorf("user sessions ttl = %s, want %s", ttl, time.Hour)
+	}
+
+	// 新的刷新token可以继续轮换
+	if _, err := tk.Refresh(refreshed.RefreshToken, "10.0.0.2"); err != nil {
+		t.Errorf("Refresh() with the rotated token error = %v", err)
+	}
+}
+
+func TestRefreshReuseDetection(t *testing.T) {
+	tk, s := newTestToken(t)
+
+	pair, err := tk.CreateSession(1, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+	other, err := tk.CreateSession(1, "other-agent", "10.0.0.3")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	refreshed, err := tk.Refresh(pair.RefreshToken, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	// 重复使用已轮换的刷新token，整个会话被删除
+	if _, err := tk.Refresh(pair.RefreshToken, "10.0.0.9"); err != apierr.ErrRefreshTokenReused {
+		t.Fatalf("Refresh() with the old token error = %v, want ErrRefreshTokenReused", err)
+	}
+	if s.Exists(tk.sessionKey(pair.SessionId)) {
+		t.Error("session still exists after reuse")
+	}
+	members, err := s.ZMembers(tk.userSessionsKey(1))
+	if err != nil {
+		t.Fatalf("ZMembers() error = %v", err)
+	}
+	if len(members) != 1 || members[0] != other.SessionId {
+		t.Errorf("user sessions = %v, want only %s", members, other.SessionId)
+	}
+
+	// 会话删除后，轮换得到的新token和访问token也失效
+	if _, err := tk.Refresh(refreshed.RefreshToken, "10.0.0.1"); err != apierr.ErrRefreshTokenInvalid {
+		t.Errorf("Refresh() after reuse error = %v, want ErrRefreshTokenInvalid", err)
+	}
+	if claims, err := tk.Parse(refreshed.AccessToken); err != nil || claims != nil {
+		t.Errorf("Parse() access token of the revoked session = %+v, %v, want nil", claims, err)
+	}
+
+	// 其他会话不受影响
+	if _, err := tk.Refresh(other.RefreshToken, "10.0.0.3"); err != nil {
+		t.Errorf("Refresh() other session error = %v", err)
+	}
+}
+
+func TestRefreshInvalid(t *testing.T) {
+	tk, s := newTestToken(t)
+
+	pair, err := tk.CreateSession(1, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession() error = %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"malformed", "abc.def"},
+		{"no secret", pair.SessionId + "."},
+		{"unknown session", strings.Repeat("0", sessionIdLen) + ".secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tk.Refresh(tt.token, "10.0.0.1"); err != apierr.ErrRefreshTokenInvalid {
+				t.Errorf("Refresh(%q) error = %v, want ErrRefreshTokenInvalid", tt.token, err)
+			}
+		})
+	}
+
+	// 会话过期后刷新token失效
+	s.FastForward(time.Hour + time.Second)
+	if _, err := tk.Refresh(pair.RefreshToken, "10.0.0.1"); err != apierr.ErrRefreshTokenInvalid {
+		t.Errorf("Refresh() expired session error = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+func TestRevokeAll(t *testing.T) {
+	tk, s := newTestToken(t)
+
+	var pairs []*TokenPair
+	for _, userId := range []int64{1, 1, 2} {
+		pair, err := tk.CreateSession(userId, "test-agent", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("CreateSession() error = %v", err)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	if err := tk.RevokeAll(1); err != nil {
+		t.Fatalf("RevokeAll() error = %v", err)
+	}
+
+	for _, pair := range pairs[:2] {
+		if s.Exists(tk.sessionKey(pair.SessionId)) {
+			t.Errorf("session %s still exists", pair.SessionId)
+		}
+		if claims, _ := tk.Parse(pair.AccessToken); claims != nil {
+			t.Errorf("Parse() revoked access token = %+v, want nil", claims)
+		}
+		if _, err := tk.Refresh(pair.RefreshToken, "10.0.0.1"); err != apierr.ErrRefreshTokenInvalid {
+			t.Errorf("Refresh() revoked token error = %v, want ErrRefreshTokenInvalid", err)
+		}
+	}
+	if s.Exists(tk.userSessionsKey(1)) {
+		t.Error("user sessions still exist")
+	}
+
+	// 其他用户的会话不受影响
+	if claims, err := tk.Parse(pairs[2].AccessToken); err != nil || claims == nil {
+		t.Errorf("Parse() other user's access token = %+v, %v", claims, err)
+	}
+
+	// 注销后重新登录的会话有效
+	pair, err := tk.CreateSession(1, "test-agent", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("CreateSession() after RevokeAll error = %v", err)
+	}
+	if claims, err := tk.Parse(pair.AccessToken); err != nil || claims == nil {
+		t.Errorf("Parse() new access token = %+v, %v", claims, err)
+	}
+}
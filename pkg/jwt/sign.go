@@ -0,0 +1,24 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// Sign 使用jwt密钥对数据签名，用于邮件链接等需要防篡改的一次性凭证
+// @param data 签名的数据
+// @return string base64url编码的签名
+func Sign(data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySign 校验数据签名
+// @param data 签名的数据
+// @param sign Sign生成的签名
+// @return bool 签名是否正确
+func VerifySign(data, sign string) bool {
+	return hmac.Equal([]byte(Sign(data)), []byte(sign))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,24 +17,58 @@ var secret string // 服务开始后请勿更改密钥，否则会导致已经
 var Token *token
 
 type token struct {
-	Expire    time.Duration
-	Cache     cache.CustomRedis
-	BlackName string // redis token黑名单集合键名
+	Expire        time.Duration // 访问token有效期
+	RefreshExpire time.Duration // 刷新token有效期，有效期内刷新会顺延
+	Cache         cache.CustomRedis
+	BlackName     string // redis token黑名单集合键名
 }
 
+// TokenData token中的数据，sub为账号标识，格式: user:用户id
 type TokenData struct {
-	UserId int64 `json:"userId"`
-	Role   int   `json:"role"`
+	UserId     int64  `json:"-"` // 从sub解析
+	SessionId  string `json:"sid"`
+	Generation int64  `json:"gen"` // 签发时用户的token代数，注销用户所有会话时代数加一，旧token全部失效
 	jwt.StandardClaims
 }
 
+const subjectUserPrefix = "user:"
+
+// Subject 生成账号标识
+// @param userId 用户id
+// @return string 账号标识
+func Subject(userId int64) string {
+	return subjectUserPrefix + strconv.FormatInt(userId, 10)
+}
+
+// ParseSubject 解析账号标识
+// @param subject 账号标识
+// @return int64 用户id
+// @return bool 是否为合法的账号标识
+func ParseSubject(subject string) (int64, bool) {
+	if !strings.HasPrefix(subject, subjectUserPrefix) {
+		return 0, false
+	}
+
+	userId, err := strconv.ParseInt(subject[len(subjectUserPrefix):], 10, 64)
+	if err != nil || userId <= 0 {
+		return 0, false
+	}
+
+	return userId, true
+}
+
 // InitToken 初始化
-// @param expireHours
 func InitToken(customRedis cache.CustomRedis) {
-	var expire time.Duration
-	if err := viper.UnmarshalKey("jwt.expire", &expire); err != nil {
+	var expire, refreshExpire time.Duration
+	if err := viper.UnmarshalKey("jwt.accessExpire", &expire); err != nil {
+		panic(fmt.Sprintf("jwt init err: %s", err))
+	}
+	if err := viper.UnmarshalKey("jwt.refreshExpire", &refreshExpire); err != nil {
 		panic(fmt.Sprintf("jwt init err: %s", err))
 	}
+	if expire <= 0 || refreshExpire <= 0 {
+		panic("jwt.accessExpire and jwt.refreshExpire config must be greater than 0")
+	}
 
 	if err := viper.UnmarshalKey("jwt.secret", &secret); err != nil {
 		panic(fmt.Sprintf("jwt init err: %s", err))
@@ -42,15 +77,15 @@ func InitToken(customRedis cache.CustomRedis) {
 		panic("jwt.secret config cannot be empty")
 	}
 
-	Token = NewToken(expire, customRedis)
+	Token = NewToken(expire, refreshExpire, customRedis)
 }
 
 // NewToken 生成新的token配置
-// @param expireHours token过期时间，单位：小时
+// @param expireMinutes 访问token过期时间，单位：分钟
+// @param refreshExpireHours 刷新token过期时间，单位：小时
 // @param cache redis操作句柄
 // @return *token token句柄
-func NewToken(expire time.Duration, customRedis cache.CustomRedis) *token {
-	expire = expire * time.Second * 3600
+func NewToken(expireMinutes, refreshExpireHours time.Duration, customRedis cache.CustomRedis) *token {
 
 	var serverName string
 	if err := viper.UnmarshalKey("server.name", &serverName); err != nil {
@@ -58,23 +93,31 @@ func NewToken(expire time.Duration, customRedis cache.CustomRedis) *token {
 	}
 
 	return &token{
-		Expire:    expire,
-		Cache:     customRedis,
-		BlackName: fmt.Sprintf("%s:token:blacklist", serverName),
+		Expire:        expireMinutes * time.Minute,
+		RefreshExpire: refreshExpireHours * time.Hour,
+		Cache:         customRedis,
+		BlackName:     fmt.Sprintf("%s:token:blacklist", serverName),
 	}
 }
 
-// Create 生成token
+// Create 生成访问token
 // @param userId 用户id
-// @param role 用户角色
+// @param sessionId 所属的会话id，会话注销后token失效
 // @return string token字符串
 // @return error
-func (ctl *token) Create(userId int64, role int) (string, error) {
-	claims := jwt.MapClaims{
-		"id":     userId,
-		"role":   role,
-		"userId": userId,
-		"exp":    time.Now().Add(ctl.Expire).Unix(),
+func (ctl *token) Create(userId int64, sessionId string) (string, error) {
+	generation, err := ctl.generation(userId)
+	if err != nil {
+		return "", err
+	}
+
+	claims := TokenData{
+		SessionId:  sessionId,
+		Generation: generation,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   Subject(userId),
+			ExpiresAt: time.Now().Add(ctl.Expire).Unix(),
+		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
@@ -114,6 +157,17 @@ func (ctl *token) Parse(tokenString string) (*TokenData, error) {
 
 	if token != nil {
 		if claims, ok := token.Claims.(*TokenData); ok && token.Valid {
+			// 账号合并前签发的token没有sub
+			userId, ok := ParseSubject(claims.Subject)
+			if !ok {
+				return nil, nil
+			}
+			claims.UserId = userId
+
+			// 所属的会话已注销或用户的所有会话已被注销
+			if !ctl.isActive(claims) {
+				return nil, nil
+			}
 			return claims, nil
 		}
 	}
@@ -0,0 +1,115 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const (
+	argon2SaltLen = 16 // 随机盐的长度，单位: 字节
+	argon2KeyLen  = 32 // 哈希结果的长度，单位: 字节
+)
+
+type Argon2Config struct {
+	Memory  uint32 // 内存成本，单位: KB
+	Time    uint32 // 迭代次数
+	Threads uint8  // 并行度
+}
+
+type argon2idHasher struct {
+	conf Argon2Config
+}
+
+// NewArgon2id 创建argon2id算法，哈希编码格式: $argon2id$v=19$m=内存,t=迭代次数,p=并行度$盐$哈希
+// @param conf 算法参数，为0的参数使用默认值
+// @return Hasher
+func NewArgon2id(conf Argon2Config) Hasher {
+	if conf.Memory == 0 {
+		conf.Memory = 64 * 1024
+	}
+	if conf.Time == 0 {
+		conf.Time = 1
+	}
+	if conf.Threads == 0 {
+		conf.Threads = 2
+	}
+
+	return &argon2idHasher{conf: conf}
+}
+
+func (ctl *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, ctl.conf.Time, ctl.conf.Memory, ctl.conf.Threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		ctl.conf.Memory, ctl.conf.Time, ctl.conf.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (ctl *argon2idHasher) Verify(password, encoded string) (bool, error) {
+	conf, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, conf.Time, conf.Memory, conf.Threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (ctl *argon2idHasher) Match(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (ctl *argon2idHasher) NeedsRehash(encoded string) bool {
+	conf, _, _, err := decodeArgon2id(encoded)
+	return err != nil || conf != ctl.conf
+}
+
+// decodeArgon2id 解析argon2id哈希编码
+// @param encoded 哈希编码
+// @return conf 生成哈希使用的参数
+// @return salt 盐
+// @return key 哈希结果
+// @return err
+func decodeArgon2id(encoded string) (conf Argon2Config, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		err = errors.New("invalid argon2id hash")
+		return
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	if version != argon2.Version {
+		err = errors.Errorf("unsupported argon2 version %d", version)
+		return
+	}
+
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &conf.Memory, &conf.Time, &conf.Threads); err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		err = errors.WithStack(err)
+		return
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		err = errors.WithStack(err)
+	}
+
+	return
+}
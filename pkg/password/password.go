@@ -0,0 +1,123 @@
+package password
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/mittacy/blogBack/utils"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// Hasher 密码哈希算法，哈希结果中编码了算法和参数，修改参数后旧的哈希仍然可以校验
+type Hasher interface {
+	// Hash 生成密码哈希
+	Hash(password string) (string, error)
+	// Verify 校验密码，encoded不属于该算法时返回false
+	Verify(password, encoded string) (bool, error)
+	// Match 判断encoded是否由该算法生成
+	Match(encoded string) bool
+	// NeedsRehash 判断encoded使用的参数是否与当前配置不同，需要重新哈希
+	NeedsRehash(encoded string) bool
+}
+
+type Config struct {
+	Algorithm  string       // 新密码使用的算法: bcrypt/argon2id
+	BcryptCost int          // bcrypt计算成本
+	Argon2     Argon2Config // argon2id参数
+}
+
+var (
+	current Hasher   // 生成新密码使用的算法
+	hashers []Hasher // 所有支持校验的算法
+	dummy   string   // 使用当前算法和参数生成的固定哈希，见VerifyDummy
+)
+
+// Init 初始化密码哈希算法
+func Init() {
+	conf := Config{}
+	if err := viper.UnmarshalKey("password", &conf); err != nil {
+		panic(fmt.Sprintf("password init err: %s", err))
+	}
+
+	if err := setup(conf); err != nil {
+		panic(fmt.Sprintf("password init err: %s", err))
+	}
+}
+
+// setup 按配置创建支持的算法和VerifyDummy使用的哈希
+func setup(conf Config) error {
+	bcryptHasher := NewBcrypt(conf.BcryptCost)
+	argon2Hasher := NewArgon2id(conf.Argon2)
+
+	var h Hasher
+	switch conf.Algorithm {
+	case AlgorithmBcrypt:
+		h = bcryptHasher
+	case AlgorithmArgon2id, "":
+		h = argon2Hasher
+	default:
+		return errors.Errorf("unknown algorithm %s", conf.Algorithm)
+	}
+
+	encoded, err := h.Hash("dummy password")
+	if err != nil {
+		return err
+	}
+
+	current, hashers, dummy = h, []Hasher{bcryptHasher, argon2Hasher}, encoded
+	return nil
+}
+
+// Hash 使用当前配置的算法生成密码哈希
+// @param password 原密码
+// @return string 编码了算法和参数的哈希
+// @return error
+func Hash(password string) (string, error) {
+	return current.Hash(password)
+}
+
+// VerifyDummy 使用固定的哈希校验密码，结果总是不正确
+// 账号不存在时调用，使耗时与账号存在时相同，避免通过响应时间判断账号是否存在
+// @param password 原密码
+func VerifyDummy(password string) {
+	_, _, _ = Verify(password, dummy, "")
+}
+
+// Verify 校验密码，兼容旧的 sha256+盐 密码
+// @param password 原密码
+// @param encoded 存储的密码哈希
+// @param salt 旧密码使用的盐，新密码为空
+// @return ok 密码是否正确
+// @return needsRehash 密码正确但使用了旧算法或旧参数，需要重新哈希保存
+// @return err
+func Verify(password, encoded, salt string) (ok bool, needsRehash bool, err error) {
+	for _, h := range hashers {
+		if !h.Match(encoded) {
+			continue
+		}
+
+		if ok, err = h.Verify(password, encoded); err != nil || !ok {
+			return false, false, err
+		}
+
+		return true, h != current || h.NeedsRehash(encoded), nil
+	}
+
+	// 旧密码: 单轮sha256加盐
+	if salt == "" || strings.HasPrefix(encoded, "$") {
+		return false, false, nil
+	}
+
+	legacy := utils.EncryptionBySalt(password, salt)
+	if subtle.ConstantTimeCompare([]byte(legacy), []byte(encoded)) != 1 {
+		return false, false, nil
+	}
+
+	return true, true, nil
+}
@@ -0,0 +1,160 @@
+package password
+
+import (
+	"github.com/mittacy/blogBack/utils"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+// 测试使用较小的参数，减少耗时
+var testArgon2Config = Argon2Config{Memory: 1024, Time: 1, Threads: 1}
+
+// initTestHashers 初始化支持的算法，algorithm为生成新密码使用的算法
+func initTestHashers(t *testing.T, algorithm string) {
+	if err := setup(Config{Algorithm: algorithm, BcryptCost: bcrypt.MinCost, Argon2: testArgon2Config}); err != nil {
+		t.Fatalf("setup() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		current, hashers, dummy = nil, nil, ""
+	})
+}
+
+func mustHash(t *testing.T, h Hasher, password string) string {
+	encoded, err := h.Hash(password)
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	return encoded
+}
+
+func TestVerify(t *testing.T) {
+	initTestHashers(t, AlgorithmArgon2id)
+
+	argon2Encoded := mustHash(t, current, "secret")
+	oldArgon2Encoded := mustHash(t, NewArgon2id(Argon2Config{Memory: 2048, Time: 1, Threads: 1}), "secret")
+	bcryptEncoded := mustHash(t, NewBcrypt(bcrypt.MinCost), "secret")
+
+	tests := []struct {
+		name            string
+		password        string
+		encoded         string
+		salt            string
+		wantOk          bool
+		wantNeedsRehash bool
+		wantErr         bool
+	}{
+		{name: "argon2id", password: "secret", encoded: argon2Encoded, wantOk: true},
+		{name: "argon2id wrong password", password: "wrong", encoded: argon2Encoded},
+		{name: "argon2id old params", password: "secret", encoded: oldArgon2Encoded, wantOk: true, wantNeedsRehash: true},
+		{name: "bcrypt", password: "secret", encoded: bcryptEncoded, wantOk: true, wantNeedsRehash: true},
+		{name: "bcrypt wrong password", password: "wrong", encoded: bcryptEncoded},
+		{name: "legacy sha256", password: "secret", encoded: utils.EncryptionBySalt("secret", "salt"), salt: "salt", wantOk: true, wantNeedsRehash: true},
+		{name: "legacy sha256 wrong password", password: "wrong", encoded: utils.EncryptionBySalt("secret", "salt"), salt: "salt"},
+		{name: "legacy sha256 wrong salt", password: "secret", encoded: utils.EncryptionBySalt("secret", "salt"), salt: "other"},
+		{name: "legacy without salt", password: "secret", encoded: utils.EncryptionBySalt("secret", "")},
+		{name: "empty hash", password: "", encoded: ""},
+		{name: "unknown algorithm", password: "secret", encoded: "$scrypt$abc", salt: "salt"},
+		{name: "argon2id missing parts", password: "secret", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA", wantErr: true},
+		{name: "argon2id bad version", password: "secret", encoded: "$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5", wantErr: true},
+		{name: "argon2id bad params", password: "secret", encoded: "$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5", wantErr: true},
+		{name: "argon2id bad salt", password: "secret", encoded: "$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5", wantErr: true},
+		{name: "argon2id bad key", password: "secret", encoded: "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$!!!", wantErr: true},
+		{name: "bcrypt malformed", password: "secret", encoded: "$2a$04$short", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash, err := Verify(tt.password, tt.encoded, tt.salt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOk || needsRehash != tt.wantNeedsRehash {
+				t.Errorf("Verify() = %v, %v, want %v, %v", ok, needsRehash, tt.wantOk, tt.wantNeedsRehash)
+			}
+		})
+	}
+}
+
+func TestHashRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			initTestHashers(t, algorithm)
+
+			encoded, err := Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if !current.Match(encoded) {
+				t.Errorf("Hash() = %q, not encoded by %s", encoded, algorithm)
+			}
+
+			// 每次哈希使用不同的盐
+			if other, _ := Hash("secret"); other == encoded {
+				t.Error("Hash() returned the same hash twice")
+			}
+
+			ok, needsRehash, err := Verify("secret", encoded, "")
+			if err != nil || !ok || needsRehash {
+				t.Errorf("Verify() = %v, %v, %v, want true, false, nil", ok, needsRehash, err)
+			}
+		})
+	}
+}
+
+func TestArgon2idEncoding(t *testing.T) {
+	h := NewArgon2id(testArgon2Config)
+	encoded := mustHash(t, h, "secret")
+
+	conf, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatalf("decodeArgon2id() error = %v", err)
+	}
+	if conf != testArgon2Config || len(salt) != argon2SaltLen || len(key) != argon2KeyLen {
+		t.Errorf("decodeArgon2id() = %+v, %d bytes salt, %d bytes key", conf, len(salt), len(key))
+	}
+
+	if h.NeedsRehash(encoded) {
+		t.Error("NeedsRehash() = true for the current params")
+	}
+	if !NewArgon2id(Argon2Config{}).NeedsRehash(encoded) {
+		t.Error("NeedsRehash() = false for the default params")
+	}
+}
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	h := NewBcrypt(bcrypt.MinCost)
+	encoded := mustHash(t, h, "secret")
+
+	if h.NeedsRehash(encoded) {
+		t.Error("NeedsRehash() = true for the current cost")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(encoded) {
+		t.Error("NeedsRehash() = false for a different cost")
+	}
+
+	// 不在合法范围的成本使用默认值
+	if got := NewBcrypt(100).(*bcryptHasher).cost; got != bcrypt.DefaultCost {
+		t.Errorf("NewBcrypt(100) cost = %d, want %d", got, bcrypt.DefaultCost)
+	}
+}
+
+func TestSetupUnknownAlgorithm(t *testing.T) {
+	if err := setup(Config{Algorithm: "md5"}); err == nil {
+		t.Error("setup() with unknown algorithm error = nil")
+	}
+}
+
+func TestVerifyDummy(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmArgon2id, AlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			initTestHashers(t, algorithm)
+
+			// 固定哈希使用当前的算法和参数，耗时与校验真实密码相同
+			if !current.Match(dummy) || current.NeedsRehash(dummy) {
+				t.Errorf("dummy hash %q does not use the current params", dummy)
+			}
+			VerifyDummy("secret")
+		})
+	}
+}
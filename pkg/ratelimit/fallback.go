@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+const fallbackRetryInterval = 10 * time.Second // 主限流器出错后，在该时间内直接使用备用限流器
+
+type fallbackLimiter struct {
+	primary   Limiter
+	secondary Limiter
+
+	mu        sync.RWMutex
+	downUntil time.Time
+}
+
+// NewFallback 创建带备用的限流器，主限流器出错时(如redis不可用)使用备用限流器，
+// 出错后一段时间内不再尝试主限流器，避免每个请求都等待redis连接超时
+// @param primary 主限流器
+// @param secondary 备用限流器
+// @return Limiter
+func NewFallback(primary, secondary Limiter) Limiter {
+	return &fallbackLimiter{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+func (ctl *fallbackLimiter) Allow(key string, policy Policy) (Result, error) {
+	ctl.mu.RLock()
+	down := time.Now().Before(ctl.downUntil)
+	ctl.mu.RUnlock()
+
+	if !down {
+		res, err := ctl.primary.Allow(key, policy)
+		if err == nil {
+			return res, nil
+		}
+
+		zap.S().Errorf("限流器出错，%s内使用备用限流器: %s", fallbackRetryInterval, err)
+		ctl.mu.Lock()
+		ctl.downUntil = time.Now().Add(fallbackRetryInterval)
+		ctl.mu.Unlock()
+	}
+
+	return ctl.secondary.Allow(key, policy)
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"   // 令牌桶，允许短时间突发，长期速率为 limit/window
+	AlgorithmSlidingWindow = "sliding_window" // 滑动窗口，任意window时间内最多limit次请求
+
+	KeyByIp    = "ip"    // 按客户端ip限流
+	KeyByUser  = "user"  // 按登录用户限流，未登录时按ip
+	KeyByRoute = "route" // 按路由限流，所有客户端共享额度
+)
+
+// Policy 限流策略
+type Policy struct {
+	Algorithm string // 限流算法: token_bucket/sliding_window
+	Key       string // 限流维度: ip/user/route
+	Limit     int    // 窗口内最多请求数，令牌桶为桶容量
+	Window    int    // 窗口时长，令牌桶为装满整个桶的时长，单位: 秒
+}
+
+// Config 限流配置
+type Config struct {
+	Enable   bool
+	Policies map[string]Policy // 策略名 -> 策略，策略名即路由组名
+}
+
+// Validate 校验策略参数
+// @return error
+func (p Policy) Validate() error {
+	if p.Algorithm != AlgorithmTokenBucket && p.Algorithm != AlgorithmSlidingWindow {
+		return fmt.Errorf("unknown algorithm %s", p.Algorithm)
+	}
+	if p.Key != KeyByIp && p.Key != KeyByUser && p.Key != KeyByRoute {
+		return fmt.Errorf("unknown key %s", p.Key)
+	}
+	if p.Limit <= 0 || p.Window <= 0 {
+		return fmt.Errorf("limit and window must be greater than 0")
+	}
+	return nil
+}
+
+func (p Policy) window() time.Duration {
+	return time.Duration(p.Window) * time.Second
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int           // 剩余可用请求数
+	ResetAfter time.Duration // 额度完全恢复的剩余时间
+	RetryAfter time.Duration // 被限流时，距离下一次可以请求的时间
+}
+
+// Limiter 限流器
+type Limiter interface {
+	// Allow 消耗一次请求额度
+	// @param key 限流键，同一个键共享额度
+	// @param policy 限流策略
+	Allow(key string, policy Policy) (Result, error)
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute // 清理过期额度记录的间隔
+
+type memoryEntry struct {
+	tokens   float64     // 令牌桶剩余令牌数
+	ts       time.Time   // 令牌桶上次更新时间
+	hits     []time.Time // 滑动窗口内每次请求的时间
+	expireAt time.Time
+}
+
+type memoryLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemory 创建在进程内存中保存额度的限流器，额度不在多个实例间共享
+// @return Limiter
+func NewMemory() Limiter {
+	return &memoryLimiter{
+		entries:   make(map[string]*memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (ctl *memoryLimiter) Allow(key string, policy Policy) (Result, error) {
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+
+	now := time.Now()
+	ctl.sweep(now)
+
+	key = policy.Algorithm + ":" + key
+	entry, ok := ctl.entries[key]
+	if !ok {
+		entry = &memoryEntry{tokens: float64(policy.Limit), ts: now}
+		ctl.entries[key] = entry
+	}
+	entry.expireAt = now.Add(policy.window())
+
+	if policy.Algorithm == AlgorithmTokenBucket {
+		return entry.takeToken(now, policy), nil
+	}
+	return entry.hit(now, policy), nil
+}
+
+// sweep 删除过期的额度记录，避免长期运行时占用内存持续增长
+func (ctl *memoryLimiter) sweep(now time.Time) {
+	if now.Sub(ctl.lastSweep) < memorySweepInterval {
+		return
+	}
+	ctl.lastSweep = now
+
+	for k, v := range ctl.entries {
+		if now.After(v.expireAt) {
+			delete(ctl.entries, k)
+		}
+	}
+}
+
+func (e *memoryEntry) takeToken(now time.Time, policy Policy) Result {
+	capacity := float64(policy.Limit)
+	rate := capacity / float64(policy.window()) // 每纳秒生成的令牌数
+
+	e.tokens = math.Min(capacity, e.tokens+float64(now.Sub(e.ts))*rate)
+	e.ts = now
+
+	res := Result{Limit: policy.Limit}
+	if e.tokens >= 1 {
+		e.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration(math.Ceil((1 - e.tokens) / rate))
+	}
+	res.Remaining = int(e.tokens)
+	res.ResetAfter = time.Duration(math.Ceil((capacity - e.tokens) / rate))
+
+	return res
+}
+
+func (e *memoryEntry) hit(now time.Time, policy Policy) Result {
+	window := policy.window()
+
+	// 移除窗口外的请求
+	i := 0
+	for i < len(e.hits) && now.Sub(e.hits[i]) >= window {
+		i++
+	}
+	e.hits = e.hits[i:]
+
+	res := Result{Limit: policy.Limit}
+	if len(e.hits) < policy.Limit {
+		e.hits = append(e.hits, now)
+		res.Allowed = true
+	}
+	res.Remaining = policy.Limit - len(e.hits)
+	res.ResetAfter = e.hits[0].Add(window).Sub(now)
+	if !res.Allowed {
+		res.RetryAfter = res.ResetAfter
+	}
+
+	return res
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"github.com/mittacy/blogBack/utils"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// tokenBucketScript 令牌桶
+// KEYS[1] 桶键
+// ARGV[1] 桶容量，ARGV[2] 装满整个桶的时长(毫秒)，ARGV[3] 当前时间(毫秒)
+// 返回 {是否允许, 剩余令牌数, 重试等待毫秒数, 装满等待毫秒数}
+var tokenBucketScript = redis.NewScript(1, `
+local capacity = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local rate = capacity / window
+
+local bucket = redis.call('hmget', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if not tokens then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('hmset', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('pexpire', KEYS[1], window)
+
+return {allowed, math.floor(tokens), retry, math.ceil((capacity - tokens) / rate)}
+`)
+
+// slidingWindowScript 滑动窗口日志，有序集合中保存窗口内每次请求的时间
+// KEYS[1] 窗口键
+// ARGV[1] 窗口内最多请求数，ARGV[2] 窗口时长(毫秒)，ARGV[3] 当前时间(毫秒)，ARGV[4] 本次请求的唯一标识
+// 返回 {是否允许, 剩余请求数, 重试等待毫秒数, 窗口恢复等待毫秒数}
+var slidingWindowScript = redis.NewScript(1, `
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+redis.call('zremrangebyscore', KEYS[1], '-inf', now - window)
+local count = redis.call('zcard', KEYS[1])
+
+local allowed = 0
+if count < limit then
+	redis.call('zadd', KEYS[1], now, ARGV[4])
+	redis.call('pexpire', KEYS[1], window)
+	count = count + 1
+	allowed = 1
+end
+
+local oldest = redis.call('zrange', KEYS[1], 0, 0, 'withscores')
+local reset = 0
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window - now
+end
+
+local retry = 0
+if allowed == 0 then
+	retry = reset
+end
+
+return {allowed, limit - count, retry, reset}
+`)
+
+type redisLimiter struct {
+	cache cache.CustomRedis
+}
+
+// NewRedis 创建使用redis保存额度的限流器，多个实例共享额度
+// @param customRedis redis操作句柄
+// @return Limiter
+func NewRedis(customRedis cache.CustomRedis) Limiter {
+	return &redisLimiter{cache: customRedis}
+}
+
+func (ctl *redisLimiter) Allow(key string, policy Policy) (Result, error) {
+	return ctl.allow(key, policy, time.Now())
+}
+
+// allow 以指定的当前时间消耗一次请求额度
+func (ctl *redisLimiter) allow(key string, policy Policy, at time.Time) (Result, error) {
+	conn, err := ctl.cache.GetConn()
+	if err != nil {
+		return Result{}, errors.WithStack(err)
+	}
+	defer conn.Close()
+
+	now := at.UnixNano() / int64(time.Millisecond)
+	window := policy.window().Milliseconds()
+	redisKey := fmt.Sprintf("%s:%s:%s", ctl.cache.CachePrefixKey(), policy.Algorithm, key)
+
+	var reply []int64
+	switch policy.Algorithm {
+	case AlgorithmTokenBucket:
+		reply, err = redis.Int64s(tokenBucketScript.Do(conn, redisKey, policy.Limit, window, now))
+	default:
+		var member string
+		if member, err = utils.RandToken(8); err != nil {
+			return Result{}, errors.WithStack(err)
+		}
+		reply, err = redis.Int64s(slidingWindowScript.Do(conn, redisKey, policy.Limit, window, now, member))
+	}
+	if err != nil {
+		return Result{}, errors.WithStack(err)
+	}
+	if len(reply) != 4 {
+		return Result{}, errors.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	return Result{
+		Allowed:    reply[0] == 1,
+		Limit:      policy.Limit,
+		Remaining:  int(reply[1]),
+		RetryAfter: time.Duration(reply[2]) * time.Millisecond,
+		ResetAfter: time.Duration(reply[3]) * time.Millisecond,
+	}, nil
+}
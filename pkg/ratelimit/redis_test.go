@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gomodule/redigo/redis"
+	"github.com/mittacy/blogBack/pkg/store/cache"
+	"testing"
+	"time"
+)
+
+// newTestRedisLimiter 使用miniredis创建redis限流器
+func newTestRedisLimiter(t *testing.T) (*redisLimiter, *miniredis.Miniredis) {
+	s := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", s.Addr())
+	}}
+	t.Cleanup(func() { pool.Close() })
+
+	return NewRedis(cache.ConnRedisByPool(pool, "rateLimit")).(*redisLimiter), s
+}
+
+type allowCase struct {
+	after     time.Duration // 距离第一次请求的时间
+	allowed   bool
+	remaining int
+	retry     time.Duration
+	reset     time.Duration
+}
+
+func checkAllow(t *testing.T, limiter *redisLimiter, key string, policy Policy, start time.Time, cases []allowCase) {
+	for i, c := range cases {
+		res, err := limiter.allow(key, policy, start.Add(c.after))
+		if err != nil {
+			t.Fatalf("#%d allow() error = %v", i, err)
+		}
+		if res.Allowed != c.allowed || res.Remaining != c.remaining || res.Limit != policy.Limit {
+			t.Errorf("#%d allow() = %+v, want allowed %v remaining %d", i, res, c.allowed, c.remaining)
+		}
+		// 浮点误差最多1毫秒
+		if d := res.RetryAfter - c.retry; d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("#%d allow() RetryAfter = %s, want %s", i, res.RetryAfter, c.retry)
+		}
+		if d := res.ResetAfter - c.reset; d < -time.Millisecond || d > time.Millisecond {
+			t.Errorf("#%d allow() ResetAfter = %s, want %s", i, res.ResetAfter, c.reset)
+		}
+	}
+}
+
+func TestRedisTokenBucket(t *testing.T) {
+	limiter, s := newTestRedisLimiter(t)
+	policy := Policy{Algorithm: AlgorithmTokenBucket, Key: KeyByIp, Limit: 3, Window: 3}
+	start := time.Unix(1700000000, 0)
+
+	// 每秒恢复一个令牌
+	checkAllow(t, limiter, "10.0.0.1", policy, start, []allowCase{
+		{0, true, 2, 0, time.Second},
+		{0, true, 1, 0, 2 * time.Second},
+		{0, true, 0, 0, 3 * time.Second},
+		{0, false, 0, time.Second, 3 * time.Second},
+		{500 * time.Millisecond, false, 0, 500 * time.Millisecond, 2500 * time.Millisecond},
+		{time.Second, true, 0, 0, 3 * time.Second},
+		// 长时间没有请求，令牌数不超过桶容量
+		{time.Hour, true, 2, 0, time.Second},
+	})
+
+	if ttl := s.TTL(limiter.cache.CachePrefixKey() + ":token_bucket:10.0.0.1"); ttl != 3*time.Second {
+		t.Errorf("bucket ttl = %s, want 3s", ttl)
+	}
+
+	// 不同的键额度独立
+	checkAllow(t, limiter, "10.0.0.2", policy, start, []allowCase{
+		{0, true, 2, 0, time.Second},
+	})
+}
+
+func TestRedisSlidingWindow(t *testing.T) {
+	limiter, s := newTestRedisLimiter(t)
+	policy := Policy{Algorithm: AlgorithmSlidingWindow, Key: KeyByIp, Limit: 3, Window: 1}
+	start := time.Unix(1700000000, 0)
+
+	checkAllow(t, limiter, "10.0.0.1", policy, start, []allowCase{
+		{0, true, 2, 0, time.Second},
+		{100 * time.Millisecond, true, 1, 0, 900 * time.Millisecond},
+		{200 * time.Millisecond, true, 0, 0, 800 * time.Millisecond},
+		// 被拒绝的请求不占用额度
+		{300 * time.Millisecond, false, 0, 700 * time.Millisecond, 700 * time.Millisecond},
+		{999 * time.Millisecond, false, 0, time.Millisecond, time.Millisecond},
+		// 第一次请求移出窗口
+		{time.Second, true, 0, 0, 100 * time.Millisecond},
+		{1050 * time.Millisecond, false, 0, 50 * time.Millisecond, 50 * time.Millisecond},
+		// 第二、三次请求移出窗口
+		{1200 * time.Millisecond, true, 1, 0, 800 * time.Millisecond},
+	})
+
+	if ttl := s.TTL(limiter.cache.CachePrefixKey() + ":sliding_window:10.0.0.1"); ttl != time.Second {
+		t.Errorf("window ttl = %s, want 1s", ttl)
+	}
+
+	checkAllow(t, limiter, "10.0.0.2", policy, start, []allowCase{
+		{0, true, 2, 0, time.Second},
+	})
+}
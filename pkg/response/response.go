@@ -64,3 +64,8 @@ func Unauthorized(c *gin.Context) {
 func Forbidden(c *gin.Context) {
 	Custom(c, http.StatusForbidden, 401, "权限不足", nil)
 }
+
+// TooManyRequests 请求过于频繁响应
+func TooManyRequests(c *gin.Context) {
+	Custom(c, http.StatusTooManyRequests, 429, "请求过于频繁，请稍后再试", nil)
+}
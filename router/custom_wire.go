@@ -1,38 +1,57 @@
 package router
 
 import (
+	"fmt"
 	"github.com/gomodule/redigo/redis"
 	"github.com/mittacy/blogBack/app/api"
 	"github.com/mittacy/blogBack/app/data"
 	"github.com/mittacy/blogBack/app/model"
 	"github.com/mittacy/blogBack/app/service"
 	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/spf13/viper"
 	"gorm.io/gorm"
+	"time"
 )
 
-func InitUserApi(db *gorm.DB, cache *redis.Pool, conf model.EmailConfig) api.User {
+func InitUser(db *gorm.DB, cache *redis.Pool, rbacService *service.Rbac, conf model.EmailConfig, codeConf model.EmailCodeConfig,
+	resetConf model.PasswordResetConfig, guardConf model.LoginGuardConfig) *service.User {
 	customLogger := logger.NewCustomLogger("user")
 	userData := data.NewUser(db, cache, customLogger)
-	emailData := data.NewEmail(db, cache, conf, customLogger)
-	userService := service.NewUser(userData, emailData, customLogger)
-	userApi := api.NewUser(userService, customLogger)
-	return userApi
+	emailData := data.NewEmail(db, cache, conf, codeConf, customLogger)
+	guardData := data.NewLoginGuard(cache, guardConf, customLogger)
+	return service.NewUser(userData, emailData, guardData, rbacService, resetConf, guardConf, customLogger)
 }
 
-func InitEmailApi(db *gorm.DB, cache *redis.Pool, conf model.EmailConfig) api.Email {
+func InitUserApi(userService *service.User, rbacService *service.Rbac) api.User {
+	customLogger := logger.NewCustomLogger("user")
+	return api.NewUser(userService, rbacService, customLogger)
+}
+
+func InitEmailApi(db *gorm.DB, cache *redis.Pool, conf model.EmailConfig, codeConf model.EmailCodeConfig) api.Email {
 	customLogger := logger.NewCustomLogger("email")
-	emailData := data.NewEmail(db, cache, conf, customLogger)
+	emailData := data.NewEmail(db, cache, conf, codeConf, customLogger)
 	emailService := service.NewEmail(emailData, customLogger)
 	emailApi := api.NewEmail(emailService, customLogger)
 	return emailApi
 }
 
-func InitAdminApi(db *gorm.DB) api.Admin {
-	customLogger := logger.NewCustomLogger("admin")
-	adminData := data.NewAdmin(db, customLogger)
-	adminService := service.NewAdmin(adminData, customLogger)
-	adminApi := api.NewAdmin(adminService, customLogger)
-	return adminApi
+func InitSessionApi(db *gorm.DB, cache *redis.Pool) api.Session {
+	customLogger := logger.NewCustomLogger("session")
+	userData := data.NewSessionUser(db, cache, customLogger)
+	sessionService := service.NewSession(userData, customLogger)
+	sessionApi := api.NewSession(sessionService, customLogger)
+	return sessionApi
+}
+
+func InitRbac(db *gorm.DB, cacheExpire time.Duration) *service.Rbac {
+	customLogger := logger.NewCustomLogger("rbac")
+	rbacData := data.NewRbac(db, cacheExpire, customLogger)
+	return service.NewRbac(rbacData, customLogger)
+}
+
+func InitRbacApi(rbacService *service.Rbac) api.Rbac {
+	customLogger := logger.NewCustomLogger("rbac")
+	return api.NewRbac(rbacService, customLogger)
 }
 
 func InitCategoryApi(db *gorm.DB) api.Category {
@@ -43,11 +62,153 @@ func InitCategoryApi(db *gorm.DB) api.Category {
 	return categoryApi
 }
 
-func InitArticleApi(db *gorm.DB, cache *redis.Pool) api.Article {
+func InitArticleApi(db *gorm.DB, cache *redis.Pool, statConf model.ViewStatConfig) api.Article {
 	customLogger := logger.NewCustomLogger("article")
 	categoryData := data.NewArticleCategory(db, customLogger)
+	tagData := data.NewArticleTag(db, customLogger)
 	articleData := data.NewArticle(db, cache, customLogger)
-	articleService := service.NewArticle(articleData, categoryData, customLogger)
+	articleSearch := data.NewArticleSearch(db, customLogger)
+	revisionData := data.NewArticleRevision(db, customLogger)
+	viewTracker := data.NewArticleViewTracker(cache, statConf, customLogger)
+	articleService := service.NewArticle(articleData, categoryData, tagData, revisionData, articleSearch, viewTracker, customLogger)
 	articleApi := api.NewArticle(articleService, customLogger)
 	return articleApi
 }
+
+func InitStatApi(cache *redis.Pool, statConf model.ViewStatConfig) api.Stat {
+	customLogger := logger.NewCustomLogger("stat")
+	statData := data.NewStat(cache, statConf, customLogger)
+	statService := service.NewStat(statData, customLogger)
+	statApi := api.NewStat(statService, customLogger)
+	return statApi
+}
+
+func InitFeedApi(db *gorm.DB, cache *redis.Pool, conf model.FeedConfig) api.Feed {
+	customLogger := logger.NewCustomLogger("feed")
+	articleData := data.NewFeedArticle(db, cache, customLogger)
+	categoryData := data.NewArticleCategory(db, customLogger)
+	feedService := service.NewFeed(articleData, categoryData, conf, customLogger)
+	feedApi := api.NewFeed(feedService, customLogger)
+	return feedApi
+}
+
+func InitSiteApi(db *gorm.DB, cache *redis.Pool, link string, robots model.RobotsConfig) api.Site {
+	customLogger := logger.NewCustomLogger("site")
+	articleData := data.NewSiteArticle(db, cache, customLogger)
+	categoryData := data.NewArticleCategory(db, customLogger)
+	siteService := service.NewSite(articleData, categoryData, link, robots, customLogger)
+	siteApi := api.NewSite(siteService, customLogger)
+	return siteApi
+}
+
+func InitUploadApi(db *gorm.DB, conf model.UploadConfig) api.Upload {
+	customLogger := logger.NewCustomLogger("upload")
+	uploadData := data.NewUpload(db, customLogger)
+	storage := newStorage(conf)
+	uploadService := service.NewUpload(uploadData, storage, conf, customLogger)
+	uploadApi := api.NewUpload(uploadService, customLogger)
+	return uploadApi
+}
+
+func InitCommentApi(db *gorm.DB, cache *redis.Pool) api.Comment {
+	customLogger := logger.NewCustomLogger("comment")
+	commentData := data.NewComment(db, cache, customLogger)
+	articleData := data.NewCommentArticle(db, cache, customLogger)
+	userData := data.NewCommentUser(db, cache, customLogger)
+	commentService := service.NewComment(commentData, articleData, userData, customLogger)
+	commentApi := api.NewComment(commentService, customLogger)
+	return commentApi
+}
+
+func InitTagApi(db *gorm.DB, cache *redis.Pool) api.Tag {
+	customLogger := logger.NewCustomLogger("tag")
+	tagData := data.NewTag(db, customLogger)
+	articleData := data.NewTagArticle(db, cache, customLogger)
+	tagService := service.NewTag(tagData, articleData, customLogger)
+	tagApi := api.NewTag(tagService, customLogger)
+	return tagApi
+}
+
+func InitArticleScheduler(db *gorm.DB, cache *redis.Pool) *service.ArticleScheduler {
+	interval := viper.GetDuration("job.articlePublishInterval") * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	customLogger := logger.NewCustomLogger("articleScheduler")
+	articleData := data.NewArticleSchedule(db, cache, customLogger)
+	categoryData := data.NewArticleCategory(db, customLogger)
+	articleSearch := data.NewArticleSearch(db, customLogger)
+	return service.NewArticleScheduler(articleData, categoryData, articleSearch, interval, customLogger)
+}
+
+func InitArticleViewFlusher(db *gorm.DB, cache *redis.Pool) *service.ArticleViewFlusher {
+	interval := viper.GetDuration("job.articleViewFlushInterval") * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	customLogger := logger.NewCustomLogger("articleViewFlusher")
+	articleData := data.NewArticleView(db, cache, customLogger)
+	return service.NewArticleViewFlusher(articleData, interval, customLogger)
+}
+
+func InitUploadCleaner(db *gorm.DB) *service.UploadCleaner {
+	conf := model.UploadConfig{}
+	if err := viper.UnmarshalKey("upload", &conf); err != nil {
+		panic(fmt.Sprintf("checkout the upload config: %s\n", err))
+	}
+
+	grace := time.Duration(conf.OrphanGrace) * time.Second
+	if grace <= 0 {
+		grace = 24 * time.Hour
+	}
+	interval := time.Duration(conf.CleanInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	customLogger := logger.NewCustomLogger("uploadCleaner")
+	uploadData := data.NewUploadClean(db, customLogger)
+	return service.NewUploadCleaner(uploadData, newStorage(conf), grace, interval, customLogger)
+}
+
+func InitAuditApi(db *gorm.DB) api.Audit {
+	customLogger := logger.NewCustomLogger("audit")
+	auditData := data.NewAudit(db, customLogger)
+	auditService := service.NewAudit(auditData, customLogger)
+	auditApi := api.NewAudit(auditService, customLogger)
+	return auditApi
+}
+
+func InitAuditWriter(db *gorm.DB) *service.AuditWriter {
+	conf := model.AuditConfig{}
+	if err := viper.UnmarshalKey("audit", &conf); err != nil {
+		panic(fmt.Sprintf("checkout the audit config: %s\n", err))
+	}
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1024
+	}
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 100
+	}
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = 1
+	}
+
+	customLogger := logger.NewCustomLogger("auditWriter")
+	auditData := data.NewAuditWrite(db, customLogger)
+	return service.NewAuditWriter(auditData, conf, customLogger)
+}
+
+// newStorage 根据配置创建文件存储
+func newStorage(conf model.UploadConfig) service.IStorage {
+	switch conf.Storage {
+	case model.StorageLocal:
+		return data.NewLocalStorage(conf.Local)
+	case model.StorageS3:
+		return data.NewS3Storage(conf.S3)
+	default:
+		panic(fmt.Sprintf("checkout the upload config: unknown storage %s\n", conf.Storage))
+	}
+}
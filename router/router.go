@@ -8,6 +8,7 @@ import (
 	"github.com/mittacy/blogBack/middleware"
 	"github.com/mittacy/blogBack/pkg/config"
 	"github.com/mittacy/blogBack/pkg/logger"
+	"github.com/mittacy/blogBack/pkg/ratelimit"
 	"github.com/mittacy/blogBack/pkg/store/cache"
 	"github.com/mittacy/blogBack/pkg/store/db"
 	"github.com/spf13/viper"
@@ -20,28 +21,141 @@ func InitRouter(r *gin.Engine) {
 		panic(fmt.Sprintf("checkout the email config: %s\n", err))
 	}
 
+	codeConf := model.EmailCodeConfig{}
+	if err := viper.UnmarshalKey("emailCode", &codeConf); err != nil {
+		panic(fmt.Sprintf("checkout the emailCode config: %s\n", err))
+	}
+	if codeConf.Expire <= 0 || codeConf.Window <= 0 || codeConf.MaxAttempts <= 0 || codeConf.EmailLimit <= 0 || codeConf.IpLimit <= 0 {
+		panic("checkout the emailCode config: expire, window, maxAttempts, emailLimit and ipLimit must be greater than 0\n")
+	}
+	if codeConf.Cooldown <= 0 {
+		codeConf.Cooldown = 60
+	}
+
+	resetConf := model.PasswordResetConfig{}
+	if err := viper.UnmarshalKey("passwordReset", &resetConf); err != nil {
+		panic(fmt.Sprintf("checkout the passwordReset config: %s\n", err))
+	}
+	if resetConf.Expire <= 0 || resetConf.Link == "" {
+		panic("checkout the passwordReset config: expire must be greater than 0 and link cannot be empty\n")
+	}
+
+	guardConf := model.LoginGuardConfig{}
+	if err := viper.UnmarshalKey("loginGuard", &guardConf); err != nil {
+		panic(fmt.Sprintf("checkout the loginGuard config: %s\n", err))
+	}
+	if guardConf.Window <= 0 || guardConf.MaxFailures <= 0 || guardConf.IpMaxFailures <= 0 || guardConf.LockDuration <= 0 {
+		panic("checkout the loginGuard config: window, maxFailures, ipMaxFailures and lockDuration must be greater than 0\n")
+	}
+	if guardConf.BackoffBase <= 0 {
+		guardConf.BackoffBase = 1
+	}
+	if guardConf.BackoffMax < guardConf.BackoffBase {
+		guardConf.BackoffMax = guardConf.BackoffBase
+	}
+
+	statConf := model.ViewStatConfig{}
+	if err := viper.UnmarshalKey("viewStat", &statConf); err != nil {
+		panic(fmt.Sprintf("checkout the viewStat config: %s\n", err))
+	}
+	if statConf.Window <= 0 || statConf.RetentionDays <= 0 {
+		panic("checkout the viewStat config: window and retentionDays must be greater than 0\n")
+	}
+
+	siteLink := viper.GetString("site.link")
+
+	feedConf := model.FeedConfig{}
+	if err := viper.UnmarshalKey("feed", &feedConf); err != nil {
+		panic(fmt.Sprintf("checkout the feed config: %s\n", err))
+	}
+	feedConf.Link = siteLink
+	if feedConf.Size <= 0 {
+		feedConf.Size = 20
+	}
+
+	robotsConf := model.RobotsConfig{}
+	if err := viper.UnmarshalKey("robots", &robotsConf); err != nil {
+		panic(fmt.Sprintf("checkout the robots config: %s\n", err))
+	}
+
+	uploadConf := model.UploadConfig{}
+	if err := viper.UnmarshalKey("upload", &uploadConf); err != nil {
+		panic(fmt.Sprintf("checkout the upload config: %s\n", err))
+	}
+	if uploadConf.MaxSize <= 0 || uploadConf.ThumbWidth <= 0 || uploadConf.ThumbPixels <= 0 {
+		panic("checkout the upload config: maxSize, thumbWidth and thumbPixels must be greater than 0\n")
+	}
+
+	rbacExpire := viper.GetDuration("rbac.cacheExpire") * time.Second
+	if rbacExpire <= 0 {
+		rbacExpire = time.Minute
+	}
+
+	rateLimitConf := ratelimit.Config{}
+	if err := viper.UnmarshalKey("rateLimit", &rateLimitConf); err != nil {
+		panic(fmt.Sprintf("checkout the rateLimit config: %s\n", err))
+	}
+
 	// 1. 初始化控制器
-	emailApi := InitEmailApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"), emailConf)
-	userApi := InitUserApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"), emailConf)
-	adminApi := InitAdminApi(db.ConnectGorm("blog"))
+	limiter := ratelimit.NewFallback(ratelimit.NewRedis(cache.ConnRedisByPool(cache.ConnRedis("blog"), "ratelimit")), ratelimit.NewMemory())
+	middleware.InitRateLimiter(limiter, rateLimitConf)
+
+	rbacService := InitRbac(db.ConnectGorm("blog"), rbacExpire)
+	if err := rbacService.Init(); err != nil {
+		panic(fmt.Sprintf("init rbac err: %s\n", err))
+	}
+	middleware.InitAuthorizer(rbacService)
+	rbacApi := InitRbacApi(rbacService)
+
+	emailApi := InitEmailApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"), emailConf, codeConf)
+	userService := InitUser(db.ConnectGorm("blog"), cache.ConnRedis("blog"), rbacService, emailConf, codeConf, resetConf, guardConf)
+	middleware.InitBanChecker(userService)
+	userApi := InitUserApi(userService, rbacService)
+	sessionApi := InitSessionApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"))
 	categoryApi := InitCategoryApi(db.ConnectGorm("blog"))
-	articleApi := InitArticleApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"))
+	articleApi := InitArticleApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"), statConf)
+	commentApi := InitCommentApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"))
+	tagApi := InitTagApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"))
+	statApi := InitStatApi(cache.ConnRedis("blog"), statConf)
+	feedApi := InitFeedApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"), feedConf)
+	siteApi := InitSiteApi(db.ConnectGorm("blog"), cache.ConnRedis("blog"), siteLink, robotsConf)
+	uploadApi := InitUploadApi(db.ConnectGorm("blog"), uploadConf)
+	auditApi := InitAuditApi(db.ConnectGorm("blog"))
 
 	// 2. 全局中间件
+	// 客户端ip只由RealIP按可信代理解析，gin不再自行读取转发头，避免伪造X-Forwarded-For绕过按ip的限制
+	r.ForwardedByClientIP = false
+	r.TrustedProxies = nil
+	r.Use(middleware.RealIP(config.ServerConfig.TrustedProxies))
 	r.Use(ginzap.Ginzap(logger.GetRequestLogger(), time.RFC3339, true))
 	r.Use(ginzap.RecoveryWithZap(logger.GetRequestLogger(), true))
 	r.Use(middleware.CorsMiddleware())
 
 	// 3. 初始化路由
+	// 订阅源、sitemap和robots.txt，约定放在站点根路径
+	r.GET("/feed.xml", feedApi.RSS)
+	r.GET("/atom.xml", feedApi.Atom)
+	r.GET("/category/:id/feed.xml", feedApi.CategoryRSS)
+	r.GET("/sitemap.xml", siteApi.Sitemap)
+	r.GET("/sitemap/:page", siteApi.SitemapPage)
+	r.GET("/robots.txt", siteApi.Robots)
+
+	// 使用本地存储时由服务直接提供上传的文件
+	if uploadConf.Storage == model.StorageLocal {
+		r.Static(uploadConf.Local.BaseUrl, uploadConf.Local.Dir)
+	}
+
 	relativePath := "/api/" + config.ServerConfig.Version
 	g := r.Group(relativePath) // 统一前缀
+	g.Use(middleware.RateLimit("api"))
 	{
 		/**
 		 * 不需要登录的Api
 		 */
 		// 登录
-		g.POST("/session/admin/login", adminApi.Login)
-		g.POST("/session/user/login", userApi.Login)
+		g.POST("/session/login", middleware.RateLimit("session"), userApi.Login)
+		g.POST("/session/refresh", middleware.RateLimit("session"), sessionApi.Refresh)
+		g.DELETE("/session", sessionApi.Logout)
 
 		// 邮件
 		email := g.Group("/email")
@@ -49,39 +163,114 @@ func InitRouter(r *gin.Engine) {
 			email.GET("/register_code", emailApi.GetRegisterCode)
 		}
 
+		// 找回密码
+		password := g.Group("/password")
+		password.Use(middleware.RateLimit("password"))
+		{
+			password.POST("/forgot", userApi.ForgotPassword)
+			password.POST("/reset", userApi.ResetPassword)
+		}
+
 		// 用户
 		user := g.Group("/user")
 		{
 			user.POST("", userApi.Register)
-			user.GET("/:id", userApi.GetInfo)
+			user.GET("/:id", middleware.TryParseToken(), userApi.GetInfo)
 		}
 
 		// 分类
 		g.GET("/categories", categoryApi.List)
 
+		// 标签
+		g.GET("/tags", tagApi.List)
+
 		// 文章
-		g.GET("/article/:id", articleApi.Get)
+		g.GET("/article/:id", middleware.TryParseToken(), articleApi.Get)
 		g.GET("/articles", articleApi.List)
+		g.GET("/articles/search", articleApi.Search)
 		g.GET("/articles_home", articleApi.HomeList)
 
+		// 评论
+		g.GET("/article/:id/comments", commentApi.List)
+
 		/**
 		 * 需要登录的Api
 		 */
 		needAuth := g.Group("")
-		needAuth.Use(middleware.ParseToken())
+		needAuth.Use(middleware.ParseToken(), middleware.RateLimit("auth"))
 		{
+			// 当前用户
+			needAuth.PUT("/user/me", userApi.UpdateProfile)
+			needAuth.PUT("/user/me/password", userApi.UpdatePassword)
+			needAuth.PUT("/user/me/privacy", userApi.UpdatePrivacy)
+			needAuth.DELETE("/user/me", userApi.Delete)
+			needAuth.POST("/user/me/email/code", userApi.SendChangeEmailCode)
+			needAuth.PUT("/user/me/email", userApi.ChangeEmail)
+
+			// 登录设备
+			needAuth.GET("/sessions", sessionApi.List)
+			needAuth.DELETE("/sessions/:id", sessionApi.Revoke)
+			needAuth.DELETE("/user/:id/sessions", middleware.Operate(model.PermSessionRevokeUser), sessionApi.RevokeUser)
+			needAuth.DELETE("/user/:id/lock", middleware.Operate(model.PermUserUnlock), userApi.Unlock)
+
+			// 用户管理
+			needAuth.GET("/users/admin", middleware.Operate(model.PermUserList), userApi.AdminList)
+			needAuth.GET("/user/:id/admin", middleware.Operate(model.PermUserList), userApi.AdminGet)
+			needAuth.PUT("/user/:id/ban", middleware.Operate(model.PermUserBan), userApi.Ban)
+			needAuth.DELETE("/user/:id/ban", middleware.Operate(model.PermUserBan), userApi.Unban)
+			needAuth.POST("/user/:id/password/reset", middleware.Operate(model.PermUserResetPassword), userApi.ForceResetPassword)
+
 			authCategory := needAuth.Group("/category")
 			{
-				authCategory.POST("", middleware.Operate(middleware.ActionAddCategory), categoryApi.Create)
-				authCategory.DELETE("/:id", middleware.Operate(middleware.ActionDeleteCategory), categoryApi.Delete)
-				authCategory.PUT("", middleware.Operate(middleware.ActionPutCategory), categoryApi.Update)
+				authCategory.POST("", middleware.Operate(model.PermCategoryCreate), categoryApi.Create)
+				authCategory.DELETE("/:id", middleware.Operate(model.PermCategoryDelete), categoryApi.Delete)
+				authCategory.PUT("", middleware.Operate(model.PermCategoryUpdate), categoryApi.Update)
+				authCategory.GET("/:id/stats", middleware.Operate(model.PermStatList), statApi.CategoryDaily)
+			}
+
+			// 角色权限
+			needAuth.GET("/permissions", middleware.Operate(model.PermRoleList), rbacApi.ListPermissions)
+			needAuth.GET("/roles", middleware.Operate(model.PermRoleList), rbacApi.ListRoles)
+			needAuth.POST("/role", middleware.Operate(model.PermRoleManage), rbacApi.CreateRole)
+			needAuth.PUT("/role", middleware.Operate(model.PermRoleManage), rbacApi.UpdateRole)
+			needAuth.DELETE("/role/:id", middleware.Operate(model.PermRoleManage), rbacApi.DeleteRole)
+			needAuth.GET("/user_roles", middleware.Operate(model.PermRoleList), rbacApi.ListUserRoles)
+			needAuth.PUT("/user_roles", middleware.Operate(model.PermRoleManage), rbacApi.SetUserRoles)
+
+			// 审计日志
+			needAuth.GET("/audit_logs", middleware.Operate(model.PermAuditList), auditApi.List)
+			needAuth.GET("/audit_logs/export", middleware.Operate(model.PermAuditList), auditApi.Export)
+
+			needAuth.GET("/articles/admin", middleware.Operate(model.PermArticleListAll), articleApi.AdminList)
+
+			authTag := needAuth.Group("/tag")
+			{
+				authTag.POST("", middleware.Operate(model.PermTagCreate), tagApi.Create)
+				authTag.DELETE("/:id", middleware.Operate(model.PermTagDelete), tagApi.Delete)
+				authTag.PUT("", middleware.Operate(model.PermTagUpdate), tagApi.Update)
 			}
 
 			authArticle := needAuth.Group("/article")
 			{
-				authArticle.POST("", middleware.Operate(middleware.ActionAddArticle), articleApi.Create)
-				authArticle.DELETE("/:id", middleware.Operate(middleware.ActionDeleteArticle), articleApi.Delete)
-				authArticle.PUT("", middleware.Operate(middleware.ActionPutArticle), articleApi.Update)
+				authArticle.POST("", middleware.Operate(model.PermArticleCreate), articleApi.Create)
+				authArticle.DELETE("/:id", middleware.Operate(model.PermArticleDelete), articleApi.Delete)
+				authArticle.PUT("", middleware.Operate(model.PermArticleUpdate), articleApi.Update)
+				authArticle.GET("/:id/admin", middleware.Operate(model.PermArticleListAll), articleApi.AdminGet)
+				authArticle.GET("/:id/stats", middleware.Operate(model.PermStatList), statApi.ArticleDaily)
+				authArticle.GET("/:id/revisions", middleware.Operate(model.PermArticleRevisionList), articleApi.ListRevisions)
+				authArticle.GET("/:id/revisions/diff", middleware.Operate(model.PermArticleRevisionList), articleApi.DiffRevisions)
+				authArticle.POST("/:id/revisions/restore", middleware.Operate(model.PermArticleRevisionRestore), articleApi.RestoreRevision)
+
+				authArticle.POST("/:id/comments", commentApi.Create)
+				authArticle.GET("/:id/comments/all", middleware.Operate(model.PermCommentListAll), commentApi.ListAll)
+			}
+
+			needAuth.POST("/upload", middleware.Operate(model.PermUploadCreate), middleware.RateLimit("upload"), uploadApi.Upload)
+
+			authComment := needAuth.Group("/comment")
+			{
+				authComment.PUT("", middleware.Operate(model.PermCommentUpdate), commentApi.Update)
+				authComment.DELETE("/:id", middleware.Operate(model.PermCommentDelete), commentApi.Delete)
 			}
 		}
 	}
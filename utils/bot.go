@@ -0,0 +1,29 @@
+package utils
+
+import "strings"
+
+// botUserAgentKeywords 常见爬虫、监控和命令行工具的User-Agent关键词(小写)
+var botUserAgentKeywords = []string{
+	"bot", "spider", "crawl", "slurp", "scrapy", "curl", "wget", "httpclient",
+	"python-requests", "go-http-client", "okhttp", "java/", "headless", "phantomjs",
+	"facebookexternalhit", "mediapartners", "bingpreview", "feedfetcher", "lighthouse",
+	"pingdom", "uptime", "monitor",
+}
+
+// IsBot 根据User-Agent判断是否为爬虫，空User-Agent也视为爬虫
+// @param userAgent 请求的User-Agent
+// @return bool
+func IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+
+	for _, v := range botUserAgentKeywords {
+		if strings.Contains(ua, v) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,307 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	diffContextLines = 3     // 统一格式差异中每处修改前后保留的上下文行数
+	diffMaxLines     = 10000 // 去掉相同的前缀和后缀后，两段文本合计的最大行数
+)
+
+// ErrDiffTooLarge 需要对比的行数超过上限
+var ErrDiffTooLarge = errors.New("too many lines to diff")
+
+type diffOp struct {
+	kind byte // ' ' 相同, '-' 删除, '+' 新增
+	line string
+}
+
+// UnifiedDiff 按行对比两段文本，生成统一格式(unified)的差异
+// @param fromName 旧文本名
+// @param toName 新文本名
+// @param from 旧文本
+// @param to 新文本
+// @return string 差异内容，两段文本相同时返回空字符串
+// @return error 不同的行数过多时返回ErrDiffTooLarge
+func UnifiedDiff(fromName, toName, from, to string) (string, error) {
+	if from == to {
+		return "", nil
+	}
+
+	ops, err := diffLines(splitLines(from), splitLines(to))
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+
+	// 找出所有修改的位置，合并上下文重叠的修改为一个块
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		start := i - diffContextLines
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+
+			// 相同行超过两倍上下文时结束当前块
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*diffContextLines {
+				end += diffContextLines
+				if end > len(ops) {
+					end = len(ops)
+				}
+				break
+			}
+			end = next
+		}
+
+		writeHunk(&b, ops, start, end)
+		i = end
+	}
+
+	return b.String(), nil
+}
+
+// writeHunk 输出ops[start:end]的差异块
+func writeHunk(b *strings.Builder, ops []diffOp, start, end int) {
+	fromLine, toLine := 1, 1
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			fromLine++
+		}
+		if op.kind != '-' {
+			toLine++
+		}
+	}
+
+	fromCount, toCount := 0, 0
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			fromCount++
+		}
+		if op.kind != '-' {
+			toCount++
+		}
+	}
+
+	// 空范围的起始行号为前一行
+	if fromCount == 0 {
+		fromLine--
+	}
+	if toCount == 0 {
+		toLine--
+	}
+
+	fmt.Fprintf(b, "@@ -%s +%s @@\n", hunkRange(fromLine, fromCount), hunkRange(toLine, toCount))
+	for _, op := range ops[start:end] {
+		b.WriteByte(op.kind)
+		b.WriteString(op.line)
+		b.WriteByte('\n')
+	}
+}
+
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines 使用Myers差异算法计算两组行的差异，只占用线性的内存
+// @return error 去掉相同的前缀和后缀后行数超过diffMaxLines时返回ErrDiffTooLarge
+func diffLines(a, b []string) ([]diffOp, error) {
+	// 去掉相同的前缀和后缀，减少计算量
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(ma)+len(mb) > diffMaxLines {
+		return nil, ErrDiffTooLarge
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, v := range a[:prefix] {
+		ops = append(ops, diffOp{' ', v})
+	}
+	ops = diffRegion(ops, ma, mb)
+	for _, v := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', v})
+	}
+
+	sortChanges(ops)
+	return ops, nil
+}
+
+// diffRegion 计算a和b的差异并追加到ops，在中间蛇的位置将问题一分为二递归计算
+func diffRegion(ops []diffOp, a, b []string) []diffOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{' ', a[prefix]})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	x, y, ok := -1, -1, false
+	if len(a) > 0 && len(b) > 0 {
+		x, y, ok = middleSnake(a, b)
+	}
+
+	if ok {
+		ops = diffRegion(ops, a[:x], b[:y])
+		ops = diffRegion(ops, a[x:], b[y:])
+	} else {
+		for _, v := range a {
+			ops = append(ops, diffOp{'-', v})
+		}
+		for _, v := range b {
+			ops = append(ops, diffOp{'+', v})
+		}
+	}
+
+	for _, v := range common {
+		ops = append(ops, diffOp{' ', v})
+	}
+
+	return ops
+}
+
+// middleSnake 同时从头部和尾部搜索最短编辑路径，返回两个方向相遇的位置
+// v1[k]为正向在对角线k(x-y)上到达的最远x，v2[k]为反向(从末尾往前)在对角线k上到达的最远距离
+// @return int, int 相遇位置，a[:x]和b[:y]、a[x:]和b[y:]分别计算差异即可得到最短差异
+// @return bool a和b没有相同的行时返回false
+func middleSnake(a, b []string) (int, int, bool) {
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	v1 := make([]int, 2*maxD+2)
+	v2 := make([]int, 2*maxD+2)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[offset+1], v2[offset+1] = 0, 0
+
+	delta := n - m
+	// 编辑距离为奇数时在正向搜索中相遇，偶数时在反向搜索中相遇
+	front := delta%2 != 0
+	// 走出边界的对角线不再搜索
+	k1Start, k1End, k2Start, k2End := 0, 0, 0, 0
+
+	for d := 0; d < maxD; d++ {
+		for k1 := -d + k1Start; k1 <= d-k1End; k1 += 2 {
+			i := offset + k1
+			x1 := 0
+			if k1 == -d || (k1 != d && v1[i-1] < v1[i+1]) {
+				x1 = v1[i+1]
+			} else {
+				x1 = v1[i-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			v1[i] = x1
+
+			switch {
+			case x1 > n:
+				k1End += 2
+			case y1 > m:
+				k1Start += 2
+			case front:
+				j := offset + delta - k1
+				if j >= 0 && j < len(v2) && v2[j] != -1 && x1 >= n-v2[j] {
+					return x1, y1, true
+				}
+			}
+		}
+
+		for k2 := -d + k2Start; k2 <= d-k2End; k2 += 2 {
+			i := offset + k2
+			x2 := 0
+			if k2 == -d || (k2 != d && v2[i-1] < v2[i+1]) {
+				x2 = v2[i+1]
+			} else {
+				x2 = v2[i-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-1-x2] == b[m-1-y2] {
+				x2++
+				y2++
+			}
+			v2[i] = x2
+
+			switch {
+			case x2 > n:
+				k2End += 2
+			case y2 > m:
+				k2Start += 2
+			case !front:
+				j := offset + delta - k2
+				if j >= 0 && j < len(v1) && v1[j] != -1 && v1[j] >= n-x2 {
+					x1 := v1[j]
+					return x1, x1 - (j - offset), true
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// sortChanges 相邻的删除和新增行中，删除行排在新增行之前
+func sortChanges(ops []diffOp) {
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(ops) && ops[end].kind != ' ' {
+			end++
+		}
+		sort.SliceStable(ops[i:end], func(x, y int) bool {
+			return ops[i+x].kind == '-' && ops[i+y].kind == '+'
+		})
+		i = end
+	}
+}
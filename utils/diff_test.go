@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{"same", "a\nb\n", "a\nb\n", ""},
+		{"replace", "a\nb\nc\n", "a\nx\nc\n", "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"insert into empty", "", "a\nb\n", "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n"},
+		{"delete all", "a\nb\n", "", "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n"},
+		{
+			"deletions before insertions",
+			"a\nb\nc\nd\n",
+			"a\nx\ny\nd\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n a\n-b\n-c\n+x\n+y\n d\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"x\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ny\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnifiedDiff("old", "new", tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("UnifiedDiff() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("UnifiedDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiffTooLarge(t *testing.T) {
+	from := strings.Repeat("a\n", diffMaxLines/2+1)
+	to := strings.Repeat("b\n", diffMaxLines/2)
+	if _, err := UnifiedDiff("old", "new", from, to); err != ErrDiffTooLarge {
+		t.Errorf("UnifiedDiff() error = %v, want ErrDiffTooLarge", err)
+	}
+
+	// 相同的前缀和后缀不计入行数上限
+	common := strings.Repeat("c\n", diffMaxLines)
+	if _, err := UnifiedDiff("old", "new", common+"a\n"+common, common+"b\n"+common); err != nil {
+		t.Errorf("UnifiedDiff() with long common lines error = %v", err)
+	}
+}
+
+// TestDiffLinesMinimal 随机生成的文本的差异可以还原出两段文本，且修改行数与最长公共子序列一致
+func TestDiffLinesMinimal(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	randLines := func() []string {
+		lines := make([]string, r.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + r.Intn(4)))
+		}
+		return lines
+	}
+
+	for n := 0; n < 2000; n++ {
+		a, b := randLines(), randLines()
+		ops, err := diffLines(a, b)
+		if err != nil {
+			t.Fatalf("diffLines(%q, %q) error = %v", a, b, err)
+		}
+
+		var gotA, gotB []string
+		changes := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				gotA = append(gotA, op.line)
+			}
+			if op.kind != '-' {
+				gotB = append(gotB, op.line)
+			}
+			if op.kind != ' ' {
+				changes++
+			}
+		}
+
+		if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("diffLines(%q, %q) = %v, can not restore the input", a, b, ops)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); changes != want {
+			t.Fatalf("diffLines(%q, %q) changes = %d, want %d", a, b, changes, want)
+		}
+	}
+}
+
+func lcsLength(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else if dp[i+1][j] > dp[i][j+1] {
+				dp[i][j] = dp[i+1][j]
+			} else {
+				dp[i][j] = dp[i][j+1]
+			}
+		}
+	}
+	return dp[0][0]
+}
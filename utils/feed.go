@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Feed 订阅源，用于生成RSS 2.0和Atom
+type Feed struct {
+	Title       string
+	Link        string // 站点地址
+	FeedLink    string // 订阅源自身的地址
+	Description string
+	UpdatedAt   time.Time
+	Items       []FeedItem
+}
+
+type FeedItem struct {
+	Title       string
+	Link        string
+	Description string
+	Category    string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	AtomLink      rssLink   `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Guid        rssGuid `xml:"guid"`
+	Description string  `xml:"description"`
+	Category    string  `xml:"category,omitempty"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS 生成RSS 2.0文档
+// @param feed 订阅源
+// @return []byte xml文档
+// @return error
+func RSS(feed Feed) ([]byte, error) {
+	doc := rss{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         feed.Title,
+			Link:          feed.Link,
+			AtomLink:      rssLink{Href: feed.FeedLink, Rel: "self", Type: "application/rss+xml"},
+			Description:   feed.Description,
+			LastBuildDate: feed.UpdatedAt.Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(feed.Items)),
+		},
+	}
+
+	for _, v := range feed.Items {
+		doc.Channel.Items = append(doc.Channel.Items, rssItem{
+			Title:       v.Title,
+			Link:        v.Link,
+			Guid:        rssGuid{IsPermaLink: true, Value: v.Link},
+			Description: v.Description,
+			Category:    v.Category,
+			PubDate:     v.CreatedAt.Format(time.RFC1123Z),
+		})
+	}
+
+	return marshalXML(doc)
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Id       string      `xml:"id"`
+	Links    []atomLink  `xml:"link"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title     string        `xml:"title"`
+	Id        string        `xml:"id"`
+	Link      atomLink      `xml:"link"`
+	Published string        `xml:"published"`
+	Updated   string        `xml:"updated"`
+	Category  *atomCategory `xml:"category,omitempty"`
+	Summary   atomText      `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom 生成Atom 1.0文档
+// @param feed 订阅源
+// @return []byte xml文档
+// @return error
+func Atom(feed Feed) ([]byte, error) {
+	doc := atomFeed{
+		Title: feed.Title,
+		Id:    feed.FeedLink,
+		Links: []atomLink{
+			{Href: feed.Link},
+			{Href: feed.FeedLink, Rel: "self"},
+		},
+		Subtitle: feed.Description,
+		Updated:  feed.UpdatedAt.Format(time.RFC3339),
+		Entries:  make([]atomEntry, 0, len(feed.Items)),
+	}
+
+	for _, v := range feed.Items {
+		entry := atomEntry{
+			Title:     v.Title,
+			Id:        v.Link,
+			Link:      atomLink{Href: v.Link},
+			Published: v.CreatedAt.Format(time.RFC3339),
+			Updated:   v.UpdatedAt.Format(time.RFC3339),
+			Summary:   atomText{Type: "text", Value: v.Description},
+		}
+		if v.Category != "" {
+			entry.Category = &atomCategory{Term: v.Category}
+		}
+		doc.Entries = append(doc.Entries, entry)
+	}
+
+	return marshalXML(doc)
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	highlightPreTag  = "<em>"
+	highlightPostTag = "</em>"
+)
+
+// Keywords 将搜索语句按空白字符拆分为关键词，去重并转为小写
+// @param q 搜索语句
+// @return []string 关键词
+func Keywords(q string) []string {
+	fields := strings.Fields(strings.ToLower(q))
+	keywords := make([]string, 0, len(fields))
+
+	for _, v := range fields {
+		if IndexOf(keywords, v) == -1 {
+			keywords = append(keywords, v)
+		}
+	}
+
+	return keywords
+}
+
+// Highlight 使用<em>标签包裹文本中出现的关键词，文本其余部分进行html转义
+// @param text 原文本
+// @param keywords 关键词，需为小写
+// @return string 高亮后的文本
+func Highlight(text string, keywords []string) string {
+	runes := []rune(text)
+	return highlightRunes(runes, matchRanges(runes, keywords))
+}
+
+// Snippet 截取文本中第一个关键词附近的片段并高亮
+// @param text 原文本
+// @param keywords 关键词，需为小写
+// @param length 片段长度，单位: 字符
+// @return string 高亮后的片段，文本中没有关键词时返回空字符串
+func Snippet(text string, keywords []string, length int) string {
+	runes := []rune(text)
+	ranges := matchRanges(runes, keywords)
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	// 关键词前保留1/4的上下文
+	start := ranges[0][0] - length/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + length
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	// 只保留完整落在片段内的匹配
+	inner := make([][2]int, 0, len(ranges))
+	for _, v := range ranges {
+		if v[0] >= start && v[1] <= end {
+			inner = append(inner, [2]int{v[0] - start, v[1] - start})
+		}
+	}
+
+	snippet := highlightRunes(runes[start:end], inner)
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet = snippet + "..."
+	}
+
+	return snippet
+}
+
+// matchRanges 查找关键词在文本中出现的位置(忽略大小写)，重叠或相邻的位置会被合并
+// @return [][2]int 按起始位置排序的[start, end)区间，单位: 字符
+func matchRanges(runes []rune, keywords []string) [][2]int {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	var ranges [][2]int
+	for _, keyword := range keywords {
+		k := []rune(keyword)
+		if len(k) == 0 {
+			continue
+		}
+
+		for i := 0; i+len(k) <= len(lower); i++ {
+			if string(lower[i:i+len(k)]) == keyword {
+				ranges = append(ranges, [2]int{i, i + len(k)})
+			}
+		}
+	}
+
+	if len(ranges) == 0 {
+		return ranges
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i][0] < ranges[j][0]
+	})
+
+	merged := [][2]int{ranges[0]}
+	for _, v := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if v[0] <= last[1] {
+			if v[1] > last[1] {
+				last[1] = v[1]
+			}
+			continue
+		}
+		merged = append(merged, v)
+	}
+
+	return merged
+}
+
+func highlightRunes(runes []rune, ranges [][2]int) string {
+	var b strings.Builder
+	prev := 0
+
+	for _, v := range ranges {
+		b.WriteString(html.EscapeString(string(runes[prev:v[0]])))
+		b.WriteString(highlightPreTag)
+		b.WriteString(html.EscapeString(string(runes[v[0]:v[1]])))
+		b.WriteString(highlightPostTag)
+		prev = v[1]
+	}
+	b.WriteString(html.EscapeString(string(runes[prev:])))
+
+	return b.String()
+}
@@ -0,0 +1,874 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TocItem 目录项，按标题在文中出现的顺序排列，由客户端根据Level组织层级
+type TocItem struct {
+	Level int    `json:"level"`
+	Id    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// RenderMarkdown 将Markdown渲染为经过SanitizeHTML过滤的html，并为标题生成锚点id和目录
+// 支持标题、段落、强调、删除线、行内代码、代码块、引用、列表、表格、链接、图片、分割线和内嵌html
+// @param src Markdown文本
+// @return string 过滤后的html
+// @return []TocItem 目录
+func RenderMarkdown(src string) (string, []TocItem) {
+	r := &mdRenderer{
+		ids: make(map[string]int, 0),
+		toc: []TocItem{},
+	}
+
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	r.blocks(strings.Split(src, "\n"), false)
+
+	return SanitizeHTML(r.b.String()), r.toc
+}
+
+type mdRenderer struct {
+	b   strings.Builder
+	ids map[string]int // 已使用的锚点id => 次数
+	toc []TocItem
+}
+
+var (
+	mdAtxHeadingReg   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ ]+(.*?))?(?:[ ]+#+)?[ ]*$`)
+	mdSetextReg       = regexp.MustCompile(`^ {0,3}(=+|-+)[ ]*$`)
+	mdHrReg           = regexp.MustCompile(`^ {0,3}(?:(?:\*[ ]*){3,}|(?:-[ ]*){3,}|(?:_[ ]*){3,})$`)
+	mdFenceReg        = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ ]*([^`]*)$")
+	mdQuoteReg        = regexp.MustCompile(`^ {0,3}>[ ]?`)
+	mdListReg         = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ ]+|$)`)
+	mdHTMLBlockReg    = regexp.MustCompile(`^ {0,3}(?:<!--|</?[A-Za-z][A-Za-z0-9-]*(?:[\s/>]|$))`)
+	mdTableDelimReg   = regexp.MustCompile(`^ {0,3}\|?[ ]*:?-+:?[ ]*(?:\|[ ]*:?-+:?[ ]*)*\|?[ ]*$`)
+	mdAutolinkReg     = regexp.MustCompile(`^<((?:https?://|mailto:)[^<>\s]+)>`)
+	mdInlineHTMLReg   = regexp.MustCompile(`^(?:<!--[\s\S]*?-->|</?[A-Za-z][A-Za-z0-9-]*(?:\s+[A-Za-z_:][\w:.-]*(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?)*\s*/?>)`)
+	mdEntityReg       = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	mdHTMLTagReg      = regexp.MustCompile(`<[^>]*>`)
+	mdIndentedCodeReg = regexp.MustCompile(`^ {4}`)
+)
+
+// blocks 渲染块级元素
+// @param lines 行
+// @param tight 是否为紧凑列表项，紧凑列表项中的段落不使用<p>包裹
+func (r *mdRenderer) blocks(lines []string, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if isBlank(line) {
+			i++
+			continue
+		}
+
+		// 缩进代码块
+		if mdIndentedCodeReg.MatchString(line) {
+			i = r.indentedCode(lines, i)
+			continue
+		}
+
+		if m := mdFenceReg.FindStringSubmatch(line); m != nil {
+			i = r.fencedCode(lines, i, m)
+			continue
+		}
+
+		if m := mdAtxHeadingReg.FindStringSubmatch(line); m != nil {
+			r.heading(len(m[1]), m[2])
+			i++
+			continue
+		}
+
+		if mdHrReg.MatchString(line) {
+			r.b.WriteString("<hr>\n")
+			i++
+			continue
+		}
+
+		if mdQuoteReg.MatchString(line) {
+			i = r.blockquote(lines, i)
+			continue
+		}
+
+		if mdListReg.MatchString(line) {
+			i = r.list(lines, i)
+			continue
+		}
+
+		if mdHTMLBlockReg.MatchString(line) {
+			i = r.htmlBlock(lines, i)
+			continue
+		}
+
+		if i+1 < len(lines) && strings.Contains(line, "|") && mdTableDelimReg.MatchString(lines[i+1]) {
+			if next, ok := r.table(lines, i); ok {
+				i = next
+				continue
+			}
+		}
+
+		i = r.paragraph(lines, i, tight)
+	}
+}
+
+func (r *mdRenderer) heading(level int, text string) {
+	text = strings.TrimSpace(text)
+	content := r.inline(text)
+	// 目录和锚点使用过滤后的文本，不包含被删除的脚本内容
+	title := plainText(SanitizeHTML(content))
+	id := r.anchorId(title)
+
+	if title != "" {
+		r.toc = append(r.toc, TocItem{Level: level, Id: id, Title: title})
+	}
+	fmt.Fprintf(&r.b, "<h%d id=\"%s\">%s</h%d>\n", level, html.EscapeString(id), content, level)
+}
+
+// anchorId 根据标题生成唯一的锚点id，重复的标题依次追加-1、-2
+func (r *mdRenderer) anchorId(title string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '-' || c == '_':
+			b.WriteRune(c)
+		case unicode.IsSpace(c):
+			b.WriteRune('-')
+		}
+	}
+
+	id := strings.Trim(b.String(), "-")
+	if id == "" {
+		id = "section"
+	}
+
+	count := r.ids[id]
+	r.ids[id] = count + 1
+	if count == 0 {
+		return id
+	}
+
+	// 追加序号后可能与其他标题冲突，继续递增
+	for {
+		candidate := fmt.Sprintf("%s-%d", id, count)
+		if _, ok := r.ids[candidate]; !ok {
+			r.ids[candidate] = 1
+			return candidate
+		}
+		count++
+	}
+}
+
+func (r *mdRenderer) indentedCode(lines []string, i int) int {
+	var code []string
+	for ; i < len(lines); i++ {
+		if isBlank(lines[i]) {
+			code = append(code, "")
+			continue
+		}
+		if !mdIndentedCodeReg.MatchString(lines[i]) {
+			break
+		}
+		code = append(code, lines[i][4:])
+	}
+
+	// 去掉末尾的空行
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+
+	r.b.WriteString("<pre><code>")
+	r.b.WriteString(html.EscapeString(strings.Join(code, "\n") + "\n"))
+	r.b.WriteString("</code></pre>\n")
+	return i
+}
+
+func (r *mdRenderer) fencedCode(lines []string, i int, m []string) int {
+	indent, fence := len(m[1]), m[2]
+	lang := strings.Fields(m[3])
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimLeft(lines[i], " ")
+		if strings.HasPrefix(trimmed, fence) && strings.TrimSpace(strings.TrimLeft(trimmed, fence[:1])) == "" {
+			i++
+			break
+		}
+
+		// 去掉与开始标记相同的缩进
+		line := lines[i]
+		for n := 0; n < indent && strings.HasPrefix(line, " "); n++ {
+			line = line[1:]
+		}
+		code = append(code, line)
+	}
+
+	r.b.WriteString("<pre><code")
+	if len(lang) > 0 {
+		fmt.Fprintf(&r.b, " class=\"language-%s\"", html.EscapeString(lang[0]))
+	}
+	r.b.WriteString(">")
+	if len(code) > 0 {
+		r.b.WriteString(html.EscapeString(strings.Join(code, "\n") + "\n"))
+	}
+	r.b.WriteString("</code></pre>\n")
+	return i
+}
+
+func (r *mdRenderer) blockquote(lines []string, i int) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if loc := mdQuoteReg.FindStringIndex(line); loc != nil {
+			inner = append(inner, line[loc[1]:])
+			continue
+		}
+
+		// 段落的延续行
+		if !isBlank(line) && len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !r.startsBlock(line) {
+			inner = append(inner, line)
+			continue
+		}
+		break
+	}
+
+	r.b.WriteString("<blockquote>\n")
+	r.blocks(inner, false)
+	r.b.WriteString("</blockquote>\n")
+	return i
+}
+
+type mdListItem struct {
+	indent int // 内容的缩进
+	lines  []string
+}
+
+func (r *mdRenderer) list(lines []string, i int) int {
+	first := mdListReg.FindStringSubmatch(lines[i])
+	ordered := first[2][0] >= '0' && first[2][0] <= '9'
+	delim := first[2][len(first[2])-1]
+
+	var (
+		items      []mdListItem
+		loose      bool
+		blankAfter bool // 上一行是否为空行
+	)
+
+	for i < len(lines) {
+		line := lines[i]
+		m := mdListReg.FindStringSubmatch(line)
+
+		// 新的列表项，标记类型需要与第一项一致，缩进达到当前列表项内容缩进的是嵌套列表
+		nested := len(items) > 0 && leadingSpaces(line) >= items[len(items)-1].indent
+		if m != nil && !nested && !mdHrReg.MatchString(line) {
+			isOrdered := m[2][0] >= '0' && m[2][0] <= '9'
+			if isOrdered != ordered || m[2][len(m[2])-1] != delim {
+				break
+			}
+
+			if blankAfter && len(items) > 0 {
+				loose = true
+			}
+
+			// 列表项内容的缩进为标记宽度加空格数，空格超过4个时视为1个空格加缩进代码
+			width := len(m[0])
+			if m[3] == "" || len(m[3]) > 4 {
+				width = len(m[1]) + len(m[2]) + 1
+			}
+
+			content := ""
+			if width < len(line) {
+				content = line[width:]
+			}
+			items = append(items, mdListItem{indent: width, lines: []string{content}})
+			blankAfter = false
+			i++
+			continue
+		}
+
+		item := &items[len(items)-1]
+		indent := item.indent
+
+		if isBlank(line) {
+			item.lines = append(item.lines, "")
+			blankAfter = true
+			i++
+			continue
+		}
+
+		// 缩进足够的行属于当前列表项
+		if leadingSpaces(line) >= indent {
+			if blankAfter {
+				// 空行后仍有内容，列表项包含多个块
+				loose = loose || hasContent(item.lines)
+			}
+			item.lines = append(item.lines, line[indent:])
+			blankAfter = false
+			i++
+			continue
+		}
+
+		// 段落的延续行
+		if !blankAfter && !r.startsBlock(line) {
+			item.lines = append(item.lines, strings.TrimLeft(line, " "))
+			i++
+			continue
+		}
+
+		break
+	}
+
+	if ordered {
+		start, _ := strconv.Atoi(first[2][:len(first[2])-1])
+		if start != 1 {
+			fmt.Fprintf(&r.b, "<ol start=\"%d\">\n", start)
+		} else {
+			r.b.WriteString("<ol>\n")
+		}
+	} else {
+		r.b.WriteString("<ul>\n")
+	}
+
+	for _, item := range items {
+		r.b.WriteString("<li>")
+		r.blocks(item.lines, !loose)
+		r.trimTrailingNewline()
+		r.b.WriteString("</li>\n")
+	}
+
+	if ordered {
+		r.b.WriteString("</ol>\n")
+	} else {
+		r.b.WriteString("</ul>\n")
+	}
+
+	return i
+}
+
+func (r *mdRenderer) htmlBlock(lines []string, i int) int {
+	for ; i < len(lines) && !isBlank(lines[i]); i++ {
+		r.b.WriteString(lines[i])
+		r.b.WriteString("\n")
+	}
+	return i
+}
+
+// table 渲染GFM表格，表头与分隔行的列数不一致时不是表格
+func (r *mdRenderer) table(lines []string, i int) (int, bool) {
+	header := splitTableRow(lines[i])
+	delims := splitTableRow(lines[i+1])
+	if len(header) != len(delims) {
+		return i, false
+	}
+
+	aligns := make([]string, len(delims))
+	for k, v := range delims {
+		left, right := strings.HasPrefix(v, ":"), strings.HasSuffix(v, ":")
+		switch {
+		case left && right:
+			aligns[k] = "center"
+		case left:
+			aligns[k] = "left"
+		case right:
+			aligns[k] = "right"
+		}
+	}
+
+	r.b.WriteString("<table>\n<thead>\n")
+	r.tableRow("th", header, aligns)
+	r.b.WriteString("</thead>\n")
+
+	i += 2
+	if i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|") {
+		r.b.WriteString("<tbody>\n")
+		for ; i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|"); i++ {
+			r.tableRow("td", splitTableRow(lines[i]), aligns)
+		}
+		r.b.WriteString("</tbody>\n")
+	}
+	r.b.WriteString("</table>\n")
+
+	return i, true
+}
+
+func (r *mdRenderer) tableRow(tag string, cells []string, aligns []string) {
+	r.b.WriteString("<tr>\n")
+	for k, align := range aligns {
+		cell := ""
+		if k < len(cells) {
+			cell = cells[k]
+		}
+
+		if align != "" {
+			fmt.Fprintf(&r.b, "<%s align=\"%s\">%s</%s>\n", tag, align, r.inline(cell), tag)
+		} else {
+			fmt.Fprintf(&r.b, "<%s>%s</%s>\n", tag, r.inline(cell), tag)
+		}
+	}
+	r.b.WriteString("</tr>\n")
+}
+
+func (r *mdRenderer) paragraph(lines []string, i int, tight bool) int {
+	para := []string{strings.TrimLeft(lines[i], " ")}
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+
+		// Setext标题: 段落下一行为===或---
+		if m := mdSetextReg.FindStringSubmatch(line); m != nil {
+			level := 1
+			if m[1][0] == '-' {
+				level = 2
+			}
+			r.heading(level, strings.Join(para, "\n"))
+			return i + 1
+		}
+
+		if r.startsBlock(line) {
+			break
+		}
+		para = append(para, strings.TrimLeft(line, " "))
+	}
+
+	content := r.inline(strings.TrimRight(strings.Join(para, "\n"), " "))
+	if tight {
+		r.b.WriteString(content)
+		r.b.WriteString("\n")
+	} else {
+		r.b.WriteString("<p>")
+		r.b.WriteString(content)
+		r.b.WriteString("</p>\n")
+	}
+
+	return i
+}
+
+// startsBlock 判断一行是否会打断段落，开始新的块
+func (r *mdRenderer) startsBlock(line string) bool {
+	if mdFenceReg.MatchString(line) || mdAtxHeadingReg.MatchString(line) || mdHrReg.MatchString(line) ||
+		mdQuoteReg.MatchString(line) || mdHTMLBlockReg.MatchString(line) {
+		return true
+	}
+
+	// 只有非空的无序列表项或从1开始的有序列表项能打断段落
+	if m := mdListReg.FindStringSubmatch(line); m != nil && m[3] != "" {
+		c := m[2][0]
+		return c == '-' || c == '*' || c == '+' || m[2][:len(m[2])-1] == "1"
+	}
+
+	return false
+}
+
+func (r *mdRenderer) trimTrailingNewline() {
+	s := r.b.String()
+	if strings.HasSuffix(s, "\n") {
+		r.b.Reset()
+		r.b.WriteString(s[:len(s)-1])
+	}
+}
+
+// inline 渲染行内元素
+func (r *mdRenderer) inline(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				b.WriteString("<br>\n")
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && isASCIIPunct(s[i+1]) {
+				b.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+		case '`':
+			n := runLength(s, i, '`')
+			if end := findBacktickRun(s, i+n, n); end >= 0 {
+				code := strings.ReplaceAll(s[i+n:end], "\n", " ")
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(code))
+				b.WriteString("</code>")
+				i = end + n
+				continue
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				if text, dest, title, end, ok := parseLink(s, i+1); ok {
+					fmt.Fprintf(&b, "<img src=\"%s\" alt=\"%s\"", html.EscapeString(dest), html.EscapeString(plainText(r.inline(text))))
+					if title != "" {
+						fmt.Fprintf(&b, " title=\"%s\"", html.EscapeString(title))
+					}
+					b.WriteString(">")
+					i = end
+					continue
+				}
+			}
+		case '[':
+			if text, dest, title, end, ok := parseLink(s, i); ok {
+				fmt.Fprintf(&b, "<a href=\"%s\"", html.EscapeString(dest))
+				if title != "" {
+					fmt.Fprintf(&b, " title=\"%s\"", html.EscapeString(title))
+				}
+				b.WriteString(">")
+				b.WriteString(r.inline(text))
+				b.WriteString("</a>")
+				i = end
+				continue
+			}
+		case '<':
+			if m := mdAutolinkReg.FindStringSubmatch(s[i:]); m != nil {
+				fmt.Fprintf(&b, "<a href=\"%s\">%s</a>", html.EscapeString(m[1]), html.EscapeString(m[1]))
+				i += len(m[0])
+				continue
+			}
+			// 内嵌html原样输出，最后统一过滤
+			if m := mdInlineHTMLReg.FindString(s[i:]); m != "" {
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+		case '&':
+			if m := mdEntityReg.FindString(s[i:]); m != "" {
+				b.WriteString(m)
+				i += len(m)
+				continue
+			}
+		case '*', '_', '~':
+			if out, end, ok := r.emphasis(s, i); ok {
+				b.WriteString(out)
+				i = end
+				continue
+			}
+			n := runLength(s, i, c)
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case ' ':
+			// 行尾两个以上空格为硬换行
+			n := runLength(s, i, ' ')
+			if n >= 2 && i+n < len(s) && s[i+n] == '\n' {
+				b.WriteString("<br>\n")
+				i += n + 1
+				continue
+			}
+			if i+n < len(s) && s[i+n] == '\n' {
+				i += n
+				continue
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+
+	return b.String()
+}
+
+// emphasis 渲染强调(*、_)、加粗(**、__)和删除线(~~)
+// @return string 渲染结果
+// @return int 结束位置
+// @return bool 是否为成对的标记
+func (r *mdRenderer) emphasis(s string, i int) (string, int, bool) {
+	d := s[i]
+	n := runLength(s, i, d)
+
+	// 开始标记后不能是空白，下划线不能在单词内部
+	if i+n >= len(s) || isSpace(s[i+n]) {
+		return "", 0, false
+	}
+	if d == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0, false
+	}
+
+	type style struct {
+		width      int
+		open, shut string
+	}
+	var styles []style
+	switch {
+	case d == '~' && n == 2:
+		styles = []style{{2, "<del>", "</del>"}}
+	case d == '~':
+		return "", 0, false
+	case n >= 3:
+		styles = []style{{3, "<em><strong>", "</strong></em>"}, {2, "<strong>", "</strong>"}, {1, "<em>", "</em>"}}
+
+		// 第一个结束标记较短时先闭合内层，***a** b*为<em><strong>a</strong> b</em>
+		if j := findCloseDelim(s, i+n, d, 0); j >= 0 {
+			if m := runLength(s, j, d); m < 3 {
+				outer, inner := styles[2], styles[1]
+				if m == 1 {
+					outer, inner = inner, outer
+				}
+				if end := findCloseDelim(s, i+outer.width, d, outer.width); end >= 0 {
+					return outer.open + r.inline(s[i+outer.width:end]) + outer.shut, end + outer.width, true
+				}
+
+				// 外层没有结束标记，多余的开始标记原样输出，***a**为*<strong>a</strong>
+				return s[i:i+n-m] + inner.open + r.inline(s[i+n:j]) + inner.shut, j + m, true
+			}
+		}
+	case n == 2:
+		styles = []style{{2, "<strong>", "</strong>"}}
+	default:
+		styles = []style{{1, "<em>", "</em>"}}
+	}
+
+	for _, st := range styles {
+		start := i + st.width
+		if end := findCloseDelim(s, start, d, st.width); end >= 0 {
+			return st.open + r.inline(s[start:end]) + st.shut, end + st.width, true
+		}
+	}
+
+	return "", 0, false
+}
+
+// findCloseDelim 从start开始查找长度为width的结束标记，跳过代码和转义字符
+// width为0时返回第一个任意长度的结束标记
+func findCloseDelim(s string, start int, d byte, width int) int {
+	for j := start; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			n := runLength(s, j, '`')
+			if end := findBacktickRun(s, j+n, n); end >= 0 {
+				j = end + n
+				continue
+			}
+			j += n
+			continue
+		case d:
+			m := runLength(s, j, d)
+
+			// 结束标记前不能是空白，下划线结束标记后不能紧跟单词
+			closable := j > start && !isSpace(s[j-1]) &&
+				!(d == '_' && j+m < len(s) && isWordByte(s[j+m]))
+			if closable && (m == width || width == 0) {
+				return j
+			}
+
+			// 更长的标记可能是嵌套的强调，整体跳过
+			j += m
+			continue
+		}
+		j++
+	}
+
+	return -1
+}
+
+// parseLink 解析[text](dest "title")
+// @param i '['的位置
+func parseLink(s string, i int) (text, dest, title string, end int, ok bool) {
+	// 查找匹配的']'
+	depth := 0
+	j := i
+	for ; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+			continue
+		case '`':
+			n := runLength(s, j, '`')
+			if e := findBacktickRun(s, j+n, n); e >= 0 {
+				j = e + n - 1
+			} else {
+				j += n - 1
+			}
+			continue
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+		if depth == 0 {
+			break
+		}
+	}
+	if j >= len(s) || j+1 >= len(s) || s[j+1] != '(' {
+		return
+	}
+	text = s[i+1 : j]
+
+	k := skipSpaces(s, j+2)
+	if k < len(s) && s[k] == '<' {
+		e := strings.IndexAny(s[k+1:], ">\n")
+		if e < 0 || s[k+1+e] != '>' {
+			return
+		}
+		dest = s[k+1 : k+1+e]
+		k += e + 2
+	} else {
+		parens := 0
+		e := k
+		for ; e < len(s); e++ {
+			c := s[e]
+			if c == '\\' && e+1 < len(s) {
+				e++
+				continue
+			}
+			if c == '(' {
+				parens++
+			} else if c == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			} else if c == ' ' || c == '\n' {
+				break
+			}
+		}
+		dest = unescapeBackslash(s[k:e])
+		k = e
+	}
+
+	k = skipSpaces(s, k)
+	if k < len(s) && (s[k] == '"' || s[k] == '\'' || s[k] == '(') {
+		closer := s[k]
+		if closer == '(' {
+			closer = ')'
+		}
+		e := strings.IndexByte(s[k+1:], closer)
+		if e < 0 {
+			return
+		}
+		title = unescapeBackslash(s[k+1 : k+1+e])
+		k = skipSpaces(s, k+e+2)
+	}
+
+	if k >= len(s) || s[k] != ')' {
+		return
+	}
+
+	return text, html.UnescapeString(dest), html.UnescapeString(title), k + 1, true
+}
+
+// findBacktickRun 查找长度恰好为n的反引号串
+func findBacktickRun(s string, start, n int) int {
+	for j := start; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j, '`')
+		if m == n {
+			return j
+		}
+		j += m
+	}
+	return -1
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var (
+		cells []string
+		cell  strings.Builder
+	)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == '|' {
+			cell.WriteByte('|')
+			i++
+			continue
+		}
+		if line[i] == '|' {
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+			continue
+		}
+		cell.WriteByte(line[i])
+	}
+	cells = append(cells, strings.TrimSpace(cell.String()))
+
+	return cells
+}
+
+// plainText 去掉html标签并反转义，得到纯文本
+func plainText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(mdHTMLTagReg.ReplaceAllString(s, "")))
+}
+
+func unescapeBackslash(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func leadingSpaces(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+func hasContent(lines []string) bool {
+	for _, v := range lines {
+		if !isBlank(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\n'
+}
+
+func isWordByte(c byte) bool {
+	return c >= 0x80 || c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// 标题
+		{"atx heading", "# Title", `<h1 id="title">Title</h1>` + "\n"},
+		{"atx closing hashes", "## Title ##", `<h2 id="title">Title</h2>` + "\n"},
+		{"setext heading", "Title\n---", `<h2 id="title">Title</h2>` + "\n"},
+		{"heading with inline", "## `code` *em*", `<h2 id="code-em"><code>code</code> <em>em</em></h2>` + "\n"},
+		{"empty heading", "#", `<h1 id="section"></h1>` + "\n"},
+
+		// 强调
+		{"em", "*a*", "<p><em>a</em></p>\n"},
+		{"strong", "__a__", "<p><strong>a</strong></p>\n"},
+		{"em strong", "***a***", "<p><em><strong>a</strong></em></p>\n"},
+		{"strong in em", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>\n"},
+		{"em in strong", "**a *b* c**", "<p><strong>a <em>b</em> c</strong></p>\n"},
+		{"strong closes first", "***a** b*", "<p><em><strong>a</strong> b</em></p>\n"},
+		{"em closes first", "***a* b**", "<p><strong><em>a</em> b</strong></p>\n"},
+		{"strong without outer close", "***a**", "<p>*<strong>a</strong></p>\n"},
+		{"em without outer close", "***a*", "<p>**<em>a</em></p>\n"},
+		{"underscore in word", "snake_case_word", "<p>snake_case_word</p>\n"},
+		{"space after opener", "* a*", "<ul>\n<li>a*</li>\n</ul>\n"},
+		{"del", "~~a **b**~~", "<p><del>a <strong>b</strong></del></p>\n"},
+		{"escaped", `\*a\*`, "<p>*a*</p>\n"},
+		{"code span", "`a *b* <c>`", "<p><code>a *b* &lt;c&gt;</code></p>\n"},
+
+		// 代码块
+		{"fenced with language", "```go\nfmt.Println(\"<a>\")\n```", `<pre><code class="language-go">fmt.Println(&#34;&lt;a&gt;&#34;)` + "\n</code></pre>\n"},
+		{"fenced without language", "~~~\nplain\n~~~", "<pre><code>plain\n</code></pre>\n"},
+		{"indented code", "    a\n    b", "<pre><code>a\nb\n</code></pre>\n"},
+
+		// 列表
+		{"tight list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"loose list", "- a\n\n- b", "<ul>\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ul>\n"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>a\n<ul>\n<li>b</li>\n</ul></li>\n</ul>\n"},
+		{"ordered list", "1. a\n2. b", "<ol>\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"ordered list start", "3) a\n4) b", `<ol start="3">` + "\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+
+		// 表格
+		{"table", "| a | b |\n|:--|--:|\n| 1 | 2 |",
+			"<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\">2</td>\n</tr>\n</tbody>\n</table>\n"},
+		{"table without body", "| a |\n|---|", "<table>\n<thead>\n<tr>\n<th>a</th>\n</tr>\n</thead>\n</table>\n"},
+		{"table column mismatch", "| a | b |\n|---|", "<p>| a | b |\n|---|</p>\n"},
+
+		// 链接和图片
+		{"link", `[x](https://example.com "t")`, `<p><a href="https://example.com" title="t">x</a></p>` + "\n"},
+		{"link angle dest", "[x](</a b>)", `<p><a href="/a b">x</a></p>` + "\n"},
+		{"autolink", "<https://example.com>", `<p><a href="https://example.com">https://example.com</a></p>` + "\n"},
+		{"image", "![alt *x*](/a.png)", `<p><img src="/a.png" alt="alt x"></p>` + "\n"},
+		{"not a link", "[x] (y)", "<p>[x] (y)</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := RenderMarkdown(tt.in); got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownToc(t *testing.T) {
+	src := "# Title\n## Title\n## Title 1\n### Title-1\n#\n## 中文 标题"
+
+	html, toc := RenderMarkdown(src)
+
+	// 重复的标题依次追加序号，追加后与其他标题冲突时继续递增，空标题不进入目录
+	want := []TocItem{
+		{Level: 1, Id: "title", Title: "Title"},
+		{Level: 2, Id: "title-1", Title: "Title"},
+		{Level: 2, Id: "title-1-1", Title: "Title 1"},
+		{Level: 3, Id: "title-1-2", Title: "Title-1"},
+		{Level: 2, Id: "中文-标题", Title: "中文 标题"},
+	}
+	if !reflect.DeepEqual(toc, want) {
+		t.Errorf("RenderMarkdown() toc = %+v, want %+v", toc, want)
+	}
+
+	for _, v := range want {
+		if !strings.Contains(html, `id="`+v.Id+`"`) {
+			t.Errorf("RenderMarkdown() html has no anchor %q: %s", v.Id, html)
+		}
+	}
+}
+
+// TestRenderMarkdownSanitize 渲染结果经过SanitizeHTML过滤，不能包含脚本
+func TestRenderMarkdownSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"javascript link", "[x](javascript:alert(1))", "<p><a>x</a></p>\n"},
+		{"javascript link mixed case", "[x](JaVaScRiPt:alert(1))", "<p><a>x</a></p>\n"},
+		{"javascript link entity", "[x](&#106;avascript:alert(1))", "<p><a>x</a></p>\n"},
+		{"javascript image", "![x](javascript:alert(1))", `<p><img alt="x"></p>` + "\n"},
+		{"script block", "<script>alert(1)</script>\n\nok", "\n<p>ok</p>\n"},
+		{"inline script", "a <script>alert(1)</script> b", "<p>a  b</p>\n"},
+		{"event handler", "<img src=x onerror=alert(1)>", `<img src="x">` + "\n"},
+		{"script in heading", "# a<script>alert(1)</script>", `<h1 id="a">a</h1>` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, toc := RenderMarkdown(tt.in)
+			if got != tt.want {
+				t.Errorf("RenderMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			if strings.Contains(strings.ToLower(got), "script") || strings.Contains(got, "alert") {
+				t.Errorf("RenderMarkdown(%q) = %q, script not stripped", tt.in, got)
+			}
+			for _, v := range toc {
+				if strings.Contains(v.Title, "alert") || strings.Contains(v.Id, "alert") {
+					t.Errorf("RenderMarkdown(%q) toc = %+v, script not stripped", tt.in, v)
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"encoding/base64"
 	"math/rand"
 	"time"
 )
@@ -34,3 +36,15 @@ func RandCode(n int) string {
 	return string(b)
 }
 
+
+// RandToken 使用加密安全的随机数生成凭证，用于邮件链接等不可被猜测的场景
+// @param n 随机字节数
+// @return string base64url编码的随机串
+// @return error
+func RandToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
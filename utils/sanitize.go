@@ -0,0 +1,263 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// sanitizeAllowedTags 允许保留的标签 => 该标签允许的属性(不包括全局属性)
+var sanitizeAllowedTags = map[string][]string{
+	"h1": nil, "h2": nil, "h3": nil, "h4": nil, "h5": nil, "h6": nil,
+	"p": nil, "br": nil, "hr": nil, "div": nil, "span": nil, "blockquote": nil,
+	"pre": nil, "code": {"class"}, "kbd": nil, "samp": nil,
+	"em": nil, "strong": nil, "b": nil, "i": nil, "u": nil, "s": nil, "del": nil, "ins": nil,
+	"mark": nil, "sub": nil, "sup": nil, "small": nil, "abbr": nil,
+	"ul": nil, "ol": {"start"}, "li": nil, "dl": nil, "dt": nil, "dd": nil,
+	"table": nil, "thead": nil, "tbody": nil, "tfoot": nil, "tr": nil,
+	"th": {"align", "colspan", "rowspan"}, "td": {"align", "colspan", "rowspan"},
+	"a":       {"href"},
+	"img":     {"src", "alt", "width", "height"},
+	"details": nil, "summary": nil, "figure": nil, "figcaption": nil,
+}
+
+// sanitizeGlobalAttrs 所有允许的标签都可以使用的属性
+var sanitizeGlobalAttrs = []string{"id", "title"}
+
+// sanitizeDropContentTags 连同内容一起删除的标签
+var sanitizeDropContentTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frameset": true, "object": true, "embed": true,
+	"applet": true, "noscript": true, "noembed": true, "textarea": true, "title": true,
+	"template": true, "svg": true, "math": true, "xmp": true, "plaintext": true,
+}
+
+// sanitizeURLSchemes 链接允许的协议，不带协议的相对地址总是允许
+var sanitizeURLSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+var sanitizeCodeClassReg = regexp.MustCompile(`^language-[\w+#.-]+$`)
+
+type htmlTag struct {
+	name        string
+	closing     bool
+	selfClosing bool
+	attrs       [][2]string
+}
+
+// SanitizeHTML 基于白名单过滤html
+// 删除script等危险标签及其内容、所有事件处理属性(on*)、style属性和非http(s)/mailto协议的链接，
+// 其他不在白名单中的标签只删除标签本身，保留内容
+// @param s html
+// @return string 过滤后的html
+func SanitizeHTML(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		lt := strings.IndexByte(s[i:], '<')
+		if lt < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+		b.WriteString(s[i : i+lt])
+		i += lt
+
+		// 删除注释，未闭合的注释删除到末尾
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i+4:], "-->")
+			if end < 0 {
+				break
+			}
+			i += 4 + end + 3
+			continue
+		}
+
+		tag, end, ok := parseHTMLTag(s, i)
+		if !ok {
+			b.WriteString("&lt;")
+			i++
+			continue
+		}
+		i = end
+
+		if tag.closing {
+			if _, ok := sanitizeAllowedTags[tag.name]; ok {
+				b.WriteString("</" + tag.name + ">")
+			}
+			continue
+		}
+
+		if sanitizeDropContentTags[tag.name] {
+			if !tag.selfClosing {
+				i = skipUntilCloseTag(s, i, tag.name)
+			}
+			continue
+		}
+
+		allowedAttrs, ok := sanitizeAllowedTags[tag.name]
+		if !ok {
+			continue
+		}
+
+		b.WriteString("<" + tag.name)
+		for _, attr := range tag.attrs {
+			name, value := attr[0], html.UnescapeString(attr[1])
+			if IndexOf(allowedAttrs, name) == -1 && IndexOf(sanitizeGlobalAttrs, name) == -1 {
+				continue
+			}
+			if (name == "href" || name == "src") && !isSafeURL(value) {
+				continue
+			}
+			if tag.name == "code" && name == "class" && !sanitizeCodeClassReg.MatchString(value) {
+				continue
+			}
+			b.WriteString(" " + name + "=\"" + html.EscapeString(value) + "\"")
+		}
+		b.WriteString(">")
+	}
+
+	return b.String()
+}
+
+// parseHTMLTag 解析s[i]处的标签
+// @return htmlTag 标签，标签名和属性名为小写
+// @return int 标签结束后的位置
+// @return bool 是否为合法的标签
+func parseHTMLTag(s string, i int) (htmlTag, int, bool) {
+	tag := htmlTag{}
+	j := i + 1
+
+	if j < len(s) && s[j] == '/' {
+		tag.closing = true
+		j++
+	}
+
+	start := j
+	for j < len(s) && (isASCIILetter(s[j]) || (j > start && (s[j] == '-' || (s[j] >= '0' && s[j] <= '9')))) {
+		j++
+	}
+	if j == start {
+		return tag, 0, false
+	}
+	tag.name = strings.ToLower(s[start:j])
+
+	for j < len(s) {
+		j = skipHTMLSpaces(s, j)
+		if j >= len(s) {
+			break
+		}
+
+		switch {
+		case s[j] == '>':
+			return tag, j + 1, true
+		case strings.HasPrefix(s[j:], "/>"):
+			tag.selfClosing = true
+			return tag, j + 2, true
+		case s[j] == '/':
+			j++
+			continue
+		}
+
+		// 属性名
+		nameStart := j
+		for j < len(s) && !isHTMLSpace(s[j]) && s[j] != '=' && s[j] != '>' && s[j] != '/' {
+			j++
+		}
+		name := strings.ToLower(s[nameStart:j])
+		if name == "" {
+			// 非法字符，跳过
+			j++
+			continue
+		}
+
+		// 属性值
+		value := ""
+		k := skipHTMLSpaces(s, j)
+		if k < len(s) && s[k] == '=' {
+			k = skipHTMLSpaces(s, k+1)
+			if k >= len(s) {
+				return tag, 0, false
+			}
+
+			if s[k] == '"' || s[k] == '\'' {
+				end := strings.IndexByte(s[k+1:], s[k])
+				if end < 0 {
+					return tag, 0, false
+				}
+				value = s[k+1 : k+1+end]
+				k += end + 2
+			} else {
+				valueStart := k
+				for k < len(s) && !isHTMLSpace(s[k]) && s[k] != '>' {
+					k++
+				}
+				value = s[valueStart:k]
+			}
+			j = k
+		}
+
+		tag.attrs = append(tag.attrs, [2]string{name, value})
+	}
+
+	// 标签没有结束
+	return tag, 0, false
+}
+
+// skipUntilCloseTag 跳过到name的结束标签之后，没有结束标签时跳过到末尾
+func skipUntilCloseTag(s string, i int, name string) int {
+	lower := strings.ToLower(s)
+
+	for {
+		k := strings.Index(lower[i:], "</"+name)
+		if k < 0 {
+			return len(s)
+		}
+		k += i + 2 + len(name)
+
+		if k >= len(s) || isHTMLSpace(s[k]) || s[k] == '>' || s[k] == '/' {
+			end := strings.IndexByte(s[k:], '>')
+			if end < 0 {
+				return len(s)
+			}
+			return k + end + 1
+		}
+		i = k
+	}
+}
+
+// isSafeURL 判断链接是否为允许的协议或相对地址
+func isSafeURL(u string) bool {
+	// 浏览器会忽略协议中的空白和控制字符，如"java\tscript:"
+	var b strings.Builder
+	for _, c := range u {
+		if c > ' ' && c != 0x7f {
+			b.WriteRune(c)
+		}
+	}
+	cleaned := strings.ToLower(b.String())
+
+	colon := strings.IndexByte(cleaned, ':')
+	if colon < 0 {
+		return true
+	}
+
+	// 冒号出现在路径、查询或锚点中时是相对地址
+	if sep := strings.IndexAny(cleaned, "/?#"); sep >= 0 && sep < colon {
+		return true
+	}
+
+	return sanitizeURLSchemes[cleaned[:colon]]
+}
+
+func skipHTMLSpaces(s string, i int) int {
+	for i < len(s) && isHTMLSpace(s[i]) {
+		i++
+	}
+	return i
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
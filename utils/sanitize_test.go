@@ -0,0 +1,79 @@
+package utils
+
+import "testing"
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		// 白名单
+		{"plain text", "hello world", "hello world"},
+		{"allowed tags", "<p>a <strong>b</strong><br/>c</p>", "<p>a <strong>b</strong><br>c</p>"},
+		{"uppercase tags", "<P>a</P>", "<p>a</p>"},
+		{"unknown tag keeps content", "<font color=red>a</font>", "a"},
+		{"allowed attrs", `<a href="https://example.com" title="t" id="x">a</a>`, `<a href="https://example.com" title="t" id="x">a</a>`},
+		{"attr not allowed", `<p align="center" class="x">a</p>`, "<p>a</p>"},
+		{"style attr", `<p style="background:url(javascript:alert(1))">a</p>`, "<p>a</p>"},
+		{"attr value escaped", `<a title='a" onclick="x'>a</a>`, `<a title="a&#34; onclick=&#34;x">a</a>`},
+		{"code language class", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"code other class", `<code class="x y">x</code>`, "<code>x</code>"},
+
+		// script/style等标签连同内容删除
+		{"script", "a<script>alert(1)</script>b", "ab"},
+		{"script uppercase", "a<SCRIPT type=text/javascript>alert(1)</ScRiPt >b", "ab"},
+		{"script fake close", "a<script>x</scriptx>alert(1)</script>b", "ab"},
+		{"style", "a<style>p{color:red}</style>b", "ab"},
+		{"svg", `a<svg onload=alert(1)><script>alert(1)</script></svg>b`, "ab"},
+		{"iframe", `a<iframe src="https://example.com"></iframe>b`, "ab"},
+		{"nested script in name", "<scr<script>ipt>alert(1)</script>", "ipt>alert(1)"},
+
+		// 事件处理属性
+		{"onerror", `<img src="x.png" onerror="alert(1)">`, `<img src="x.png">`},
+		{"onclick uppercase", `<p ONCLICK="alert(1)">a</p>`, "<p>a</p>"},
+		{"onload unquoted", `<img src=x.png onload=alert(1)>`, `<img src="x.png">`},
+		{"on attr after slash", `<img src="x.png"/onerror=alert(1)>`, `<img src="x.png">`},
+
+		// javascript:等协议
+		{"javascript href", `<a href="javascript:alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript mixed case", `<a href="JaVaScRiPt:alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript leading space", `<a href="  javascript:alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript tab", "<a href=\"java\tscript:alert(1)\">a</a>", "<a>a</a>"},
+		{"javascript newline", "<a href=\"java\nscript:alert(1)\">a</a>", "<a>a</a>"},
+		{"javascript null byte", "<a href=\"java\x00script:alert(1)\">a</a>", "<a>a</a>"},
+		{"javascript decimal entity", `<a href="&#106;avascript:alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript entity no semicolon", `<a href="&#0000106avascript:alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript hex entity", `<a href="&#x6A;avascript&#x3A;alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript named entity", `<a href="javascript&colon;alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript entity tab", `<a href="java&Tab;script:alert(1)">a</a>`, "<a>a</a>"},
+		{"javascript entity newline", `<a href="java&#10;script:alert(1)">a</a>`, "<a>a</a>"},
+		{"vbscript", `<a href="vbscript:msgbox(1)">a</a>`, "<a>a</a>"},
+		{"data src", `<img src="data:text/html;base64,PHNjcmlwdD4=">`, "<img>"},
+		{"mailto", `<a href="mailto:a@example.com">a</a>`, `<a href="mailto:a@example.com">a</a>`},
+		{"relative", `<a href="/a/b?c=d:e#f">a</a>`, `<a href="/a/b?c=d:e#f">a</a>`},
+		{"relative colon in path", `<img src="img/a:b.png">`, `<img src="img/a:b.png">`},
+
+		// 未闭合的标签
+		{"unclosed tag", `a<img src="x.png" onerror="alert(1)"`, `a&lt;img src="x.png" onerror="alert(1)"`},
+		{"unclosed quote", `<a href="javascript:alert(1)>a</a>`, `&lt;a href="javascript:alert(1)>a</a>`},
+		{"unclosed script", "a<script>alert(1)", "a"},
+		{"unclosed element", "<p><strong>a", "<p><strong>a"},
+		{"lone lt", "a < b <3", "a &lt; b &lt;3"},
+		{"closing tag without name", "a</ script>b", "a&lt;/ script>b"},
+
+		// 注释
+		{"comment", "a<!-- <script>alert(1)</script> -->b", "ab"},
+		{"conditional comment", "a<!--[if IE]><script>alert(1)</script><![endif]-->b", "ab"},
+		{"unclosed comment", "a<!-- <script>alert(1)</script>", "a"},
+		{"bogus comment", "a<!DOCTYPE html><?php echo 1 ?>b", "a&lt;!DOCTYPE html>&lt;?php echo 1 ?>b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.in); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// SitemapMaxURLs 单个sitemap文件最多包含的地址数
+const SitemapMaxURLs = 50000
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// SitemapURL sitemap中的地址，LastMod为零值时不输出
+type SitemapURL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+type sitemapUrlset struct {
+	XMLName xml.Name          `xml:"urlset"`
+	Xmlns   string            `xml:"xmlns,attr"`
+	URLs    []sitemapLocation `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name          `xml:"sitemapindex"`
+	Xmlns    string            `xml:"xmlns,attr"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+type sitemapLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Sitemap 生成sitemap文档
+// @param urls 地址，不能超过SitemapMaxURLs个
+// @return []byte xml文档
+// @return error
+func Sitemap(urls []SitemapURL) ([]byte, error) {
+	return marshalXML(sitemapUrlset{
+		Xmlns: sitemapNamespace,
+		URLs:  sitemapLocations(urls),
+	})
+}
+
+// SitemapIndex 生成sitemap索引文档
+// @param sitemaps 各个sitemap文件的地址
+// @return []byte xml文档
+// @return error
+func SitemapIndex(sitemaps []SitemapURL) ([]byte, error) {
+	return marshalXML(sitemapIndex{
+		Xmlns:    sitemapNamespace,
+		Sitemaps: sitemapLocations(sitemaps),
+	})
+}
+
+func sitemapLocations(urls []SitemapURL) []sitemapLocation {
+	locations := make([]sitemapLocation, 0, len(urls))
+
+	for _, v := range urls {
+		location := sitemapLocation{Loc: v.Loc}
+		if !v.LastMod.IsZero() {
+			location.LastMod = v.LastMod.Format(time.RFC3339)
+		}
+		locations = append(locations, location)
+	}
+
+	return locations
+}
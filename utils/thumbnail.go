@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// ErrImageTooLarge 图片像素数超过上限
+var ErrImageTooLarge = errors.New("image pixels exceed the limit")
+
+// Thumbnail 生成图片缩略图，宽度不超过maxWidth时按原尺寸重新编码
+// 带透明通道的图片编码为png，其他编码为jpeg
+// 解码前先读取图片尺寸，防止文件很小但尺寸极大的图片解码时占用大量内存
+// @param data 原图数据，支持jpeg/png/gif(取第一帧)
+// @param maxWidth 缩略图最大宽度
+// @param maxPixels 原图宽×高的上限
+// @return []byte 缩略图数据
+// @return string 缩略图的mime类型
+// @return error 图片无法解码时返回错误，超过像素上限时返回ErrImageTooLarge
+func Thumbnail(data []byte, maxWidth int, maxPixels int64) ([]byte, string, error) {
+	conf, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if conf.Width <= 0 || conf.Height <= 0 || int64(conf.Width)*int64(conf.Height) > maxPixels {
+		return nil, "", ErrImageTooLarge
+	}
+
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxWidth {
+		height = height * maxWidth / width
+		width = maxWidth
+		if height < 1 {
+			height = 1
+		}
+	}
+
+	dst := resizeImage(src, width, height)
+
+	var buf bytes.Buffer
+	if format == "png" || format == "gif" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// resizeImage 使用区域平均缩小图片，目标像素取其覆盖的原图像素的平均值
+func resizeImage(src image.Image, width, height int) *image.NRGBA {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	if bounds.Dx() == width && bounds.Dy() == height {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			// 预乘alpha的颜色值求和，避免透明像素的颜色影响结果
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
+		}
+	}
+
+	return dst
+}